@@ -1,5 +1,20 @@
 module github.com/walterfan/lazy-mock-server
 
-go 1.21
+go 1.22
 
-require gopkg.in/yaml.v2 v2.4.0
+require (
+	github.com/bufbuild/protocompile v0.14.1
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/gorilla/websocket v1.5.3
+	google.golang.org/grpc v1.64.1
+	google.golang.org/protobuf v1.36.6
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+)
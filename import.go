@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+	"github.com/walterfan/lazy-mock-server/internal/importers"
+	"github.com/walterfan/lazy-mock-server/internal/openapi"
+)
+
+// importUsage describes the import subcommand
+const importUsage = `Usage: mock-server import <format> [-o output.yaml] <file>
+
+Formats:
+  openapi   OpenAPI 3.x document in YAML or JSON
+  postman   Postman v2.0 or v2.1 collection, including saved examples
+  har       HTTP Archive captured by a browser or proxy
+
+Routes are written to the output file in the configuration format, or to
+standard output when -o is omitted.
+`
+
+// runImport converts an API description into route configuration
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	output := flags.String("o", "", "Write routes to this file instead of standard output")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), importUsage)
+		flags.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		flags.Usage()
+		return fmt.Errorf("missing import format")
+	}
+	format := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected exactly one input file")
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", flags.Arg(0), err)
+	}
+
+	var routes []config.Route
+	switch format {
+	case "openapi":
+		doc, err := openapi.Parse(data)
+		if err != nil {
+			return err
+		}
+		if routes, err = doc.Routes(); err != nil {
+			return err
+		}
+	case "postman":
+		if routes, err = importers.FromPostman(data); err != nil {
+			return err
+		}
+	case "har":
+		if routes, err = importers.FromHAR(data); err != nil {
+			return err
+		}
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown import format %q", format)
+	}
+
+	manager := config.NewManager(*output)
+	manager.SetConfig(&config.Config{Routes: routes})
+	for _, route := range routes {
+		if err := manager.ValidateRoute(route); err != nil {
+			return fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+	}
+
+	if *output == "" {
+		data, err := manager.ToBytes()
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := manager.Save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d routes into %s\n", len(routes), *output)
+	return nil
+}
@@ -1,9 +1,16 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
+	"github.com/walterfan/lazy-mock-server/internal/jsonpath"
+	"github.com/walterfan/lazy-mock-server/internal/xmldoc"
 	"gopkg.in/yaml.v2"
 )
 
@@ -16,6 +23,339 @@ type Route struct {
 	Response    interface{}       `yaml:"response" json:"response"`
 	Headers     map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
 	Parameters  map[string]string `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	Match       *RequestMatch     `yaml:"match,omitempty" json:"match,omitempty"`
+
+	// BodyFile serves the body from a file, relative to the file defining
+	// the route; Template renders its content as a response template
+	BodyFile string `yaml:"body_file,omitempty" json:"body_file,omitempty"`
+	Template bool   `yaml:"template,omitempty" json:"template,omitempty"`
+
+	// BodyBase64 is a binary body, base64 encoded
+	BodyBase64 string `yaml:"body_base64,omitempty" json:"body_base64,omitempty"`
+
+	// SSE streams server-sent events instead of a single response body
+	SSE *SSE `yaml:"sse,omitempty" json:"sse,omitempty"`
+
+	// WebSocket upgrades the connection and plays a scripted conversation
+	WebSocket *WebSocket `yaml:"websocket,omitempty" json:"websocket,omitempty"`
+
+	// GraphQL matches GraphQL operations and answers with their data and errors
+	GraphQL *GraphQL `yaml:"graphql,omitempty" json:"graphql,omitempty"`
+
+	// Responses returns successive entries on successive calls, selected
+	// according to ResponseMode
+	Responses    []ResponseVariant `yaml:"responses,omitempty" json:"responses,omitempty"`
+	ResponseMode string            `yaml:"response_mode,omitempty" json:"response_mode,omitempty"`
+
+	// Delay postpones the response; Dribble spreads the body over time
+	Delay   *Delay   `yaml:"delay,omitempty" json:"delay,omitempty"`
+	Dribble *Dribble `yaml:"dribble,omitempty" json:"dribble,omitempty"`
+
+	// Proxy forwards matching requests to this base URL instead of
+	// answering them; ProxyHeaders rewrites the forwarded request headers
+	Proxy        string            `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	ProxyHeaders map[string]string `yaml:"proxy_headers,omitempty" json:"proxy_headers,omitempty"`
+
+	// Fault replaces the response with a broken connection, see the Fault* constants
+	Fault string `yaml:"fault,omitempty" json:"fault,omitempty"`
+
+	// Validation checks requests matching the route against an OpenAPI document
+	Validation *Validation `yaml:"validation,omitempty" json:"validation,omitempty"`
+
+	// Source is the file the route was loaded from and is saved back to
+	Source string `yaml:"-" json:"source,omitempty"`
+
+	// Scenario makes the route part of a state machine shared by routes
+	// with the same scenario name
+	Scenario      string `yaml:"scenario,omitempty" json:"scenario,omitempty"`
+	RequiredState string `yaml:"required_state,omitempty" json:"required_state,omitempty"`
+	NewState      string `yaml:"new_state,omitempty" json:"new_state,omitempty"`
+}
+
+// ScenarioStarted is the initial state of every scenario
+const ScenarioStarted = "Started"
+
+// Response modes for routes with a responses list
+const (
+	// ResponseModeStick returns entries in order and then repeats the last one
+	ResponseModeStick = "stick"
+	// ResponseModeCycle returns entries in order and starts over after the last
+	ResponseModeCycle = "cycle"
+	// ResponseModeRandom picks an entry at random according to its weight
+	ResponseModeRandom = "random"
+)
+
+// ProxyConfig describes an upstream server requests are forwarded to
+type ProxyConfig struct {
+	Target string `yaml:"target" json:"target"`
+	// Headers are set on the forwarded request; an empty value removes the header
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// PreserveHost keeps the client's Host header instead of the target's
+	PreserveHost bool `yaml:"preserve_host,omitempty" json:"preserve_host,omitempty"`
+}
+
+// Validation checks requests against the operations of an OpenAPI document.
+// Requests for operations the document does not describe are not checked.
+type Validation struct {
+	// Spec is the path of the OpenAPI document, relative to the configuration file
+	Spec string `yaml:"spec" json:"spec"`
+	// StatusCode answers invalid requests and defaults to 400
+	StatusCode int `yaml:"status_code,omitempty" json:"status_code,omitempty"`
+	// Response replaces the default error body; the validation errors are
+	// added to it under "errors" when it is an object
+	Response interface{} `yaml:"response,omitempty" json:"response,omitempty"`
+
+	// dir is the directory of the file defining the validation
+	dir string
+}
+
+// SpecPath returns the path of the OpenAPI document, resolved against the
+// directory of the file defining the validation when that is known
+func (v *Validation) SpecPath() string {
+	if v.dir == "" || filepath.IsAbs(v.Spec) {
+		return v.Spec
+	}
+	return filepath.Join(v.dir, v.Spec)
+}
+
+// Faults a route can inject instead of a well-formed response
+const (
+	// FaultConnectionReset closes the connection with a TCP reset
+	FaultConnectionReset = "connection_reset"
+	// FaultEmptyResponse closes the connection without sending anything
+	FaultEmptyResponse = "empty_response"
+	// FaultRandomData sends random bytes and closes the connection
+	FaultRandomData = "random_data"
+	// FaultMalformedChunk sends a valid status line followed by an invalid chunk
+	FaultMalformedChunk = "malformed_chunk"
+	// FaultTruncatedBody announces the full Content-Length but sends half the body
+	FaultTruncatedBody = "truncated_body"
+)
+
+// Delay distributions
+const (
+	// DelayFixed always waits Ms milliseconds
+	DelayFixed = "fixed"
+	// DelayUniform waits a random time between MinMs and MaxMs
+	DelayUniform = "uniform"
+	// DelayNormal waits a normally distributed time around MeanMs
+	DelayNormal = "normal"
+	// DelayLogNormal waits a log-normally distributed time with median MedianMs
+	DelayLogNormal = "lognormal"
+)
+
+// Delay describes how long to wait before responding
+type Delay struct {
+	Distribution string  `yaml:"distribution,omitempty" json:"distribution,omitempty"`
+	Ms           int     `yaml:"ms,omitempty" json:"ms,omitempty"`
+	MinMs        int     `yaml:"min_ms,omitempty" json:"min_ms,omitempty"`
+	MaxMs        int     `yaml:"max_ms,omitempty" json:"max_ms,omitempty"`
+	MeanMs       int     `yaml:"mean_ms,omitempty" json:"mean_ms,omitempty"`
+	StdDevMs     int     `yaml:"stddev_ms,omitempty" json:"stddev_ms,omitempty"`
+	MedianMs     int     `yaml:"median_ms,omitempty" json:"median_ms,omitempty"`
+	Sigma        float64 `yaml:"sigma,omitempty" json:"sigma,omitempty"`
+}
+
+// Dribble delivers the response body in chunks spread over a duration
+type Dribble struct {
+	Chunks     int `yaml:"chunks" json:"chunks"`
+	DurationMs int `yaml:"duration_ms" json:"duration_ms"`
+}
+
+// SSE is a stream of server-sent events
+type SSE struct {
+	Events []SSEEvent `yaml:"events" json:"events"`
+	// Loop replays the events until the client disconnects
+	Loop bool `yaml:"loop,omitempty" json:"loop,omitempty"`
+}
+
+// SSEEvent is a single server-sent event. The id and data strings are
+// rendered as response templates; other data values are sent as JSON.
+type SSEEvent struct {
+	ID    string      `yaml:"id,omitempty" json:"id,omitempty"`
+	Event string      `yaml:"event,omitempty" json:"event,omitempty"`
+	Data  interface{} `yaml:"data,omitempty" json:"data,omitempty"`
+	// Retry tells the client how long to wait before reconnecting
+	Retry int `yaml:"retry,omitempty" json:"retry,omitempty"`
+	// DelayMs is the pause before the event is sent
+	DelayMs int `yaml:"delay_ms,omitempty" json:"delay_ms,omitempty"`
+}
+
+// GetJSONSafeData returns the event data with YAML maps converted for JSON
+func (e *SSEEvent) GetJSONSafeData() interface{} {
+	return convertYAMLToJSON(e.Data)
+}
+
+// WebSocket scripts the conversation on an upgraded connection
+type WebSocket struct {
+	// OnConnect is sent once the connection is upgraded
+	OnConnect []WebSocketMessage `yaml:"on_connect,omitempty" json:"on_connect,omitempty"`
+	// Replies answer incoming messages; the first matching reply is used
+	Replies []WebSocketReply `yaml:"replies,omitempty" json:"replies,omitempty"`
+	// Periodic messages are sent repeatedly while the connection is open
+	Periodic []WebSocketPeriodic `yaml:"periodic,omitempty" json:"periodic,omitempty"`
+	// Close ends the conversation after on_connect has been sent
+	Close *WebSocketClose `yaml:"close,omitempty" json:"close,omitempty"`
+}
+
+// GetJSONSafe returns the script as a document with YAML maps converted for
+// JSON, keyed like the configuration file
+func (w *WebSocket) GetJSONSafe() interface{} {
+	data, err := yaml.Marshal(w)
+	if err != nil {
+		return nil
+	}
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil
+	}
+	return convertYAMLToJSON(document)
+}
+
+// WebSocketMessage is a message sent to the client. Data strings are
+// rendered as response templates and other values are sent as JSON text;
+// DataBase64 sends a binary message instead.
+type WebSocketMessage struct {
+	Data       interface{} `yaml:"data,omitempty" json:"data,omitempty"`
+	DataBase64 string      `yaml:"data_base64,omitempty" json:"data_base64,omitempty"`
+	DelayMs    int         `yaml:"delay_ms,omitempty" json:"delay_ms,omitempty"`
+}
+
+// WebSocketReply answers incoming messages. Matches is a regular expression
+// applied to the whole message and JSON checks JSON messages like a body
+// matcher; a reply without conditions answers every message.
+type WebSocketReply struct {
+	Matches  string             `yaml:"matches,omitempty" json:"matches,omitempty"`
+	JSON     *BodyMatcher       `yaml:"json,omitempty" json:"json,omitempty"`
+	Messages []WebSocketMessage `yaml:"messages,omitempty" json:"messages,omitempty"`
+	// Close ends the conversation after the messages have been sent
+	Close *WebSocketClose `yaml:"close,omitempty" json:"close,omitempty"`
+}
+
+// WebSocketPeriodic sends a message every IntervalMs, at most Count times
+// when Count is set
+type WebSocketPeriodic struct {
+	IntervalMs       int `yaml:"interval_ms" json:"interval_ms"`
+	Count            int `yaml:"count,omitempty" json:"count,omitempty"`
+	WebSocketMessage `yaml:",inline"`
+}
+
+// WebSocketClose closes the connection with a close frame after AfterMs.
+// Code defaults to 1000 (normal closure).
+type WebSocketClose struct {
+	Code    int    `yaml:"code,omitempty" json:"code,omitempty"`
+	Reason  string `yaml:"reason,omitempty" json:"reason,omitempty"`
+	AfterMs int    `yaml:"after_ms,omitempty" json:"after_ms,omitempty"`
+}
+
+// GraphQL matches requests by the operation they execute. All specified
+// checks must pass: Fields lists root fields the operation must select and
+// Variables must be contained in the request's variables. Data and Errors
+// form the response unless a response body is configured.
+type GraphQL struct {
+	OperationName string        `yaml:"operation_name,omitempty" json:"operation_name,omitempty"`
+	OperationType string        `yaml:"operation_type,omitempty" json:"operation_type,omitempty"`
+	Fields        []string      `yaml:"fields,omitempty" json:"fields,omitempty"`
+	Variables     interface{}   `yaml:"variables,omitempty" json:"variables,omitempty"`
+	Data          interface{}   `yaml:"data,omitempty" json:"data,omitempty"`
+	Errors        []interface{} `yaml:"errors,omitempty" json:"errors,omitempty"`
+}
+
+// HasPayload reports whether data or errors are configured
+func (g *GraphQL) HasPayload() bool {
+	return g.Data != nil || len(g.Errors) > 0
+}
+
+// Payload returns the response document holding the data and errors
+func (g *GraphQL) Payload() map[string]interface{} {
+	payload := make(map[string]interface{})
+	if g.Data != nil {
+		payload["data"] = g.Data
+	}
+	if len(g.Errors) > 0 {
+		payload["errors"] = g.Errors
+	}
+	return payload
+}
+
+// GetJSONSafe returns the matcher and payload with YAML maps converted for
+// JSON, keyed like the configuration file
+func (g *GraphQL) GetJSONSafe() map[string]interface{} {
+	result := make(map[string]interface{})
+	if g.OperationName != "" {
+		result["operation_name"] = g.OperationName
+	}
+	if g.OperationType != "" {
+		result["operation_type"] = g.OperationType
+	}
+	if len(g.Fields) > 0 {
+		result["fields"] = g.Fields
+	}
+	if g.Variables != nil {
+		result["variables"] = convertYAMLToJSON(g.Variables)
+	}
+	if g.Data != nil {
+		result["data"] = convertYAMLToJSON(g.Data)
+	}
+	if len(g.Errors) > 0 {
+		result["errors"] = convertYAMLToJSON(g.Errors)
+	}
+	return result
+}
+
+// ResponseVariant is one entry of a route's responses list. Empty fields
+// fall back to the route's own values; headers are merged. Weight is only
+// used in random mode and defaults to 1.
+type ResponseVariant struct {
+	StatusCode  int               `yaml:"status_code,omitempty" json:"status_code,omitempty"`
+	ContentType string            `yaml:"content_type,omitempty" json:"content_type,omitempty"`
+	Response    interface{}       `yaml:"response,omitempty" json:"response,omitempty"`
+	BodyFile    string            `yaml:"body_file,omitempty" json:"body_file,omitempty"`
+	BodyBase64  string            `yaml:"body_base64,omitempty" json:"body_base64,omitempty"`
+	Headers     map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Weight      int               `yaml:"weight,omitempty" json:"weight,omitempty"`
+}
+
+// RequestMatch holds additional request predicates a route requires
+type RequestMatch struct {
+	Headers map[string]HeaderMatcher `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Body    *BodyMatcher             `yaml:"body,omitempty" json:"body,omitempty"`
+	// SOAPAction requires the SOAPAction header of SOAP 1.1, or the action
+	// parameter of the SOAP 1.2 content type, to equal this value
+	SOAPAction string `yaml:"soap_action,omitempty" json:"soap_action,omitempty"`
+}
+
+// BodyMatcher describes the checks applied to a JSON or XML request body.
+// All specified checks must pass.
+type BodyMatcher struct {
+	// EqualToJSON requires the body to equal this document exactly
+	EqualToJSON interface{} `yaml:"equal_to_json,omitempty" json:"equal_to_json,omitempty"`
+	// PartialJSON requires the body to contain this document as a subset
+	PartialJSON interface{} `yaml:"partial_json,omitempty" json:"partial_json,omitempty"`
+	// JSONPath lists predicates such as "$.user.age > 18" or "$.user.id"
+	JSONPath []string `yaml:"json_path,omitempty" json:"json_path,omitempty"`
+	// Matches maps JSONPath expressions to regexes applied to string fields
+	Matches map[string]string `yaml:"matches,omitempty" json:"matches,omitempty"`
+	// XPath lists expressions an XML body must satisfy, such as
+	// "//m:AccountId = '42'"; Namespaces binds their prefixes to URIs
+	XPath      []string          `yaml:"xpath,omitempty" json:"xpath,omitempty"`
+	Namespaces map[string]string `yaml:"namespaces,omitempty" json:"namespaces,omitempty"`
+}
+
+// ChecksJSON reports whether the body must be JSON: the matcher has JSON
+// checks, or no checks at all
+func (m *BodyMatcher) ChecksJSON() bool {
+	return m.EqualToJSON != nil || m.PartialJSON != nil || len(m.JSONPath) > 0 || len(m.Matches) > 0 || len(m.XPath) == 0
+}
+
+// HeaderMatcher describes the checks applied to a single request header.
+// All specified checks must pass; Present set to false requires the header
+// to be absent.
+type HeaderMatcher struct {
+	Equals   string `yaml:"equals,omitempty" json:"equals,omitempty"`
+	Contains string `yaml:"contains,omitempty" json:"contains,omitempty"`
+	Matches  string `yaml:"matches,omitempty" json:"matches,omitempty"`
+	Present  *bool  `yaml:"present,omitempty" json:"present,omitempty"`
 }
 
 // GetJSONSafeResponse returns a JSON-safe version of the response
@@ -23,9 +363,70 @@ func (r *Route) GetJSONSafeResponse() interface{} {
 	return convertYAMLToJSON(r.Response)
 }
 
+// GetJSONSafeResponse returns a JSON-safe version of the response
+func (v *ResponseVariant) GetJSONSafeResponse() interface{} {
+	return convertYAMLToJSON(v.Response)
+}
+
+// UnmarshalYAML decodes a route. YAML maps decode unordered, so XML
+// responses are decoded again as yaml.MapSlice to write their elements in
+// the order they are configured.
+func (r *Route) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Route
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+
+	var ordered struct {
+		Response  orderedValue `yaml:"response"`
+		Responses []struct {
+			Response orderedValue `yaml:"response"`
+		} `yaml:"responses"`
+	}
+	if err := unmarshal(&ordered); err != nil {
+		return err
+	}
+	if xmldoc.IsXMLContentType(r.ContentType) {
+		r.Response = ordered.Response.value
+	}
+	for i := range r.Responses {
+		contentType := r.Responses[i].ContentType
+		if contentType == "" {
+			contentType = r.ContentType
+		}
+		if xmldoc.IsXMLContentType(contentType) && i < len(ordered.Responses) {
+			r.Responses[i].Response = ordered.Responses[i].Response.value
+		}
+	}
+	return nil
+}
+
+// orderedValue decodes a YAML value with its maps as yaml.MapSlice
+type orderedValue struct {
+	value interface{}
+}
+
+// UnmarshalYAML decodes maps as yaml.MapSlice and anything else as it is
+func (v *orderedValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var ordered yaml.MapSlice
+	if err := unmarshal(&ordered); err == nil {
+		v.value = ordered
+		return nil
+	}
+	return unmarshal(&v.value)
+}
+
 // convertYAMLToJSON converts YAML interface{} types to JSON-compatible types
 func convertYAMLToJSON(data interface{}) interface{} {
 	switch v := data.(type) {
+	case yaml.MapSlice:
+		result := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			if strKey, ok := item.Key.(string); ok {
+				result[strKey] = convertYAMLToJSON(item.Value)
+			}
+		}
+		return result
 	case map[interface{}]interface{}:
 		// Convert map[interface{}]interface{} to map[string]interface{}
 		result := make(map[string]interface{})
@@ -50,7 +451,22 @@ func convertYAMLToJSON(data interface{}) interface{} {
 
 // Config represents the entire mock configuration
 type Config struct {
-	Routes []Route `yaml:"routes" json:"routes"`
+	// Delay applies to every route that does not define its own delay
+	Delay *Delay `yaml:"delay,omitempty" json:"delay,omitempty"`
+	// Proxy forwards requests that match no route to an upstream server
+	Proxy *ProxyConfig `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	// Validation checks every request against an OpenAPI document before
+	// it is matched
+	Validation *Validation `yaml:"validation,omitempty" json:"validation,omitempty"`
+	// GRPC serves mocked gRPC services on a separate port
+	GRPC *GRPC `yaml:"grpc,omitempty" json:"grpc,omitempty"`
+	// Include loads the routes of further files; glob patterns are relative
+	// to the including file
+	Include []string `yaml:"include,omitempty" json:"include,omitempty"`
+	Routes  []Route  `yaml:"routes" json:"routes"`
+
+	// files lists the files the configuration was read from
+	files []*configFile
 }
 
 // Manager handles configuration loading, saving, and management
@@ -68,26 +484,48 @@ func NewManager(configPath string) *Manager {
 
 // Load loads the configuration from the file
 func (m *Manager) Load() error {
-	data, err := os.ReadFile(m.configPath)
+	config, err := m.Read()
 	if err != nil {
-		return fmt.Errorf("failed to read config file %s: %w", m.configPath, err)
+		return err
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("failed to parse YAML config: %w", err)
+	m.config = config
+	return nil
+}
+
+// Read parses the configuration without replacing the current one. The
+// configuration path may be a file or a directory, whose *.yaml, *.yml and
+// *.json files are read in name order; included files are read after the
+// file including them.
+func (m *Manager) Read() (*Config, error) {
+	info, err := os.Stat(m.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", m.configPath, err)
 	}
 
-	m.config = &config
-	return nil
+	r := newConfigReader()
+	if info.IsDir() {
+		err = r.readDir(m.configPath)
+	} else {
+		err = r.readFile(m.configPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.config, nil
 }
 
-// Save saves the current configuration to the file
+// Save saves the current configuration. Routes are written back to the file
+// they were loaded from; new routes go to the first file.
 func (m *Manager) Save() error {
 	if m.config == nil {
 		return fmt.Errorf("no configuration to save")
 	}
 
+	if len(m.config.files) > 0 {
+		return m.config.saveFiles()
+	}
+
 	data, err := yaml.Marshal(m.config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config to YAML: %w", err)
@@ -134,6 +572,9 @@ func (m *Manager) UpdateRoute(path, method string, newRoute Route) error {
 
 	for i, route := range m.config.Routes {
 		if route.Path == path && route.Method == method {
+			if newRoute.Source == "" {
+				newRoute.Source = route.Source
+			}
 			m.config.Routes[i] = newRoute
 			return nil
 		}
@@ -225,10 +666,419 @@ func (m *Manager) ValidateRoute(route Route) error {
 		return fmt.Errorf("invalid HTTP method: %s", route.Method)
 	}
 
-	if route.StatusCode < 100 || route.StatusCode > 599 {
+	// Proxied routes take their status code from the upstream
+	if (route.StatusCode < 100 || route.StatusCode > 599) && !(route.Proxy != "" && route.StatusCode == 0) {
 		return fmt.Errorf("invalid status code: %d", route.StatusCode)
 	}
 
+	if HasPathParams(route.Path) {
+		if _, err := CompilePathPattern(route.Path); err != nil {
+			return err
+		}
+	}
+
+	if route.Match != nil {
+		if err := validateRequestMatch(route.Match); err != nil {
+			return err
+		}
+	}
+
+	if err := validateResponses(route); err != nil {
+		return err
+	}
+
+	if route.Delay != nil {
+		if err := ValidateDelay(route.Delay); err != nil {
+			return err
+		}
+	}
+
+	if route.SSE != nil {
+		if err := validateSSE(route); err != nil {
+			return err
+		}
+	}
+
+	if route.WebSocket != nil {
+		if err := validateWebSocket(route); err != nil {
+			return err
+		}
+	}
+
+	if route.GraphQL != nil {
+		if err := validateGraphQL(route); err != nil {
+			return err
+		}
+	}
+
+	if route.Dribble != nil && (route.Dribble.Chunks < 1 || route.Dribble.DurationMs < 0) {
+		return fmt.Errorf("dribble needs at least one chunk and a non-negative duration")
+	}
+
+	if route.Proxy != "" {
+		if err := ValidateProxyTarget(route.Proxy); err != nil {
+			return err
+		}
+	}
+
+	if route.Validation != nil {
+		if err := ValidateValidation(route.Validation); err != nil {
+			return err
+		}
+	}
+
+	switch route.Fault {
+	case "", FaultConnectionReset, FaultEmptyResponse, FaultRandomData, FaultMalformedChunk, FaultTruncatedBody:
+	default:
+		return fmt.Errorf("invalid fault: %s", route.Fault)
+	}
+	if route.Fault != "" && (route.SSE != nil || route.WebSocket != nil || route.Proxy != "") {
+		return fmt.Errorf("fault cannot be combined with sse, websocket or proxy")
+	}
+	if route.Fault == FaultTruncatedBody && route.Response == nil && route.BodyBase64 == "" && len(route.Responses) == 0 &&
+		(route.GraphQL == nil || !route.GraphQL.HasPayload()) {
+		return fmt.Errorf("fault %s needs a response body to truncate", route.Fault)
+	}
+
+	if route.Scenario == "" && (route.RequiredState != "" || route.NewState != "") {
+		return fmt.Errorf("required_state and new_state need a scenario")
+	}
+
+	return nil
+}
+
+// ValidateConfig validates the global settings and every route of a
+// configuration
+func (m *Manager) ValidateConfig(config *Config) error {
+	if config.Proxy != nil {
+		if err := ValidateProxyTarget(config.Proxy.Target); err != nil {
+			return err
+		}
+	}
+
+	if config.Delay != nil {
+		if err := ValidateDelay(config.Delay); err != nil {
+			return err
+		}
+	}
+
+	if config.Validation != nil {
+		if err := ValidateValidation(config.Validation); err != nil {
+			return err
+		}
+	}
+
+	if config.GRPC != nil {
+		if err := ValidateGRPC(config.GRPC); err != nil {
+			return err
+		}
+	}
+
+	for i, route := range config.Routes {
+		if err := m.ValidateRoute(route); err != nil {
+			return fmt.Errorf("route %d (%s %s): %w", i+1, route.Method, route.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateProxyTarget checks that a proxy target is an absolute HTTP(S) URL
+func ValidateProxyTarget(target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("invalid proxy target %s: %w", target, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("proxy target must be an absolute http(s) URL: %s", target)
+	}
+	return nil
+}
+
+// ValidateValidation validates a request validation configuration
+func ValidateValidation(validation *Validation) error {
+	if validation.Spec == "" {
+		return fmt.Errorf("validation spec cannot be empty")
+	}
+	if validation.StatusCode != 0 && (validation.StatusCode < 100 || validation.StatusCode > 599) {
+		return fmt.Errorf("invalid validation status code: %d", validation.StatusCode)
+	}
+	return nil
+}
+
+// ValidateDelay validates a delay configuration
+func ValidateDelay(delay *Delay) error {
+	switch delay.Distribution {
+	case "", DelayFixed:
+		if delay.Ms < 0 {
+			return fmt.Errorf("fixed delay cannot be negative")
+		}
+	case DelayUniform:
+		if delay.MinMs < 0 || delay.MaxMs < delay.MinMs {
+			return fmt.Errorf("uniform delay needs 0 <= min_ms <= max_ms")
+		}
+	case DelayNormal:
+		if delay.MeanMs < 0 || delay.StdDevMs < 0 {
+			return fmt.Errorf("normal delay needs non-negative mean_ms and stddev_ms")
+		}
+	case DelayLogNormal:
+		if delay.MedianMs <= 0 || delay.Sigma < 0 {
+			return fmt.Errorf("lognormal delay needs a positive median_ms and non-negative sigma")
+		}
+	default:
+		return fmt.Errorf("invalid delay distribution: %s", delay.Distribution)
+	}
+
+	return nil
+}
+
+// validateResponses validates the responses list and response mode
+func validateResponses(route Route) error {
+	switch route.ResponseMode {
+	case "", ResponseModeStick, ResponseModeCycle, ResponseModeRandom:
+	default:
+		return fmt.Errorf("invalid response mode: %s", route.ResponseMode)
+	}
+
+	if err := validateBody(route.Response, route.BodyFile, route.BodyBase64); err != nil {
+		return err
+	}
+	if err := validateXMLResponse(route.ContentType, route.Response); err != nil {
+		return err
+	}
+
+	for i, variant := range route.Responses {
+		if err := validateBody(variant.Response, variant.BodyFile, variant.BodyBase64); err != nil {
+			return fmt.Errorf("%w in responses[%d]", err, i)
+		}
+		contentType := variant.ContentType
+		if contentType == "" {
+			contentType = route.ContentType
+		}
+		if err := validateXMLResponse(contentType, variant.Response); err != nil {
+			return fmt.Errorf("%w in responses[%d]", err, i)
+		}
+		if variant.StatusCode != 0 && (variant.StatusCode < 100 || variant.StatusCode > 599) {
+			return fmt.Errorf("invalid status code in responses[%d]: %d", i, variant.StatusCode)
+		}
+		if variant.Weight < 0 {
+			return fmt.Errorf("negative weight in responses[%d]", i)
+		}
+	}
+
+	return nil
+}
+
+// validateXMLResponse checks that structured responses with an XML content
+// type can be written as XML
+func validateXMLResponse(contentType string, response interface{}) error {
+	if !xmldoc.IsXMLContentType(contentType) {
+		return nil
+	}
+	switch value := convertYAMLToJSON(response).(type) {
+	case map[string]interface{}, []interface{}:
+		if _, err := xmldoc.Marshal(value); err != nil {
+			return fmt.Errorf("invalid XML response: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateBody checks that at most one body source is set and that a base64
+// body decodes
+func validateBody(response interface{}, bodyFile, bodyBase64 string) error {
+	sources := 0
+	for _, set := range []bool{response != nil, bodyFile != "", bodyBase64 != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("only one of response, body_file and body_base64 can be set")
+	}
+
+	if bodyBase64 != "" {
+		if _, err := DecodeBase64(bodyBase64); err != nil {
+			return fmt.Errorf("invalid body_base64: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateSSE validates the event stream of a route
+func validateSSE(route Route) error {
+	if route.Response != nil || route.BodyFile != "" || route.BodyBase64 != "" || len(route.Responses) > 0 {
+		return fmt.Errorf("sse cannot be combined with a response body")
+	}
+	if len(route.SSE.Events) == 0 {
+		return fmt.Errorf("sse needs at least one event")
+	}
+
+	totalDelay := 0
+	for i, event := range route.SSE.Events {
+		if event.DelayMs < 0 || event.Retry < 0 {
+			return fmt.Errorf("negative delay_ms or retry in sse event %d", i)
+		}
+		totalDelay += event.DelayMs
+	}
+	if route.SSE.Loop && totalDelay == 0 {
+		return fmt.Errorf("looping sse events need a delay_ms")
+	}
+	return nil
+}
+
+// validateWebSocket validates the scripted conversation of a route
+func validateWebSocket(route Route) error {
+	if route.Method != "GET" {
+		return fmt.Errorf("websocket routes must use GET")
+	}
+	if route.Response != nil || route.BodyFile != "" || route.BodyBase64 != "" || len(route.Responses) > 0 || route.SSE != nil {
+		return fmt.Errorf("websocket cannot be combined with a response body or sse")
+	}
+
+	ws := route.WebSocket
+	for i := range ws.OnConnect {
+		if err := validateWebSocketMessage(&ws.OnConnect[i]); err != nil {
+			return fmt.Errorf("%w in on_connect[%d]", err, i)
+		}
+	}
+	for i, reply := range ws.Replies {
+		if reply.Matches != "" {
+			if _, err := regexp.Compile(reply.Matches); err != nil {
+				return fmt.Errorf("invalid regex in replies[%d]: %w", i, err)
+			}
+		}
+		if reply.JSON != nil {
+			if len(reply.JSON.XPath) > 0 {
+				return fmt.Errorf("json matcher cannot use xpath in replies[%d]", i)
+			}
+			if err := validateRequestMatch(&RequestMatch{Body: reply.JSON}); err != nil {
+				return fmt.Errorf("%w in replies[%d]", err, i)
+			}
+		}
+		for j := range reply.Messages {
+			if err := validateWebSocketMessage(&reply.Messages[j]); err != nil {
+				return fmt.Errorf("%w in replies[%d].messages[%d]", err, i, j)
+			}
+		}
+		if err := validateWebSocketClose(reply.Close); err != nil {
+			return fmt.Errorf("%w in replies[%d]", err, i)
+		}
+	}
+	for i := range ws.Periodic {
+		periodic := &ws.Periodic[i]
+		if periodic.IntervalMs <= 0 || periodic.Count < 0 {
+			return fmt.Errorf("periodic[%d] needs a positive interval_ms and a non-negative count", i)
+		}
+		if err := validateWebSocketMessage(&periodic.WebSocketMessage); err != nil {
+			return fmt.Errorf("%w in periodic[%d]", err, i)
+		}
+	}
+	return validateWebSocketClose(ws.Close)
+}
+
+// validateGraphQL validates the operation matcher and payload of a route
+func validateGraphQL(route Route) error {
+	if route.Method != "GET" && route.Method != "POST" {
+		return fmt.Errorf("graphql routes must use GET or POST")
+	}
+	if route.SSE != nil || route.WebSocket != nil {
+		return fmt.Errorf("graphql cannot be combined with sse or websocket")
+	}
+
+	graphQL := route.GraphQL
+	switch graphQL.OperationType {
+	case "", "query", "mutation", "subscription":
+	default:
+		return fmt.Errorf("invalid graphql operation_type: %s", graphQL.OperationType)
+	}
+	if graphQL.Variables != nil {
+		if _, ok := convertYAMLToJSON(graphQL.Variables).(map[string]interface{}); !ok {
+			return fmt.Errorf("graphql variables must be a map")
+		}
+	}
+	if graphQL.HasPayload() && (route.Response != nil || route.BodyFile != "" || route.BodyBase64 != "") {
+		return fmt.Errorf("graphql data and errors cannot be combined with a response body")
+	}
+	return nil
+}
+
+// validateWebSocketMessage validates a scripted WebSocket message
+func validateWebSocketMessage(message *WebSocketMessage) error {
+	if message.Data != nil && message.DataBase64 != "" {
+		return fmt.Errorf("data and data_base64 cannot both be set")
+	}
+	if message.DelayMs < 0 {
+		return fmt.Errorf("negative delay_ms")
+	}
+	if message.DataBase64 != "" {
+		if _, err := DecodeBase64(message.DataBase64); err != nil {
+			return fmt.Errorf("invalid data_base64: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateWebSocketClose validates a close frame; codes 1004-1006 and 1015
+// are reserved and cannot be sent
+func validateWebSocketClose(close *WebSocketClose) error {
+	if close == nil {
+		return nil
+	}
+	if close.AfterMs < 0 {
+		return fmt.Errorf("negative close after_ms")
+	}
+	switch {
+	case close.Code == 0:
+	case close.Code < 1000 || close.Code > 4999, close.Code >= 1004 && close.Code <= 1006, close.Code == 1015:
+		return fmt.Errorf("invalid close code: %d", close.Code)
+	}
+	return nil
+}
+
+// DecodeBase64 decodes a body_base64 value, ignoring line breaks and other
+// whitespace
+func DecodeBase64(encoded string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+}
+
+// validateRequestMatch validates the request predicates of a route
+func validateRequestMatch(match *RequestMatch) error {
+	for name, matcher := range match.Headers {
+		if matcher.Equals == "" && matcher.Contains == "" && matcher.Matches == "" && matcher.Present == nil {
+			return fmt.Errorf("header matcher for %s has no checks", name)
+		}
+		if matcher.Present != nil && !*matcher.Present && (matcher.Equals != "" || matcher.Contains != "" || matcher.Matches != "") {
+			return fmt.Errorf("header matcher for %s cannot require absence and a value", name)
+		}
+		if matcher.Matches != "" {
+			if _, err := regexp.Compile(matcher.Matches); err != nil {
+				return fmt.Errorf("invalid regex for header %s: %w", name, err)
+			}
+		}
+	}
+
+	if match.Body != nil {
+		for _, expr := range match.Body.JSONPath {
+			if _, err := jsonpath.ParsePredicate(expr); err != nil {
+				return fmt.Errorf("invalid body JSONPath predicate: %w", err)
+			}
+		}
+		for expr, pattern := range match.Body.Matches {
+			if _, err := jsonpath.Compile(expr); err != nil {
+				return fmt.Errorf("invalid body JSONPath: %w", err)
+			}
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid regex for body field %s: %w", expr, err)
+			}
+		}
+		for _, expr := range match.Body.XPath {
+			if _, err := xmldoc.Compile(expr, match.Body.Namespaces); err != nil {
+				return fmt.Errorf("invalid body XPath: %w", err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -237,6 +1087,24 @@ func (m *Manager) GetConfigPath() string {
 	return m.configPath
 }
 
+// BaseDir returns the configuration directory, or the directory of the
+// configuration file
+func (m *Manager) BaseDir() string {
+	if info, err := os.Stat(m.configPath); err == nil && info.IsDir() {
+		return m.configPath
+	}
+	return filepath.Dir(m.configPath)
+}
+
+// ResolveSource returns the file a route with the given source is saved to,
+// resolving relative sources against the configuration directory
+func (m *Manager) ResolveSource(source string) (string, error) {
+	if m.config == nil {
+		return "", nil
+	}
+	return m.config.ResolveSource(m.BaseDir(), source)
+}
+
 // SetConfigPath sets the configuration file path
 func (m *Manager) SetConfigPath(path string) {
 	m.configPath = path
@@ -259,6 +1127,21 @@ func (m *Manager) Clone() *Config {
 		return nil
 	}
 
+	// Keep what YAML does not carry
+	for i := range cloned.Routes {
+		cloned.Routes[i].Source = m.config.Routes[i].Source
+		if cloned.Routes[i].Validation != nil {
+			cloned.Routes[i].Validation.dir = m.config.Routes[i].Validation.dir
+		}
+	}
+	if cloned.Validation != nil {
+		cloned.Validation.dir = m.config.Validation.dir
+	}
+	if cloned.GRPC != nil {
+		cloned.GRPC.dir = m.config.GRPC.dir
+	}
+	cloned.files = m.config.files
+
 	return &cloned
 }
 
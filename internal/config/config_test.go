@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"gopkg.in/yaml.v2"
 )
 
 func TestNewManager(t *testing.T) {
@@ -356,6 +358,20 @@ func TestClone(t *testing.T) {
 	}
 }
 
+func TestCloneKeepsSpecPaths(t *testing.T) {
+	manager := NewManager("test.yaml")
+	manager.AddRoute(Route{Path: "/api/test", Method: "GET", StatusCode: 200, Validation: &Validation{Spec: "users.yaml", dir: "specs/users"}})
+	manager.GetConfig().Validation = &Validation{Spec: "api.yaml", dir: "specs"}
+
+	cloned := manager.Clone()
+	if path := cloned.Validation.SpecPath(); path != filepath.Join("specs", "api.yaml") {
+		t.Errorf("Expected the global spec path to be kept, got %s", path)
+	}
+	if path := cloned.Routes[0].Validation.SpecPath(); path != filepath.Join("specs", "users", "users.yaml") {
+		t.Errorf("Expected the route spec path to be kept, got %s", path)
+	}
+}
+
 func TestLoadFromBytes(t *testing.T) {
 	manager := NewManager("test.yaml")
 
@@ -423,3 +439,502 @@ func TestToBytes(t *testing.T) {
 		t.Errorf("Expected converted response 'converted to bytes', got '%v'", convertedRoute.Response)
 	}
 }
+
+func TestLoadHeaderMatch(t *testing.T) {
+	manager := NewManager("test.yaml")
+
+	configData := []byte(`routes:
+  - path: "/api/users"
+    method: "GET"
+    status_code: 200
+    match:
+      headers:
+        Accept:
+          contains: "application/xml"
+        Authorization:
+          present: false
+`)
+
+	if err := manager.LoadFromBytes(configData); err != nil {
+		t.Fatalf("Failed to load from bytes: %v", err)
+	}
+
+	route := manager.GetRoutes()[0]
+	if route.Match == nil {
+		t.Fatal("Expected match block to be loaded")
+	}
+	if route.Match.Headers["Accept"].Contains != "application/xml" {
+		t.Errorf("Expected Accept contains 'application/xml', got '%s'", route.Match.Headers["Accept"].Contains)
+	}
+	present := route.Match.Headers["Authorization"].Present
+	if present == nil || *present {
+		t.Error("Expected Authorization to require absence")
+	}
+
+	if err := manager.ValidateRoute(route); err != nil {
+		t.Errorf("Expected valid route, got error: %v", err)
+	}
+}
+
+func TestValidateHeaderMatch(t *testing.T) {
+	manager := NewManager("test.yaml")
+	absent := false
+
+	tests := []struct {
+		name    string
+		matcher HeaderMatcher
+		wantErr bool
+	}{
+		{"Equals", HeaderMatcher{Equals: "application/json"}, false},
+		{"Regex", HeaderMatcher{Matches: "^Bearer .+$"}, false},
+		{"Absent", HeaderMatcher{Present: &absent}, false},
+		{"No checks", HeaderMatcher{}, true},
+		{"Invalid regex", HeaderMatcher{Matches: "[a-"}, true},
+		{"Absent with value", HeaderMatcher{Present: &absent, Equals: "x"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := Route{
+				Path:       "/api/test",
+				Method:     "GET",
+				StatusCode: 200,
+				Match:      &RequestMatch{Headers: map[string]HeaderMatcher{"X-Test": tt.matcher}},
+			}
+			err := manager.ValidateRoute(route)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRoute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateBodyMatch(t *testing.T) {
+	manager := NewManager("test.yaml")
+
+	tests := []struct {
+		name    string
+		matcher BodyMatcher
+		wantErr bool
+	}{
+		{"Predicate", BodyMatcher{JSONPath: []string{"$.user.age > 18"}}, false},
+		{"Regex", BodyMatcher{Matches: map[string]string{"$.email": "@example\\.com$"}}, false},
+		{"Invalid predicate", BodyMatcher{JSONPath: []string{"$.user.age > abc"}}, true},
+		{"Invalid path", BodyMatcher{Matches: map[string]string{"$.items[": ".*"}}, true},
+		{"Invalid regex", BodyMatcher{Matches: map[string]string{"$.email": "[a-"}}, true},
+		{"XPath", BodyMatcher{XPath: []string{"//m:AccountId = '42'"}, Namespaces: map[string]string{"m": "urn:billing"}}, false},
+		{"Unbound XPath prefix", BodyMatcher{XPath: []string{"//m:AccountId"}}, true},
+		{"Invalid XPath", BodyMatcher{XPath: []string{"//AccountId["}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher := tt.matcher
+			route := Route{
+				Path:       "/api/test",
+				Method:     "POST",
+				StatusCode: 200,
+				Match:      &RequestMatch{Body: &matcher},
+			}
+			err := manager.ValidateRoute(route)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRoute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateScenario(t *testing.T) {
+	manager := NewManager("test.yaml")
+
+	route := Route{Path: "/api/order", Method: "GET", StatusCode: 200, Scenario: "order", RequiredState: ScenarioStarted, NewState: "SHIPPED"}
+	if err := manager.ValidateRoute(route); err != nil {
+		t.Errorf("Expected valid route, got error: %v", err)
+	}
+
+	route.Scenario = ""
+	if err := manager.ValidateRoute(route); err == nil {
+		t.Error("Expected error for state without scenario")
+	}
+}
+
+func TestValidateResponses(t *testing.T) {
+	manager := NewManager("test.yaml")
+
+	route := Route{
+		Path: "/api/jobs", Method: "GET", StatusCode: 200, ResponseMode: ResponseModeCycle,
+		Responses: []ResponseVariant{{StatusCode: 202}, {Response: "done"}},
+	}
+	if err := manager.ValidateRoute(route); err != nil {
+		t.Errorf("Expected valid route, got error: %v", err)
+	}
+
+	route.ResponseMode = "shuffle"
+	if err := manager.ValidateRoute(route); err == nil {
+		t.Error("Expected error for invalid response mode")
+	}
+
+	route.ResponseMode = ""
+	route.Responses = []ResponseVariant{{StatusCode: 700}}
+	if err := manager.ValidateRoute(route); err == nil {
+		t.Error("Expected error for invalid status code in responses")
+	}
+
+	route.Responses = []ResponseVariant{{Weight: -1}}
+	if err := manager.ValidateRoute(route); err == nil {
+		t.Error("Expected error for negative weight")
+	}
+
+	route.Responses = []ResponseVariant{{BodyFile: "done.json", Response: "done"}}
+	if err := manager.ValidateRoute(route); err == nil {
+		t.Error("Expected error for both response and body_file in responses")
+	}
+
+	route.Responses = nil
+	route.BodyFile, route.Response = "report.pdf", "inline"
+	if err := manager.ValidateRoute(route); err == nil {
+		t.Error("Expected error for both response and body_file")
+	}
+
+	route.BodyFile, route.Response, route.BodyBase64 = "", nil, "not base64!"
+	if err := manager.ValidateRoute(route); err == nil {
+		t.Error("Expected error for invalid body_base64")
+	}
+
+	route.BodyBase64 = "iVBO\nRw0K\nGgo="
+	if err := manager.ValidateRoute(route); err != nil {
+		t.Errorf("Expected wrapped base64 to be valid, got error: %v", err)
+	}
+}
+
+func TestValidateDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		delay   Delay
+		wantErr bool
+	}{
+		{"Fixed", Delay{Ms: 100}, false},
+		{"Uniform", Delay{Distribution: DelayUniform, MinMs: 10, MaxMs: 50}, false},
+		{"Normal", Delay{Distribution: DelayNormal, MeanMs: 100, StdDevMs: 20}, false},
+		{"Lognormal", Delay{Distribution: DelayLogNormal, MedianMs: 100, Sigma: 0.5}, false},
+		{"Negative fixed", Delay{Ms: -1}, true},
+		{"Uniform min above max", Delay{Distribution: DelayUniform, MinMs: 50, MaxMs: 10}, true},
+		{"Lognormal without median", Delay{Distribution: DelayLogNormal, Sigma: 0.5}, true},
+		{"Unknown distribution", Delay{Distribution: "poisson"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDelay(&tt.delay)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDelay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFault(t *testing.T) {
+	manager := NewManager("test.yaml")
+
+	route := Route{Path: "/api/test", Method: "GET", StatusCode: 200, Fault: FaultConnectionReset}
+	if err := manager.ValidateRoute(route); err != nil {
+		t.Errorf("Expected valid route, got error: %v", err)
+	}
+
+	route.Fault = "explode"
+	if err := manager.ValidateRoute(route); err == nil {
+		t.Error("Expected error for unknown fault")
+	}
+
+	route.Fault = FaultTruncatedBody
+	if err := manager.ValidateRoute(route); err == nil {
+		t.Error("Expected error for truncated_body without a response")
+	}
+
+	for name, other := range map[string]Route{
+		"sse":       {SSE: &SSE{Events: []SSEEvent{{Data: "x"}}}},
+		"websocket": {WebSocket: &WebSocket{OnConnect: []WebSocketMessage{{Data: "x"}}}},
+		"proxy":     {Proxy: "http://localhost:9000"},
+	} {
+		other.Path, other.Method, other.StatusCode, other.Fault = "/api/test", "GET", 200, FaultConnectionReset
+		if err := manager.ValidateRoute(other); err == nil {
+			t.Errorf("Expected error for a fault combined with %s", name)
+		}
+	}
+	route.Response = "partial"
+	if err := manager.ValidateRoute(route); err != nil {
+		t.Errorf("Expected valid route, got error: %v", err)
+	}
+}
+
+func TestValidateProxy(t *testing.T) {
+	manager := NewManager("test.yaml")
+
+	route := Route{Path: "/api/*", Method: "GET", Proxy: "https://api.example.com/v1"}
+	if err := manager.ValidateRoute(route); err != nil {
+		t.Errorf("Expected valid route, got error: %v", err)
+	}
+
+	for _, target := range []string{"api.example.com", "ftp://api.example.com", "/relative"} {
+		route.Proxy = target
+		if err := manager.ValidateRoute(route); err == nil {
+			t.Errorf("Expected error for proxy target %s", target)
+		}
+	}
+}
+
+func TestValidateValidation(t *testing.T) {
+	manager := NewManager("test.yaml")
+
+	route := Route{Path: "/api/orders", Method: "POST", StatusCode: 201, Validation: &Validation{Spec: "orders.yaml"}}
+	if err := manager.ValidateRoute(route); err != nil {
+		t.Errorf("Expected valid route, got error: %v", err)
+	}
+
+	for _, validation := range []*Validation{{}, {Spec: "orders.yaml", StatusCode: 42}} {
+		route.Validation = validation
+		if err := manager.ValidateRoute(route); err == nil {
+			t.Errorf("Expected error for validation %+v", validation)
+		}
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	manager := NewManager("test.yaml")
+
+	valid := &Config{Routes: []Route{{Path: "/a", Method: "GET", StatusCode: 200}}}
+	if err := manager.ValidateConfig(valid); err != nil {
+		t.Errorf("Expected valid config, got error: %v", err)
+	}
+
+	invalid := []*Config{
+		{Proxy: &ProxyConfig{Target: "not a url"}},
+		{Delay: &Delay{Ms: -1}},
+		{Validation: &Validation{}},
+		{Routes: []Route{{Path: "/a", Method: "GET", StatusCode: 200}, {Path: "/b", Method: "GET"}}},
+	}
+	for _, config := range invalid {
+		if err := manager.ValidateConfig(config); err == nil {
+			t.Errorf("Expected error for config %+v", config)
+		}
+	}
+}
+
+func TestDiffRoutes(t *testing.T) {
+	oldRoutes := []Route{
+		{Path: "/a", Method: "GET", StatusCode: 200, Response: map[interface{}]interface{}{"x": 1}},
+		{Path: "/b", Method: "GET", StatusCode: 200},
+		{Path: "/c", Method: "GET", StatusCode: 200},
+		{Path: "/c", Method: "GET", StatusCode: 404},
+	}
+	newRoutes := []Route{
+		{Path: "/a", Method: "GET", StatusCode: 200, Response: map[string]interface{}{"x": 1}},
+		{Path: "/b", Method: "GET", StatusCode: 500},
+		{Path: "/c", Method: "GET", StatusCode: 200},
+		{Path: "/d", Method: "POST", StatusCode: 201},
+	}
+
+	diff := DiffRoutes(oldRoutes, newRoutes)
+	want := "1 added (POST /d), 1 removed (GET /c #2), 1 changed (GET /b)"
+	if diff.String() != want {
+		t.Errorf("Expected %q, got %q", want, diff.String())
+	}
+	if !DiffRoutes(oldRoutes, oldRoutes).Empty() {
+		t.Error("Expected no differences between identical routes")
+	}
+}
+
+func TestRouteKey(t *testing.T) {
+	routes := []Route{
+		{Method: "GET", Path: "/a"},
+		{Method: "POST", Path: "/a"},
+		{Method: "GET", Path: "/a"},
+	}
+
+	expected := []string{"GET /a", "POST /a", "GET /a #2"}
+	for i, key := range expected {
+		if result := RouteKey(routes, i); result != key {
+			t.Errorf("RouteKey(%d) = %s, expected %s", i, result, key)
+		}
+	}
+}
+
+func TestValidateSSE(t *testing.T) {
+	manager := NewManager("test.yaml")
+	event := SSEEvent{Data: "hello"}
+
+	tests := []struct {
+		name  string
+		sse   *SSE
+		body  interface{}
+		valid bool
+	}{
+		{"Single event", &SSE{Events: []SSEEvent{event}}, nil, true},
+		{"Looping with delay", &SSE{Loop: true, Events: []SSEEvent{{Data: "tick", DelayMs: 100}}}, nil, true},
+		{"No events", &SSE{}, nil, false},
+		{"Looping without delay", &SSE{Loop: true, Events: []SSEEvent{event}}, nil, false},
+		{"Negative retry", &SSE{Events: []SSEEvent{{Retry: -1}}}, nil, false},
+		{"With response", &SSE{Events: []SSEEvent{event}}, "body", false},
+	}
+
+	for _, tt := range tests {
+		route := Route{Path: "/events", Method: "GET", StatusCode: 200, SSE: tt.sse, Response: tt.body}
+		if err := manager.ValidateRoute(route); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got error %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestValidateWebSocket(t *testing.T) {
+	manager := NewManager("test.yaml")
+	message := WebSocketMessage{Data: "hello"}
+
+	tests := []struct {
+		name   string
+		method string
+		ws     *WebSocket
+		valid  bool
+	}{
+		{"Script", "GET", &WebSocket{
+			OnConnect: []WebSocketMessage{message},
+			Replies:   []WebSocketReply{{Matches: "^ping$", Messages: []WebSocketMessage{{Data: "pong"}}}},
+			Periodic:  []WebSocketPeriodic{{IntervalMs: 1000, WebSocketMessage: message}},
+			Close:     &WebSocketClose{Code: 4001, AfterMs: 5000},
+		}, true},
+		{"Not GET", "POST", &WebSocket{}, false},
+		{"Invalid regex", "GET", &WebSocket{Replies: []WebSocketReply{{Matches: "("}}}, false},
+		{"Invalid JSON matcher", "GET", &WebSocket{Replies: []WebSocketReply{{JSON: &BodyMatcher{Matches: map[string]string{"$.id": "("}}}}}, false},
+		{"Both data and base64", "GET", &WebSocket{OnConnect: []WebSocketMessage{{Data: "a", DataBase64: "AA=="}}}, false},
+		{"Periodic without interval", "GET", &WebSocket{Periodic: []WebSocketPeriodic{{WebSocketMessage: message}}}, false},
+		{"Reserved close code", "GET", &WebSocket{Close: &WebSocketClose{Code: 1006}}, false},
+	}
+
+	for _, tt := range tests {
+		route := Route{Path: "/ws", Method: tt.method, StatusCode: 200, WebSocket: tt.ws}
+		if err := manager.ValidateRoute(route); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got error %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestValidateGraphQL(t *testing.T) {
+	manager := NewManager("test.yaml")
+
+	tests := []struct {
+		name  string
+		route Route
+		valid bool
+	}{
+		{"Operation", Route{Method: "POST", GraphQL: &GraphQL{
+			OperationName: "GetUser", OperationType: "query", Fields: []string{"user"},
+			Variables: map[interface{}]interface{}{"id": 1}, Data: map[interface{}]interface{}{"user": nil},
+		}}, true},
+		{"GET with response", Route{Method: "GET", Response: "ok", GraphQL: &GraphQL{Fields: []string{"user"}}}, true},
+		{"Invalid method", Route{Method: "PUT", GraphQL: &GraphQL{}}, false},
+		{"Invalid operation type", Route{Method: "POST", GraphQL: &GraphQL{OperationType: "fragment"}}, false},
+		{"Variables not a map", Route{Method: "POST", GraphQL: &GraphQL{Variables: []interface{}{1}}}, false},
+		{"Data and response", Route{Method: "POST", Response: "ok", GraphQL: &GraphQL{Data: "x"}}, false},
+	}
+
+	for _, tt := range tests {
+		tt.route.Path, tt.route.StatusCode = "/graphql", 200
+		if err := manager.ValidateRoute(tt.route); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got error %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestValidateGRPC(t *testing.T) {
+	tests := []struct {
+		name  string
+		grpc  GRPC
+		valid bool
+	}{
+		{"Methods", GRPC{Port: 9090, Methods: []GRPCMethod{
+			{Method: "demo.v1.Greeter/SayHello", Match: map[interface{}]interface{}{"name": "Alice"}, Response: map[interface{}]interface{}{"message": "hi"}},
+			{Method: "/demo.v1.Greeter/Count", Stream: []GRPCMessage{{Message: "{}", DelayMs: 10}}, Status: &GRPCStatus{Code: "RESOURCE_EXHAUSTED"}},
+			{Method: "demo.v1.Greeter/SayHello", Status: &GRPCStatus{Code: "5"}},
+		}}, true},
+		{"Invalid port", GRPC{Port: 70000}, false},
+		{"Invalid method", GRPC{Methods: []GRPCMethod{{Method: "SayHello"}}}, false},
+		{"Match not a map", GRPC{Methods: []GRPCMethod{{Method: "demo.Greeter/SayHello", Match: "Alice"}}}, false},
+		{"Response and stream", GRPC{Methods: []GRPCMethod{{Method: "demo.Greeter/SayHello", Response: "{}", Stream: []GRPCMessage{{Message: "{}"}}}}}, false},
+		{"Negative stream delay", GRPC{Methods: []GRPCMethod{{Method: "demo.Greeter/Count", Stream: []GRPCMessage{{DelayMs: -1}}}}}, false},
+		{"Invalid status", GRPC{Methods: []GRPCMethod{{Method: "demo.Greeter/SayHello", Status: &GRPCStatus{Code: "MISSING"}}}}, false},
+		{"Response with error status", GRPC{Methods: []GRPCMethod{{Method: "demo.Greeter/SayHello", Response: "{}", Status: &GRPCStatus{Code: "NOT_FOUND"}}}}, false},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateGRPC(&tt.grpc); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got error %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestXMLResponsesKeepOrder(t *testing.T) {
+	manager := NewManager("test.yaml")
+	err := manager.LoadFromBytes([]byte(`
+routes:
+  - path: /soap
+    method: POST
+    content_type: text/xml
+    response: {b: {z: 1, a: 2}}
+    responses:
+      - response: {c: 1}
+      - content_type: application/json
+        response: {d: 1}
+  - path: /json
+    method: GET
+    response: {e: 1}
+`))
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	routes := manager.GetRoutes()
+	document, ok := routes[0].Response.(yaml.MapSlice)
+	if !ok {
+		t.Fatalf("Expected an ordered XML response, got %T", routes[0].Response)
+	}
+	if inner, ok := document[0].Value.(yaml.MapSlice); !ok || inner[0].Key != "z" || inner[1].Key != "a" {
+		t.Errorf("Expected nested elements in their configured order, got %v", document[0].Value)
+	}
+	if _, ok := routes[0].Responses[0].Response.(yaml.MapSlice); !ok {
+		t.Errorf("Expected variants to inherit the XML content type, got %T", routes[0].Responses[0].Response)
+	}
+	if _, ok := routes[0].Responses[1].Response.(map[interface{}]interface{}); !ok {
+		t.Errorf("Expected a JSON variant to stay a map, got %T", routes[0].Responses[1].Response)
+	}
+	if _, ok := routes[1].Response.(map[interface{}]interface{}); !ok {
+		t.Errorf("Expected a JSON response to stay a map, got %T", routes[1].Response)
+	}
+}
+
+func TestValidateXMLResponse(t *testing.T) {
+	manager := NewManager("test.yaml")
+	document := map[interface{}]interface{}{"m:Balance": map[interface{}]interface{}{"@xmlns:m": "urn:billing", "#text": 12}}
+
+	tests := []struct {
+		name  string
+		route Route
+		valid bool
+	}{
+		{"Document", Route{ContentType: "text/xml", Response: document}, true},
+		{"String", Route{ContentType: "application/soap+xml", Response: "<Balance/>"}, true},
+		{"JSON list", Route{Response: []interface{}{1}}, true},
+		{"List", Route{ContentType: "application/xml", Response: []interface{}{1}}, false},
+		{"Invalid name", Route{ContentType: "application/xml", Response: map[interface{}]interface{}{"a b": 1}}, false},
+		{"Two roots", Route{ContentType: "application/xml", Response: map[interface{}]interface{}{"a": 1, "b": 2}}, false},
+		{"Repeated root", Route{ContentType: "application/xml", Response: map[interface{}]interface{}{"a": []interface{}{1, 2}}}, false},
+		{"No root", Route{ContentType: "application/xml", Response: map[interface{}]interface{}{}}, false},
+		{"Invalid variant", Route{ContentType: "application/xml", Responses: []ResponseVariant{{Response: map[interface{}]interface{}{"@id": 1}}}}, false},
+		{"Variant content type", Route{Responses: []ResponseVariant{{ContentType: "text/xml", Response: []interface{}{1}}}}, false},
+	}
+
+	for _, tt := range tests {
+		tt.route.Path, tt.route.Method, tt.route.StatusCode = "/soap", "POST", 200
+		if err := manager.ValidateRoute(tt.route); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got error %v", tt.name, tt.valid, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// RouteDiff lists the routes added, removed and changed between two
+// configurations. Routes are named "METHOD path", with " #n" appended to the
+// nth route sharing a method and path.
+type RouteDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// DiffRoutes compares the routes of two configurations
+func DiffRoutes(oldRoutes, newRoutes []Route) RouteDiff {
+	var diff RouteDiff
+
+	oldByKey := keyRoutes(oldRoutes)
+	newByKey := keyRoutes(newRoutes)
+
+	for _, key := range routeKeys(newRoutes) {
+		old, ok := oldByKey[key]
+		if !ok {
+			diff.Added = append(diff.Added, key)
+			continue
+		}
+		if !sameRoute(old, newByKey[key]) {
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+	for _, key := range routeKeys(oldRoutes) {
+		if _, ok := newByKey[key]; !ok {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+	return diff
+}
+
+// Empty reports whether the configurations have the same routes
+func (d RouteDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String summarizes the diff, e.g. "1 added (GET /a), 0 removed, 0 changed"
+func (d RouteDiff) String() string {
+	part := func(routes []string, what string) string {
+		if len(routes) == 0 {
+			return "0 " + what
+		}
+		return fmt.Sprintf("%d %s (%s)", len(routes), what, strings.Join(routes, ", "))
+	}
+	return part(d.Added, "added") + ", " + part(d.Removed, "removed") + ", " + part(d.Changed, "changed")
+}
+
+// routeKeys names the routes in order
+func routeKeys(routes []Route) []string {
+	keys := make([]string, len(routes))
+	for i := range routes {
+		keys[i] = RouteKey(routes, i)
+	}
+	return keys
+}
+
+// keyRoutes indexes the routes by name
+func keyRoutes(routes []Route) map[string]*Route {
+	byKey := make(map[string]*Route, len(routes))
+	for i, key := range routeKeys(routes) {
+		byKey[key] = &routes[i]
+	}
+	return byKey
+}
+
+// sameRoute compares two routes by their YAML form, so that maps decoded
+// from YAML and JSON compare equal
+func sameRoute(a, b *Route) bool {
+	encodedA, errA := yaml.Marshal(a)
+	encodedB, errB := yaml.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
@@ -0,0 +1,335 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultConfigFile is where routes are saved when the configuration
+// directory has no files yet
+const DefaultConfigFile = "mock_response.yaml"
+
+// configExtensions are the extensions of the files read from a configuration
+// directory
+var configExtensions = []string{".yaml", ".yml", ".json"}
+
+// configFile is a file the configuration was read from
+type configFile struct {
+	path string
+	// settings holds the file's own settings and includes, without routes
+	settings Config
+
+	mutex sync.Mutex
+	// saved is the encoded content as last read or written, so that saving
+	// leaves unchanged files alone
+	saved []byte
+}
+
+// configReader merges configuration files into a single configuration
+type configReader struct {
+	config *Config
+	seen   map[string]bool
+	// owners names the file defining each global setting
+	owners map[string]string
+}
+
+// newConfigReader creates a reader for a new configuration
+func newConfigReader() *configReader {
+	return &configReader{
+		config: &Config{},
+		seen:   make(map[string]bool),
+		owners: make(map[string]string),
+	}
+}
+
+// readDir reads the configuration files of a directory in name order
+func (r *configReader) readDir(dir string) error {
+	paths, err := globConfigFiles(dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := r.readFile(path); err != nil {
+			return err
+		}
+	}
+
+	// Give new routes a home when the directory is empty
+	if len(r.config.files) == 0 {
+		r.config.files = append(r.config.files, &configFile{path: filepath.Join(absPath(dir), DefaultConfigFile)})
+	}
+	return nil
+}
+
+// readFile reads a configuration file and the files it includes. Files are
+// read once, even when included several times.
+func (r *configReader) readFile(path string) error {
+	path = absPath(path)
+	if r.seen[path] {
+		return nil
+	}
+	r.seen[path] = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to parse YAML config %s: %w", path, err)
+	}
+
+	file := &configFile{path: path, settings: config}
+	file.settings.Routes = nil
+	file.saved, _ = file.encode(&config)
+	r.config.files = append(r.config.files, file)
+
+	if config.Validation != nil {
+		config.Validation.dir = filepath.Dir(path)
+	}
+	if config.GRPC != nil {
+		config.GRPC.dir = filepath.Dir(path)
+	}
+	settings := []struct {
+		name    string
+		defined bool
+		apply   func()
+	}{
+		{"delay", config.Delay != nil, func() { r.config.Delay = config.Delay }},
+		{"proxy", config.Proxy != nil, func() { r.config.Proxy = config.Proxy }},
+		{"validation", config.Validation != nil, func() { r.config.Validation = config.Validation }},
+		{"grpc", config.GRPC != nil, func() { r.config.GRPC = config.GRPC }},
+	}
+	for _, setting := range settings {
+		if !setting.defined {
+			continue
+		}
+		if owner, ok := r.owners[setting.name]; ok {
+			return fmt.Errorf("%s is set in both %s and %s", setting.name, owner, path)
+		}
+		r.owners[setting.name] = path
+		setting.apply()
+	}
+
+	for i := range config.Routes {
+		config.Routes[i].Source = path
+		if validation := config.Routes[i].Validation; validation != nil {
+			validation.dir = filepath.Dir(path)
+		}
+	}
+	r.config.Routes = append(r.config.Routes, config.Routes...)
+
+	for _, pattern := range config.Include {
+		matches, err := globInclude(filepath.Dir(path), pattern)
+		if err != nil {
+			return fmt.Errorf("invalid include in %s: %w", path, err)
+		}
+		for _, match := range matches {
+			if err := r.readFile(match); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// globConfigFiles lists the configuration files of a directory in name order
+func globConfigFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config directory %s: %w", dir, err)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() && IsConfigFile(entry.Name()) {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// globInclude resolves an include pattern against the including file's
+// directory. A pattern without wildcards must name an existing file.
+func globInclude(dir, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", pattern, err)
+	}
+	if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+		return nil, fmt.Errorf("included file %s not found", pattern)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// IsConfigFile reports whether a file name has a configuration extension
+func IsConfigFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, candidate := range configExtensions {
+		if ext == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// absPath returns the cleaned absolute form of a path
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// Files returns the files the configuration was read from
+func (c *Config) Files() []string {
+	files := make([]string, len(c.files))
+	for i, file := range c.files {
+		files[i] = file.path
+	}
+	return files
+}
+
+// IncludePatterns returns the absolute include patterns of the files the
+// configuration was read from
+func (c *Config) IncludePatterns() []string {
+	var patterns []string
+	for _, file := range c.files {
+		for _, pattern := range file.settings.Include {
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(filepath.Dir(file.path), pattern)
+			}
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// ResolveSource returns the file a route with the given source is saved to.
+// Relative sources are resolved against dir; an empty source selects the
+// first file.
+func (c *Config) ResolveSource(dir, source string) (string, error) {
+	if source == "" || len(c.files) == 0 {
+		return "", nil
+	}
+	if !filepath.IsAbs(source) {
+		source = filepath.Join(dir, source)
+	}
+	source = absPath(source)
+	for _, file := range c.files {
+		if file.path == source {
+			return source, nil
+		}
+	}
+	return "", fmt.Errorf("unknown route source %s", source)
+}
+
+// saveFiles writes each file's routes and settings back to it. Routes
+// without a known source are saved to the first file and global settings to
+// the file defining them. Files whose content did not change are left alone.
+func (c *Config) saveFiles() error {
+	primary := c.files[0]
+	byPath := make(map[string]*configFile, len(c.files))
+	for _, file := range c.files {
+		byPath[file.path] = file
+	}
+
+	routes := make(map[*configFile][]Route, len(c.files))
+	for _, route := range c.Routes {
+		file := byPath[route.Source]
+		if file == nil {
+			file = primary
+		}
+		routes[file] = append(routes[file], route)
+	}
+
+	// owner returns the file that defines a setting, or the first file
+	owner := func(defined func(*Config) bool) *configFile {
+		for _, file := range c.files {
+			if defined(&file.settings) {
+				return file
+			}
+		}
+		return primary
+	}
+	delayOwner := owner(func(s *Config) bool { return s.Delay != nil })
+	proxyOwner := owner(func(s *Config) bool { return s.Proxy != nil })
+	validationOwner := owner(func(s *Config) bool { return s.Validation != nil })
+	grpcOwner := owner(func(s *Config) bool { return s.GRPC != nil })
+
+	for _, file := range c.files {
+		out := Config{Include: file.settings.Include, Routes: routes[file]}
+		if file == delayOwner {
+			out.Delay = c.Delay
+		}
+		if file == proxyOwner {
+			out.Proxy = c.Proxy
+		}
+		if file == validationOwner {
+			out.Validation = c.Validation
+		}
+		if file == grpcOwner {
+			out.GRPC = c.GRPC
+		}
+		if err := file.save(&out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// save writes the configuration to the file unless it is unchanged
+func (f *configFile) save(config *Config) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	data, err := f.encode(config)
+	if err != nil {
+		return err
+	}
+	if f.saved != nil && bytes.Equal(data, f.saved) {
+		return nil
+	}
+
+	if err := os.WriteFile(f.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file %s: %w", f.path, err)
+	}
+	f.saved = data
+	return nil
+}
+
+// encode renders the configuration as JSON for .json files and as YAML
+// otherwise
+func (f *configFile) encode(config *Config) ([]byte, error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config to YAML: %w", err)
+	}
+	if strings.ToLower(filepath.Ext(f.path)) != ".json" {
+		return data, nil
+	}
+
+	// Go through YAML so that field names and omitted fields match
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to convert config to JSON: %w", err)
+	}
+	data, err = json.MarshalIndent(convertYAMLToJSON(document), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config to JSON: %w", err)
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files below dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestLoadDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"b-orders.yaml": "routes:\n  - path: /orders\n    method: GET\n    status_code: 200\n",
+		"a-users.json":  `{"delay": {"distribution": "fixed", "ms": 10}, "routes": [{"path": "/users", "method": "GET", "status_code": 200}]}`,
+		"notes.txt":     "not a configuration file",
+	})
+
+	manager := NewManager(dir)
+	if err := manager.Load(); err != nil {
+		t.Fatalf("Failed to load directory: %v", err)
+	}
+
+	routes := manager.GetRoutes()
+	if len(routes) != 2 || routes[0].Path != "/users" || routes[1].Path != "/orders" {
+		t.Fatalf("Expected the routes of both files in name order, got %+v", routes)
+	}
+	if routes[0].Source != filepath.Join(dir, "a-users.json") {
+		t.Errorf("Expected the route source to be recorded, got %q", routes[0].Source)
+	}
+	if manager.GetConfig().Delay == nil || manager.GetConfig().Delay.Ms != 10 {
+		t.Errorf("Expected the delay from a-users.json, got %+v", manager.GetConfig().Delay)
+	}
+	if manager.BaseDir() != dir {
+		t.Errorf("Expected the directory as base, got %s", manager.BaseDir())
+	}
+}
+
+func TestLoadEmptyDirectory(t *testing.T) {
+	dir := t.TempDir()
+	manager := NewManager(dir)
+	if err := manager.Load(); err != nil {
+		t.Fatalf("Failed to load empty directory: %v", err)
+	}
+
+	manager.AddRoute(Route{Path: "/new", Method: "GET", StatusCode: 200})
+	if err := manager.Save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, DefaultConfigFile)); err != nil {
+		t.Errorf("Expected the routes to be saved to %s: %v", DefaultConfigFile, err)
+	}
+}
+
+func TestLoadIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.yaml":          "include:\n  - teams/*.yaml\n  - shared.yaml\nroutes:\n  - path: /main\n    method: GET\n    status_code: 200\n",
+		"teams/billing.yaml": "routes:\n  - path: /billing\n    method: GET\n    status_code: 200\n",
+		"teams/alpha.yaml":   "include:\n  - ../shared.yaml\nroutes:\n  - path: /alpha\n    method: GET\n    status_code: 200\n",
+		"shared.yaml":        "include:\n  - main.yaml\nroutes:\n  - path: /shared\n    method: GET\n    status_code: 200\n",
+	})
+
+	manager := NewManager(filepath.Join(dir, "main.yaml"))
+	if err := manager.Load(); err != nil {
+		t.Fatalf("Failed to load includes: %v", err)
+	}
+
+	var paths []string
+	for _, route := range manager.GetRoutes() {
+		paths = append(paths, route.Path)
+	}
+	if got := strings.Join(paths, " "); got != "/main /alpha /shared /billing" {
+		t.Errorf("Expected every file once in include order, got %s", got)
+	}
+	if files := manager.GetConfig().Files(); len(files) != 4 {
+		t.Errorf("Expected 4 files, got %v", files)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := map[string]map[string]string{
+		"proxy is set in both": {
+			"a.yaml": "proxy:\n  target: http://a.example.com\nroutes: []\n",
+			"b.yaml": "proxy:\n  target: http://b.example.com\nroutes: []\n",
+		},
+		"missing.yaml not found": {
+			"a.yaml": "include:\n  - missing.yaml\nroutes: []\n",
+		},
+		"failed to parse": {
+			"a.yaml": "routes: [\n",
+		},
+	}
+
+	for want, files := range tests {
+		dir := t.TempDir()
+		writeFiles(t, dir, files)
+		err := NewManager(dir).Load()
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error containing %q, got %v", want, err)
+		}
+	}
+}
+
+func TestSaveToSourceFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.yaml": "routes:\n  - path: /a\n    method: GET\n    status_code: 200\n",
+		"b.json": `{"routes": [{"path": "/b", "method": "GET", "status_code": 200}]}`,
+		"c.yaml": "# hand-written comments are kept while the file is unchanged\nproxy:\n  target: http://upstream.example.com\nroutes:\n  - path: /c\n    method: GET\n    status_code: 200\n",
+	})
+
+	manager := NewManager(dir)
+	if err := manager.Load(); err != nil {
+		t.Fatalf("Failed to load directory: %v", err)
+	}
+
+	source, err := manager.ResolveSource("b.json")
+	if err != nil || source != filepath.Join(dir, "b.json") {
+		t.Fatalf("Expected b.json to resolve, got %q, %v", source, err)
+	}
+	if _, err := manager.ResolveSource("unknown.yaml"); err == nil {
+		t.Error("Expected an error for an unknown source")
+	}
+
+	manager.AddRoute(Route{Path: "/b2", Method: "POST", StatusCode: 201, Source: source})
+	manager.AddRoute(Route{Path: "/new", Method: "GET", StatusCode: 200})
+	if err := manager.UpdateRoute("/a", "GET", Route{Path: "/a", Method: "GET", StatusCode: 204}); err != nil {
+		t.Fatalf("Failed to update route: %v", err)
+	}
+	if err := manager.Save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	reloaded := NewManager(filepath.Join(dir, "a.yaml"))
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Failed to reload a.yaml: %v", err)
+	}
+	routes := reloaded.GetRoutes()
+	if len(routes) != 2 || routes[0].StatusCode != 204 || routes[1].Path != "/new" {
+		t.Errorf("Expected the updated and the new route in a.yaml, got %+v", routes)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "b.json"))
+	if err != nil {
+		t.Fatalf("Failed to read b.json: %v", err)
+	}
+	var saved Config
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Expected b.json to stay JSON: %v\n%s", err, data)
+	}
+	if len(saved.Routes) != 2 || saved.Routes[1].Path != "/b2" {
+		t.Errorf("Expected the added route in b.json, got %+v", saved.Routes)
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, "c.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read c.yaml: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "# hand-written") {
+		t.Errorf("Expected the unchanged c.yaml to be left alone, got:\n%s", data)
+	}
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+// DefaultGRPCPort is the port of the gRPC listener when neither the
+// configuration nor the command line sets one
+const DefaultGRPCPort = 9090
+
+// GRPC configures the gRPC listener. Services are described by .proto files
+// or descriptor sets produced by protoc --descriptor_set_out; their methods
+// answer with the first matching entry of Methods.
+type GRPC struct {
+	Port int `yaml:"port,omitempty" json:"port,omitempty"`
+	// Protos are .proto files or glob patterns, relative to the file
+	// defining the setting; ImportPaths are searched for their imports and
+	// default to that file's directory
+	Protos      []string `yaml:"protos,omitempty" json:"protos,omitempty"`
+	ImportPaths []string `yaml:"import_paths,omitempty" json:"import_paths,omitempty"`
+	// DescriptorSets are binary FileDescriptorSet files
+	DescriptorSets []string     `yaml:"descriptor_sets,omitempty" json:"descriptor_sets,omitempty"`
+	Methods        []GRPCMethod `yaml:"methods,omitempty" json:"methods,omitempty"`
+
+	// dir is the directory of the file defining the setting
+	dir string
+}
+
+// GRPCMethod answers calls of a method whose request contains Match. Messages
+// use the JSON mapping of protobuf; strings are rendered as response
+// templates with the request available as {{.JSON}}.
+type GRPCMethod struct {
+	// Method is the full method name, e.g. helloworld.Greeter/SayHello
+	Method string      `yaml:"method" json:"method"`
+	Match  interface{} `yaml:"match,omitempty" json:"match,omitempty"`
+	// Response answers unary calls; Stream lists the messages of
+	// server-streaming calls
+	Response interface{}   `yaml:"response,omitempty" json:"response,omitempty"`
+	Stream   []GRPCMessage `yaml:"stream,omitempty" json:"stream,omitempty"`
+	// Status ends the call with an error after the stream messages
+	Status *GRPCStatus `yaml:"status,omitempty" json:"status,omitempty"`
+	// Metadata is sent as response headers
+	Metadata map[string]string `yaml:"metadata,omitempty" json:"metadata,omitempty"`
+	DelayMs  int               `yaml:"delay_ms,omitempty" json:"delay_ms,omitempty"`
+}
+
+// GRPCMessage is a message of a server-streaming call, sent after DelayMs
+type GRPCMessage struct {
+	Message interface{} `yaml:"message" json:"message"`
+	DelayMs int         `yaml:"delay_ms,omitempty" json:"delay_ms,omitempty"`
+}
+
+// GRPCStatus is the status a call ends with. Code is a name such as
+// NOT_FOUND or a number; the message is rendered as a template.
+type GRPCStatus struct {
+	Code    string `yaml:"code" json:"code"`
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+}
+
+// FullMethod returns the method name in the /package.Service/Method form
+// gRPC uses on the wire
+func (m *GRPCMethod) FullMethod() string {
+	return "/" + strings.TrimPrefix(m.Method, "/")
+}
+
+// GetJSONSafeMatch returns the match document with YAML maps converted for JSON
+func (m *GRPCMethod) GetJSONSafeMatch() interface{} {
+	return convertYAMLToJSON(m.Match)
+}
+
+// GRPCCode returns the status code
+func (s *GRPCStatus) GRPCCode() (codes.Code, error) {
+	var code codes.Code
+	if n, err := strconv.Atoi(s.Code); err == nil {
+		if n < 0 || n > int(codes.Unauthenticated) {
+			return 0, fmt.Errorf("invalid gRPC status code: %s", s.Code)
+		}
+		return codes.Code(n), nil
+	}
+	if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(s.Code)))); err != nil {
+		return 0, fmt.Errorf("invalid gRPC status code: %s", s.Code)
+	}
+	return code, nil
+}
+
+// ResolvePath resolves a path against the directory of the file defining
+// the setting when that is known
+func (g *GRPC) ResolvePath(path string) string {
+	if g.dir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(g.dir, path)
+}
+
+// SourcePatterns returns the resolved paths and glob patterns of the .proto
+// files and descriptor sets
+func (g *GRPC) SourcePatterns() []string {
+	var patterns []string
+	for _, path := range append(append([]string(nil), g.Protos...), g.DescriptorSets...) {
+		patterns = append(patterns, g.ResolvePath(path))
+	}
+	return patterns
+}
+
+// ValidateGRPC validates the gRPC settings. Whether the methods exist is
+// only known once the descriptors are loaded.
+func ValidateGRPC(grpc *GRPC) error {
+	if grpc.Port < 0 || grpc.Port > 65535 {
+		return fmt.Errorf("invalid grpc port: %d", grpc.Port)
+	}
+
+	for i := range grpc.Methods {
+		method := &grpc.Methods[i]
+		service, name, ok := strings.Cut(strings.TrimPrefix(method.Method, "/"), "/")
+		if !ok || service == "" || name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("grpc methods[%d]: method must look like package.Service/Method: %s", i, method.Method)
+		}
+		if method.Match != nil {
+			if _, ok := convertYAMLToJSON(method.Match).(map[string]interface{}); !ok {
+				return fmt.Errorf("grpc methods[%d]: match must be a map", i)
+			}
+		}
+		if method.Response != nil && len(method.Stream) > 0 {
+			return fmt.Errorf("grpc methods[%d]: response and stream cannot both be set", i)
+		}
+		if method.DelayMs < 0 {
+			return fmt.Errorf("grpc methods[%d]: negative delay_ms", i)
+		}
+		for j, message := range method.Stream {
+			if message.DelayMs < 0 {
+				return fmt.Errorf("grpc methods[%d]: negative delay_ms in stream[%d]", i, j)
+			}
+		}
+		if method.Status != nil {
+			code, err := method.Status.GRPCCode()
+			if err != nil {
+				return fmt.Errorf("grpc methods[%d]: %w", i, err)
+			}
+			if code != codes.OK && method.Response != nil {
+				return fmt.Errorf("grpc methods[%d]: response cannot be combined with an error status", i)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// RouteKey names the route at index i as "METHOD path", adding " #n" to the
+// nth route with the same method and path. Journal entries, diagnostics and
+// configuration diffs refer to routes by these keys.
+func RouteKey(routes []Route, i int) string {
+	occurrence := 0
+	for j := 0; j < i; j++ {
+		if routes[j].Method == routes[i].Method && routes[j].Path == routes[i].Path {
+			occurrence++
+		}
+	}
+
+	key := routes[i].Method + " " + routes[i].Path
+	if occurrence > 0 {
+		key += fmt.Sprintf(" #%d", occurrence+1)
+	}
+	return key
+}
+
+// RequestKey identifies equivalent requests by method, path, sorted query
+// and the hash of the JSON body document, if any. Bodies that routes do not
+// match on are left out, so requests differing only in them share a key.
+func RequestKey(method, path string, query url.Values, body interface{}) string {
+	var canonical []byte
+	if body != nil {
+		canonical, _ = json.Marshal(body)
+	}
+	sum := sha256.Sum256(canonical)
+
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, url.QueryEscape(name)+"="+url.QueryEscape(query.Get(name)))
+	}
+
+	return method + " " + path + "?" + strings.Join(pairs, "&") + " " + hex.EncodeToString(sum[:])
+}
+
+// SortedKeys returns the keys of a string map in order
+func SortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// perResponseHeaders describe a single response rather than the mocked
+// endpoint
+var perResponseHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Encoding":  true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Date":              true,
+	"Keep-Alive":        true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+// IsPerResponseHeader reports whether a response header is left out of
+// recorded and imported routes. Content-Type is among them since routes
+// keep it as their content type.
+func IsPerResponseHeader(name string) bool {
+	return perResponseHeaders[http.CanonicalHeaderKey(name)]
+}
+
+// IsJSONContentType reports whether a content type carries JSON, such as
+// application/json; charset=utf-8 or application/problem+json
+func IsJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// pathConstraints maps named constraint types to their regular expressions
+var pathConstraints = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"alpha": `[A-Za-z]+`,
+	"alnum": `[A-Za-z0-9]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// PathPattern is a compiled route path with named parameters such as
+// /api/users/{id:int}/orders/{orderId}
+type PathPattern struct {
+	regex *regexp.Regexp
+	names []string
+}
+
+// HasPathParams reports whether the route path declares named parameters
+func HasPathParams(path string) bool {
+	return strings.Contains(path, "{")
+}
+
+// CompilePathPattern compiles a route path containing {name}, {name:type}
+// or {name:regex} segments. A '*' outside of braces keeps its wildcard meaning.
+func CompilePathPattern(path string) (*PathPattern, error) {
+	path = strings.TrimSuffix(path, "/")
+
+	var expr strings.Builder
+	var names []string
+	seen := make(map[string]bool)
+
+	expr.WriteString("^")
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '{':
+			end := MatchingBrace(path, i)
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed '{' in path pattern %s", path)
+			}
+
+			name, constraint := path[i+1:end], `[^/]+`
+			if idx := strings.Index(name, ":"); idx >= 0 {
+				name, constraint = name[:idx], name[idx+1:]
+				if named, ok := pathConstraints[constraint]; ok {
+					constraint = named
+				}
+				if _, err := regexp.Compile(constraint); err != nil {
+					return nil, fmt.Errorf("invalid constraint for parameter %s: %w", name, err)
+				}
+			}
+
+			if name == "" {
+				return nil, fmt.Errorf("empty parameter name in path pattern %s", path)
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("duplicate parameter %s in path pattern %s", name, path)
+			}
+			seen[name] = true
+			names = append(names, name)
+
+			// Use generated group names so constraints may contain their own groups
+			expr.WriteString(fmt.Sprintf("(?P<param%d>%s)", len(names)-1, constraint))
+			i = end + 1
+		case '*':
+			expr.WriteString(".*")
+			i++
+		default:
+			next := strings.IndexAny(path[i:], "{*")
+			if next < 0 {
+				next = len(path) - i
+			}
+			expr.WriteString(regexp.QuoteMeta(path[i : i+next]))
+			i += next
+		}
+	}
+	expr.WriteString("$")
+
+	regex, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid path pattern %s: %w", path, err)
+	}
+
+	return &PathPattern{regex: regex, names: names}, nil
+}
+
+// Match matches the request path against the pattern and returns the
+// captured parameter values
+func (p *PathPattern) Match(requestPath string) (map[string]string, bool) {
+	requestPath = strings.TrimSuffix(requestPath, "/")
+
+	matches := p.regex.FindStringSubmatch(requestPath)
+	if matches == nil {
+		return nil, false
+	}
+
+	params := make(map[string]string, len(p.names))
+	for i, name := range p.names {
+		params[name] = matches[p.regex.SubexpIndex(fmt.Sprintf("param%d", i))]
+	}
+	return params, true
+}
+
+// Names returns the parameter names in declaration order
+func (p *PathPattern) Names() []string {
+	return p.names
+}
+
+// MatchingBrace returns the index of the '}' closing the '{' at start,
+// allowing nested braces used by regex quantifiers such as [0-9]{4}
+func MatchingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
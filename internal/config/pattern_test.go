@@ -0,0 +1,78 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestCompilePathPattern(t *testing.T) {
+	tests := []struct {
+		pattern     string
+		requestPath string
+		expected    bool
+		params      map[string]string
+	}{
+		{"/api/users/{id}", "/api/users/123", true, map[string]string{"id": "123"}},
+		{"/api/users/{id}", "/api/users/123/", true, map[string]string{"id": "123"}},
+		{"/api/users/{id}", "/api/users/123/orders", false, nil},
+		{"/api/users/{id}/orders/{orderId}", "/api/users/7/orders/abc", true, map[string]string{"id": "7", "orderId": "abc"}},
+		{"/api/users/{id:int}", "/api/users/42", true, map[string]string{"id": "42"}},
+		{"/api/users/{id:int}", "/api/users/john", false, nil},
+		{"/api/posts/{slug:[a-z-]+}", "/api/posts/hello-world", true, map[string]string{"slug": "hello-world"}},
+		{"/api/posts/{slug:[a-z-]+}", "/api/posts/Hello", false, nil},
+		{"/api/years/{year:[0-9]{4}}", "/api/years/2024", true, map[string]string{"year": "2024"}},
+		{"/api/years/{year:[0-9]{4}}", "/api/years/24", false, nil},
+		{"/api/codes/{code:(a|b)c}", "/api/codes/bc", true, map[string]string{"code": "bc"}},
+		{"/files/{name}.json", "/files/report.json", true, map[string]string{"name": "report"}},
+		{"/files/{name}.json", "/files/reportxjson", false, nil},
+		{"/api/{version}/*", "/api/v2/anything/else", true, map[string]string{"version": "v2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" vs "+tt.requestPath, func(t *testing.T) {
+			pattern, err := CompilePathPattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("Failed to compile pattern: %v", err)
+			}
+
+			params, ok := pattern.Match(tt.requestPath)
+			if ok != tt.expected {
+				t.Fatalf("Match(%s) = %v, expected %v", tt.requestPath, ok, tt.expected)
+			}
+
+			for key, value := range tt.params {
+				if params[key] != value {
+					t.Errorf("Expected param %s = '%s', got '%s'", key, value, params[key])
+				}
+			}
+		})
+	}
+}
+
+func TestCompilePathPatternInvalid(t *testing.T) {
+	invalid := []string{
+		"/api/users/{id",
+		"/api/users/{}",
+		"/api/users/{id}/{id}",
+		"/api/users/{id:[a-z}",
+	}
+
+	for _, pattern := range invalid {
+		if _, err := CompilePathPattern(pattern); err == nil {
+			t.Errorf("Expected error for pattern %s", pattern)
+		}
+	}
+}
+
+func TestValidateRoutePathPattern(t *testing.T) {
+	manager := NewManager("test.yaml")
+
+	route := Route{Path: "/api/users/{id:int}", Method: "GET", StatusCode: 200}
+	if err := manager.ValidateRoute(route); err != nil {
+		t.Errorf("Expected valid route, got error: %v", err)
+	}
+
+	route.Path = "/api/users/{id"
+	if err := manager.ValidateRoute(route); err == nil {
+		t.Error("Expected error for invalid path pattern")
+	}
+}
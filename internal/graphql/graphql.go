@@ -0,0 +1,380 @@
+// Package graphql implements the part of GraphQL needed to tell requests
+// apart: reading requests sent over HTTP and finding the type, name and root
+// fields of the operation they execute. Documents are not validated against
+// a schema.
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Operation is the operation of a document a request executes
+type Operation struct {
+	// Type is query, mutation or subscription
+	Type string
+	// Name is empty for anonymous operations
+	Name string
+	// Fields are the names of the root fields, not their aliases, including
+	// those selected through fragments
+	Fields []string
+}
+
+// ReadRequest reads a GraphQL request from the query string of GET requests
+// or from a POST body, which is either JSON or, for application/graphql, the
+// query itself
+func ReadRequest(r *http.Request, body []byte) (*Request, error) {
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		req := &Request{Query: query.Get("query"), OperationName: query.Get("operationName")}
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return nil, fmt.Errorf("invalid variables: %w", err)
+			}
+		}
+		return req, nil
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/graphql") {
+		return &Request{Query: string(body), OperationName: r.URL.Query().Get("operationName")}, nil
+	}
+
+	var req Request
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("invalid GraphQL request: %w", err)
+	}
+	return &req, nil
+}
+
+// ParseOperation parses a document and returns the operation with the given
+// name. The name may be empty when the document has a single operation.
+func ParseOperation(query, operationName string) (*Operation, error) {
+	doc, err := parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var op *operation
+	for _, candidate := range doc.operations {
+		if operationName == "" || candidate.name == operationName {
+			if op != nil {
+				return nil, fmt.Errorf("operationName is required for documents with several operations")
+			}
+			op = candidate
+		}
+	}
+	if op == nil {
+		if operationName != "" {
+			return nil, fmt.Errorf("unknown operation %s", operationName)
+		}
+		return nil, fmt.Errorf("document contains no operation")
+	}
+
+	var fields []string
+	seen := make(map[string]bool)
+	doc.collectFields(op.selections, seen, make(map[string]bool), &fields)
+	return &Operation{Type: op.kind, Name: op.name, Fields: fields}, nil
+}
+
+// document holds the operations and fragments of a parsed document
+type document struct {
+	operations []*operation
+	fragments  map[string][]selection
+}
+
+// operation is an operation definition with its root selections
+type operation struct {
+	kind       string
+	name       string
+	selections []selection
+}
+
+// selection is a field, a fragment spread or an inline fragment
+type selection struct {
+	field     string
+	spread    string
+	fragments []selection
+}
+
+// collectFields appends the root field names of the selections, following
+// fragments once each
+func (d *document) collectFields(selections []selection, seen, visited map[string]bool, fields *[]string) {
+	for _, sel := range selections {
+		switch {
+		case sel.field != "":
+			if !seen[sel.field] {
+				seen[sel.field] = true
+				*fields = append(*fields, sel.field)
+			}
+		case sel.spread != "":
+			if !visited[sel.spread] {
+				visited[sel.spread] = true
+				d.collectFields(d.fragments[sel.spread], seen, visited, fields)
+			}
+		default:
+			d.collectFields(sel.fragments, seen, visited, fields)
+		}
+	}
+}
+
+// parser reads the definitions of a document from its tokens
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+// parse parses the executable definitions of a document
+func parse(query string) (*document, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	doc := &document{fragments: make(map[string][]selection)}
+	for !p.done() {
+		switch token := p.peek(); token {
+		case "{":
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: selections})
+		case "query", "mutation", "subscription":
+			op, err := p.operationDefinition()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case "fragment":
+			p.next()
+			name := p.next()
+			if p.next() != "on" || !isName(p.next()) {
+				return nil, fmt.Errorf("invalid fragment %s", name)
+			}
+			p.skipDirectives()
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name] = selections
+		default:
+			return nil, fmt.Errorf("unexpected %q, expected an operation or fragment", token)
+		}
+	}
+	return doc, nil
+}
+
+// operationDefinition parses an operation with its type keyword
+func (p *parser) operationDefinition() (*operation, error) {
+	op := &operation{kind: p.next()}
+	if isName(p.peek()) {
+		op.name = p.next()
+	}
+	if p.peek() == "(" {
+		if err := p.skipBalanced("(", ")"); err != nil {
+			return nil, err
+		}
+	}
+	p.skipDirectives()
+
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = selections
+	return op, nil
+}
+
+// selectionSet parses the selections between braces. Only the names of
+// fields are kept; their arguments and sub-selections are skipped.
+func (p *parser) selectionSet() ([]selection, error) {
+	if p.next() != "{" {
+		return nil, fmt.Errorf("expected a selection set")
+	}
+
+	var selections []selection
+	for p.peek() != "}" {
+		if p.done() {
+			return nil, fmt.Errorf("unterminated selection set")
+		}
+
+		if p.peek() == "..." {
+			p.next()
+			if isName(p.peek()) && p.peek() != "on" {
+				selections = append(selections, selection{spread: p.next()})
+				p.skipDirectives()
+				continue
+			}
+			if p.peek() == "on" {
+				p.next()
+				p.next()
+			}
+			p.skipDirectives()
+			fragments, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			selections = append(selections, selection{fragments: fragments})
+			continue
+		}
+
+		name := p.next()
+		if !isName(name) {
+			return nil, fmt.Errorf("unexpected %q in selection set", name)
+		}
+		if p.peek() == ":" {
+			p.next()
+			name = p.next()
+		}
+		selections = append(selections, selection{field: name})
+
+		if p.peek() == "(" {
+			if err := p.skipBalanced("(", ")"); err != nil {
+				return nil, err
+			}
+		}
+		p.skipDirectives()
+		if p.peek() == "{" {
+			if err := p.skipBalanced("{", "}"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	return selections, nil
+}
+
+// skipDirectives skips directives such as @include(if: $flag)
+func (p *parser) skipDirectives() {
+	for p.peek() == "@" {
+		p.next()
+		p.next()
+		if p.peek() == "(" {
+			_ = p.skipBalanced("(", ")")
+		}
+	}
+}
+
+// skipBalanced skips tokens from an opening token to its closing token
+func (p *parser) skipBalanced(open, close string) error {
+	depth := 0
+	for !p.done() {
+		switch p.next() {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("missing %q", close)
+}
+
+// peek returns the next token without consuming it
+func (p *parser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+// next consumes and returns the next token
+func (p *parser) next() string {
+	token := p.peek()
+	if !p.done() {
+		p.pos++
+	}
+	return token
+}
+
+// done reports whether all tokens have been consumed
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+// tokenize splits a document into punctuators, names and values. Commas,
+// white space and comments are insignificant; strings become a single
+// token.
+func tokenize(query string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(query) && query[i] != '\n' && query[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(query[i:], "..."):
+			tokens = append(tokens, "...")
+			i += 3
+		case strings.ContainsRune("!$&()/:=@[]{|}", rune(c)):
+			tokens = append(tokens, string(c))
+			i++
+		case strings.HasPrefix(query[i:], `"""`):
+			end := strings.Index(query[i+3:], `"""`)
+			for end >= 0 && strings.HasSuffix(query[i+3:i+3+end], `\`) {
+				next := strings.Index(query[i+3+end+3:], `"""`)
+				if next < 0 {
+					end = -1
+					break
+				}
+				end += 3 + next
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated block string")
+			}
+			tokens = append(tokens, query[i:i+3+end+3])
+			i += 3 + end + 3
+		case c == '"':
+			j := i + 1
+			for j < len(query) && query[j] != '"' {
+				if query[j] == '\\' {
+					j++
+				}
+				if j < len(query) && query[j] == '\n' {
+					return nil, fmt.Errorf("unterminated string")
+				}
+				j++
+			}
+			if j >= len(query) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, query[i:j+1])
+			i = j + 1
+		case isNameStart(c) || c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(query) && (isNameStart(query[j]) || (query[j] >= '0' && query[j] <= '9') || query[j] == '.' || query[j] == '+' || query[j] == '-') {
+				j++
+			}
+			tokens = append(tokens, query[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+// isNameStart reports whether a byte can start a name
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isName reports whether a token is a name
+func isName(token string) bool {
+	return token != "" && isNameStart(token[0])
+}
@@ -0,0 +1,117 @@
+package graphql
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOperation(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		expected      Operation
+	}{
+		{"shorthand", `{ hero { name } }`, "", Operation{Type: "query", Fields: []string{"hero"}}},
+		{
+			"named with variables",
+			`query GetUser($id: ID!, $withPosts: Boolean = false) @cached {
+				user(id: $id) { name posts @include(if: $withPosts) { title } }
+				viewer { id }
+			}`,
+			"",
+			Operation{Type: "query", Name: "GetUser", Fields: []string{"user", "viewer"}},
+		},
+		{
+			"aliases and comments",
+			`mutation Save {
+				# first, a comment with { braces
+				first: createUser(input: {name: "a } b", tags: ["x", "y"]}) { id }
+				second: createUser(input: {name: """block "quoted" }"""}) { id }
+			}`,
+			"",
+			Operation{Type: "mutation", Name: "Save", Fields: []string{"createUser"}},
+		},
+		{
+			"fragments",
+			`query Feed { ...Root ... on Query { ads { id } } ... @skip(if: false) { stats } }
+			fragment Root on Query { feed(first: -1.5e3) { id ...Item } ...Root }
+			fragment Item on Post { title }`,
+			"",
+			Operation{Type: "query", Name: "Feed", Fields: []string{"feed", "ads", "stats"}},
+		},
+		{
+			"selected by name",
+			`query A { a } subscription B { onEvent }`,
+			"B",
+			Operation{Type: "subscription", Name: "B", Fields: []string{"onEvent"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, err := ParseOperation(tt.query, tt.operationName)
+			if err != nil {
+				t.Fatalf("Failed to parse operation: %v", err)
+			}
+			if !reflect.DeepEqual(*op, tt.expected) {
+				t.Errorf("ParseOperation() = %+v, expected %+v", *op, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseOperationErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+	}{
+		{"empty", ``, ""},
+		{"several operations", `query A { a } query B { b }`, ""},
+		{"unknown operation", `query A { a }`, "B"},
+		{"unterminated selection", `{ hero { name }`, ""},
+		{"unterminated string", `{ hero(name: "luke) }`, ""},
+		{"invalid character", `{ hero % }`, ""},
+		{"schema definition", `type Query { hero: String }`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseOperation(tt.query, tt.operationName); err == nil {
+				t.Errorf("Expected an error for %q", tt.query)
+			}
+		})
+	}
+}
+
+func TestReadRequest(t *testing.T) {
+	post := httptest.NewRequest("POST", "/graphql", nil)
+	req, err := ReadRequest(post, []byte(`{"query": "{ a }", "operationName": "A", "variables": {"id": 1}}`))
+	if err != nil || req.Query != "{ a }" || req.OperationName != "A" || req.Variables["id"] != float64(1) {
+		t.Errorf("Unexpected JSON request %+v: %v", req, err)
+	}
+
+	raw := httptest.NewRequest("POST", "/graphql?operationName=A", nil)
+	raw.Header.Set("Content-Type", "application/graphql; charset=utf-8")
+	req, err = ReadRequest(raw, []byte("query A { a }"))
+	if err != nil || req.Query != "query A { a }" || req.OperationName != "A" {
+		t.Errorf("Unexpected application/graphql request %+v: %v", req, err)
+	}
+
+	get := httptest.NewRequest("GET", `/graphql?query=%7B+a+%7D&variables=%7B%22id%22%3A%222%22%7D`, nil)
+	req, err = ReadRequest(get, nil)
+	if err != nil || req.Query != "{ a }" || req.Variables["id"] != "2" {
+		t.Errorf("Unexpected GET request %+v: %v", req, err)
+	}
+
+	bad := httptest.NewRequest("GET", "/graphql?query=%7B+a+%7D&variables=%7B", nil)
+	if _, err := ReadRequest(bad, nil); err == nil || !strings.Contains(err.Error(), "variables") {
+		t.Errorf("Expected an error for invalid variables, got %v", err)
+	}
+	if _, err := ReadRequest(post, []byte("not json")); err == nil {
+		t.Error("Expected an error for a body that is not JSON")
+	}
+}
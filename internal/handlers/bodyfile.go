@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// bodyFileContentType infers a body file's content type from its extension
+func bodyFileContentType(path string) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// bodyFilePath resolves a route's body file against the directory of the
+// file defining the route, or the configuration directory
+func (h *MockHandler) bodyFilePath(route *config.Route) string {
+	if filepath.IsAbs(route.BodyFile) {
+		return route.BodyFile
+	}
+	if route.Source != "" {
+		return filepath.Join(filepath.Dir(route.Source), route.BodyFile)
+	}
+	return filepath.Join(h.configManager.BaseDir(), route.BodyFile)
+}
+
+// serveBodyFile answers with the route's body file. Files are streamed from
+// disk unless they are templates or the route injects faults or dribbles
+// the body, which need the whole body up front.
+func (h *MockHandler) serveBodyFile(w http.ResponseWriter, r *http.Request, route *config.Route, statusCode int) {
+	path := h.bodyFilePath(route)
+	file, err := os.Open(path)
+	if err != nil {
+		h.logger.LogErrorWithRequest(err, r, "opening body file")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		if encErr := json.NewEncoder(w).Encode(map[string]string{"error": "Failed to read body file " + route.BodyFile}); encErr != nil {
+			h.logger.LogError(encErr, "encoding error response")
+		}
+		return
+	}
+	defer file.Close()
+
+	if route.Template || route.Fault != "" || route.Dribble != nil {
+		body, err := io.ReadAll(file)
+		if err != nil {
+			h.logger.LogErrorWithRequest(err, r, "reading body file")
+			http.Error(w, "Failed to read body file", http.StatusInternalServerError)
+			return
+		}
+		if route.Template {
+			var data *RequestData
+			body = []byte(h.renderString(string(body), r, &data))
+		}
+
+		if !h.waitRoute(w, r, route) {
+			return
+		}
+		if route.Fault != "" {
+			h.injectFault(w, r, route.Fault, statusCode, body)
+			return
+		}
+		w.WriteHeader(statusCode)
+		h.writeBody(w, r, route.Dribble, body)
+		return
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		h.logger.LogErrorWithRequest(err, r, "reading body file")
+		http.Error(w, "Failed to read body file", http.StatusInternalServerError)
+		return
+	}
+
+	if !h.waitRoute(w, r, route) {
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	w.WriteHeader(statusCode)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, file); err != nil {
+		h.logger.LogError(err, "writing body file")
+	}
+}
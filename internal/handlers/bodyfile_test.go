@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+func TestBodyFile(t *testing.T) {
+	dir := t.TempDir()
+	image := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0x00, 0xff, 0xfe}
+	files := map[string][]byte{
+		"logo.png":        image,
+		"fixtures/u.json": []byte(`{"id": "{{.PathParams.id}}", "method": "{{.Method}}"}`),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{Path: "/logo", Method: "GET", StatusCode: 200, BodyFile: filepath.Join(dir, "logo.png")})
+	configManager.AddRoute(config.Route{
+		Path: "/users/{id}", Method: "GET", StatusCode: 200, BodyFile: "fixtures/u.json", Template: true,
+		Source: filepath.Join(dir, "mocks.yaml"),
+	})
+	configManager.AddRoute(config.Route{Path: "/missing", Method: "GET", StatusCode: 200, BodyFile: filepath.Join(dir, "missing.bin")})
+
+	w := doRequest(handler, "GET", "/logo", "")
+	if w.Code != 200 || !bytes.Equal(w.Body.Bytes(), image) {
+		t.Errorf("Expected the image bytes unchanged, got %d %v", w.Code, w.Body.Bytes())
+	}
+	if w.Header().Get("Content-Type") != "image/png" || w.Header().Get("Content-Length") != "11" {
+		t.Errorf("Expected image/png with length 11, got %s %s", w.Header().Get("Content-Type"), w.Header().Get("Content-Length"))
+	}
+
+	w = doRequest(handler, "GET", "/users/42", "")
+	if w.Body.String() != `{"id": "42", "method": "GET"}` {
+		t.Errorf("Expected the rendered template relative to the route's file, got %s", w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected application/json, got %s", w.Header().Get("Content-Type"))
+	}
+
+	if w = doRequest(handler, "GET", "/missing", ""); w.Code != 500 {
+		t.Errorf("Expected 500 for a missing body file, got %d", w.Code)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// routeDelay returns the delay configured for the route, falling back to
+// the global delay
+func (h *MockHandler) routeDelay(route *config.Route) time.Duration {
+	if route.Delay != nil {
+		return sampleDelay(route.Delay)
+	}
+
+	h.mutex.RLock()
+	cfg := h.configManager.GetConfig()
+	h.mutex.RUnlock()
+
+	if cfg != nil && cfg.Delay != nil {
+		return sampleDelay(cfg.Delay)
+	}
+	return 0
+}
+
+// sampleDelay draws a delay from the configured distribution
+func sampleDelay(delay *config.Delay) time.Duration {
+	var ms float64
+	switch delay.Distribution {
+	case config.DelayUniform:
+		ms = float64(delay.MinMs) + rand.Float64()*float64(delay.MaxMs-delay.MinMs)
+	case config.DelayNormal:
+		ms = float64(delay.MeanMs) + rand.NormFloat64()*float64(delay.StdDevMs)
+	case config.DelayLogNormal:
+		ms = float64(delay.MedianMs) * math.Exp(rand.NormFloat64()*delay.Sigma)
+	default:
+		ms = float64(delay.Ms)
+	}
+
+	if ms < 0 {
+		ms = 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// waitRoute applies the route's delay before its response is written. Long
+// delays and dribbled bodies would outlive the server's write timeout, so
+// the write deadline is cleared for them, as it is for event streams.
+func (h *MockHandler) waitRoute(w http.ResponseWriter, r *http.Request, route *config.Route) bool {
+	delay := h.routeDelay(route)
+	if delay > 0 || route.Dribble != nil {
+		// Recorders do not support deadlines, which is fine
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	}
+	return h.wait(r, delay)
+}
+
+// wait sleeps for the given duration, returning false if the client went
+// away in the meantime
+func (h *MockHandler) wait(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		h.logger.LogDebug("Client closed connection during delay for %s %s", r.Method, r.URL.Path)
+		return false
+	}
+}
+
+// writeBody writes the response body, spreading it over the dribble
+// duration in equally sized chunks when configured
+func (h *MockHandler) writeBody(w http.ResponseWriter, r *http.Request, dribble *config.Dribble, body []byte) {
+	if dribble == nil || dribble.Chunks <= 1 || len(body) == 0 {
+		if _, err := w.Write(body); err != nil {
+			h.logger.LogError(err, "writing response body")
+		}
+		return
+	}
+
+	chunks := dribble.Chunks
+	if chunks > len(body) {
+		chunks = len(body)
+	}
+	chunkSize := (len(body) + chunks - 1) / chunks
+	interval := time.Duration(dribble.DurationMs) * time.Millisecond / time.Duration(chunks)
+	flusher, _ := w.(http.Flusher)
+
+	for start := 0; start < len(body); start += chunkSize {
+		if start > 0 && !h.wait(r, interval) {
+			return
+		}
+
+		end := start + chunkSize
+		if end > len(body) {
+			end = len(body)
+		}
+		if _, err := w.Write(body[start:end]); err != nil {
+			h.logger.LogError(err, "writing response chunk")
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+func TestSampleDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		delay    config.Delay
+		min, max time.Duration
+	}{
+		{"Fixed", config.Delay{Ms: 50}, 50 * time.Millisecond, 50 * time.Millisecond},
+		{"Uniform", config.Delay{Distribution: config.DelayUniform, MinMs: 10, MaxMs: 20}, 10 * time.Millisecond, 20 * time.Millisecond},
+		{"Normal without deviation", config.Delay{Distribution: config.DelayNormal, MeanMs: 30}, 30 * time.Millisecond, 30 * time.Millisecond},
+		{"Lognormal without sigma", config.Delay{Distribution: config.DelayLogNormal, MedianMs: 40}, 40 * time.Millisecond, 40 * time.Millisecond},
+		{"Normal clamps at zero", config.Delay{Distribution: config.DelayNormal, MeanMs: 0, StdDevMs: 1000}, 0, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				d := sampleDelay(&tt.delay)
+				if d < tt.min || d > tt.max {
+					t.Fatalf("sampleDelay() = %v, expected between %v and %v", d, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestRouteDelay(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.GetConfig().Delay = &config.Delay{Ms: 30}
+	configManager.AddRoute(config.Route{
+		Path: "/api/slow", Method: "GET", StatusCode: 200, ContentType: "text/plain",
+		Response: "slow", Delay: &config.Delay{Ms: 60},
+	})
+
+	start := time.Now()
+	w := doRequest(handler, "GET", "/api/slow", "")
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("Expected route delay of at least 60ms, took %v", elapsed)
+	}
+	if w.Body.String() != "slow" {
+		t.Errorf("Expected body 'slow', got '%s'", w.Body.String())
+	}
+
+	start = time.Now()
+	doRequest(handler, "GET", "/test/simple", "")
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected global delay of at least 30ms, took %v", elapsed)
+	}
+}
+
+func TestDelayCancelledByClient(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/api/hang", Method: "GET", StatusCode: 200, Response: "late", Delay: &config.Delay{Ms: 5000},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	req := httptest.NewRequest("GET", "/api/hang", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ServeHTTP(w, req)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected handler to stop when the client goes away, took %v", elapsed)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected no body to be written, got '%s'", w.Body.String())
+	}
+}
+
+func TestDribble(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/api/dribble", Method: "GET", StatusCode: 200, ContentType: "text/plain",
+		Response: "0123456789", Dribble: &config.Dribble{Chunks: 5, DurationMs: 100},
+	})
+
+	start := time.Now()
+	w := doRequest(handler, "GET", "/api/dribble", "")
+	elapsed := time.Since(start)
+
+	if w.Body.String() != "0123456789" {
+		t.Errorf("Expected complete body, got '%s'", w.Body.String())
+	}
+	if elapsed < 80*time.Millisecond {
+		t.Errorf("Expected body to be spread over about 100ms, took %v", elapsed)
+	}
+	if !w.Flushed {
+		t.Error("Expected chunks to be flushed")
+	}
+}
+
+func TestDelayOutlivesWriteTimeout(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/slow", Method: "GET", StatusCode: 200, ContentType: "text/plain", Response: "late",
+		Delay: &config.Delay{Ms: 150},
+	})
+	configManager.AddRoute(config.Route{
+		Path: "/dribble", Method: "GET", StatusCode: 200, ContentType: "text/plain", Response: "abcd",
+		Dribble: &config.Dribble{Chunks: 4, DurationMs: 200},
+	})
+
+	server := httptest.NewUnstartedServer(handler.logger.Middleware(handler))
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	for path, want := range map[string]string{"/slow": "late", "/dribble": "abcd"} {
+		resp, err := server.Client().Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Expected %s to answer after the write timeout, got %v", path, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || string(body) != want {
+			t.Errorf("Expected %s to send %q, got %q (%v)", path, want, body, err)
+		}
+	}
+}
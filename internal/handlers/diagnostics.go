@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+	"github.com/walterfan/lazy-mock-server/internal/jsonpath"
+)
+
+const (
+	// maxNearMisses is the number of closest routes reported for a request
+	maxNearMisses = 3
+	// minNearMissScore hides routes that have little in common with the request
+	minNearMissScore = 0.5
+	// minPathSimilarity hides routes whose path differs too much
+	minPathSimilarity = 0.5
+)
+
+// NearMiss is a route that almost matched an unmatched request
+type NearMiss struct {
+	Route       string   `json:"route"`
+	Score       float64  `json:"score"`
+	Mismatches  []string `json:"mismatches"`
+	Explanation string   `json:"explanation"`
+}
+
+// findNearMisses ranks the routes by how close they came to matching the
+// request. The score weighs path similarity most, then the method and then
+// the share of scenario, parameter, header and body checks that passed.
+func (h *MockHandler) findNearMisses(r *http.Request) []NearMiss {
+	routes := h.configManager.GetRoutes()
+
+	var nearMisses []NearMiss
+	for i := range routes {
+		route := &routes[i]
+		var mismatches []string
+
+		methodMatched := strings.EqualFold(route.Method, r.Method)
+		if !methodMatched {
+			mismatches = append(mismatches, fmt.Sprintf("method expected %s got %s", route.Method, r.Method))
+		}
+
+		_, pathMatched := h.matchPath(route.Path, r.URL.Path)
+		similarity := 1.0
+		if !pathMatched {
+			similarity = pathSimilarity(route.Path, r.URL.Path)
+			if similarity < minPathSimilarity {
+				continue
+			}
+			mismatches = append(mismatches, fmt.Sprintf("path expected '%s' got '%s'", route.Path, r.URL.Path))
+		}
+
+		checks, failed := h.explainChecks(route, r)
+		mismatches = append(mismatches, failed...)
+
+		passed := 1.0
+		if checks > 0 {
+			passed = float64(checks-len(failed)) / float64(checks)
+		}
+		score := 0.5*similarity*similarity + 0.2*passed
+		if methodMatched {
+			score += 0.3
+		}
+		if score < minNearMissScore || len(mismatches) == 0 {
+			continue
+		}
+
+		explanation := "route " + config.RouteKey(routes, i)
+		switch {
+		case methodMatched && pathMatched:
+			explanation += " matched method and path but "
+		case pathMatched:
+			explanation += " matched path but "
+		default:
+			explanation += ": "
+		}
+		explanation += strings.Join(mismatches, ", ")
+
+		nearMisses = append(nearMisses, NearMiss{
+			Route:       config.RouteKey(routes, i),
+			Score:       float64(int(score*100+0.5)) / 100,
+			Mismatches:  mismatches,
+			Explanation: explanation,
+		})
+	}
+
+	sort.SliceStable(nearMisses, func(i, j int) bool {
+		return nearMisses[i].Score > nearMisses[j].Score
+	})
+	if len(nearMisses) > maxNearMisses {
+		nearMisses = nearMisses[:maxNearMisses]
+	}
+	return nearMisses
+}
+
+// explainChecks evaluates the route's scenario, parameter, header, SOAP
+// action, body and GraphQL checks and describes the ones the request fails
+func (h *MockHandler) explainChecks(route *config.Route, r *http.Request) (int, []string) {
+	checks := 0
+	var failed []string
+
+	if route.Scenario != "" && route.RequiredState != "" {
+		checks++
+		if state := h.scenarios.state(route.Scenario); state != route.RequiredState {
+			failed = append(failed, fmt.Sprintf("scenario %s expected state '%s' got '%s'", route.Scenario, route.RequiredState, state))
+		}
+	}
+
+	if route.Parameters != nil {
+		if err := h.parseForm(r); err != nil {
+			h.logger.LogError(err, "parsing form parameters")
+		}
+		for _, key := range config.SortedKeys(route.Parameters) {
+			checks++
+			expected := route.Parameters[key]
+			if values, ok := r.Form[key]; !ok || len(values) == 0 {
+				failed = append(failed, fmt.Sprintf("parameter %s expected '%s' got nothing", key, expected))
+			} else if values[0] != expected {
+				failed = append(failed, fmt.Sprintf("parameter %s expected '%s' got '%s'", key, expected, values[0]))
+			}
+		}
+	}
+
+	if route.Match != nil {
+		names := make([]string, 0, len(route.Match.Headers))
+		for name := range route.Match.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			checks++
+			matcher := route.Match.Headers[name]
+			values := r.Header.Values(name)
+			if !h.matchesHeader(matcher, values) {
+				failed = append(failed, describeHeaderMismatch(name, matcher, values))
+			}
+		}
+
+		if route.Match.SOAPAction != "" {
+			checks++
+			if action, ok := soapAction(r); !ok {
+				failed = append(failed, fmt.Sprintf("soap action expected '%s' got nothing", route.Match.SOAPAction))
+			} else if action != route.Match.SOAPAction {
+				failed = append(failed, fmt.Sprintf("soap action expected '%s' got '%s'", route.Match.SOAPAction, action))
+			}
+		}
+
+		if route.Match.Body != nil {
+			bodyChecks, bodyFailed := h.explainBody(route.Match.Body, r)
+			checks += bodyChecks
+			failed = append(failed, bodyFailed...)
+		}
+	}
+
+	if route.GraphQL != nil {
+		graphQLChecks, graphQLFailed := h.explainGraphQL(route.GraphQL, r)
+		checks += graphQLChecks
+		failed = append(failed, graphQLFailed...)
+	}
+
+	return checks, failed
+}
+
+// describeHeaderMismatch explains why a header failed its matcher
+func describeHeaderMismatch(name string, matcher config.HeaderMatcher, values []string) string {
+	got := "nothing"
+	if len(values) > 0 {
+		got = "'" + strings.Join(values, "', '") + "'"
+	}
+
+	var expected []string
+	if matcher.Present != nil && !*matcher.Present {
+		return fmt.Sprintf("header %s expected to be absent got %s", name, got)
+	}
+	if matcher.Equals != "" {
+		expected = append(expected, fmt.Sprintf("'%s'", matcher.Equals))
+	}
+	if matcher.Contains != "" {
+		expected = append(expected, fmt.Sprintf("to contain '%s'", matcher.Contains))
+	}
+	if matcher.Matches != "" {
+		expected = append(expected, fmt.Sprintf("to match /%s/", matcher.Matches))
+	}
+	if len(expected) == 0 {
+		expected = append(expected, "to be present")
+	}
+	return fmt.Sprintf("header %s expected %s got %s", name, strings.Join(expected, " and "), got)
+}
+
+// explainBody evaluates each body check separately and describes the ones
+// the request fails
+func (h *MockHandler) explainBody(matcher *config.BodyMatcher, r *http.Request) (int, []string) {
+	data := h.readBody(r)
+	checks := 0
+	var failed []string
+	if matcher.ChecksJSON() {
+		checks, failed = h.explainJSON(matcher, data)
+	}
+	if len(matcher.XPath) > 0 {
+		xmlChecks, xmlFailed := h.explainXML(matcher, data)
+		checks += xmlChecks
+		failed = append(failed, xmlFailed...)
+	}
+	return checks, failed
+}
+
+// explainJSON evaluates the JSON checks of a body matcher
+func (h *MockHandler) explainJSON(matcher *config.BodyMatcher, data []byte) (int, []string) {
+	checks := len(matcher.JSONPath) + len(matcher.Matches)
+	if matcher.EqualToJSON != nil {
+		checks++
+	}
+	if matcher.PartialJSON != nil {
+		checks++
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return checks, []string{"body expected JSON got an invalid or empty document"}
+	}
+
+	var failed []string
+	if matcher.EqualToJSON != nil {
+		expected := h.normalizeJSON(matcher.EqualToJSON)
+		if !reflect.DeepEqual(expected, body) {
+			failed = append(failed, fmt.Sprintf("body expected to equal %s got %s", compactJSON(expected), compactJSON(body)))
+		}
+	}
+	if matcher.PartialJSON != nil {
+		expected := h.normalizeJSON(matcher.PartialJSON)
+		if !containsJSON(body, expected) {
+			failed = append(failed, fmt.Sprintf("body expected to contain %s got %s", compactJSON(expected), compactJSON(body)))
+		}
+	}
+	for _, expr := range matcher.JSONPath {
+		predicate, err := h.getPredicate(expr)
+		if err != nil || !predicate.Match(body) {
+			failed = append(failed, fmt.Sprintf("body expected %s", expr))
+		}
+	}
+	for _, expr := range config.SortedKeys(matcher.Matches) {
+		pattern := matcher.Matches[expr]
+		value, ok := jsonpath.Get(body, expr)
+		str, isString := value.(string)
+		regex, err := h.getRegex(pattern)
+		switch {
+		case !ok:
+			failed = append(failed, fmt.Sprintf("body field %s expected to match /%s/ got nothing", expr, pattern))
+		case err != nil || !isString || !regex.MatchString(str):
+			failed = append(failed, fmt.Sprintf("body field %s expected to match /%s/ got %s", expr, pattern, compactJSON(value)))
+		}
+	}
+	return checks, failed
+}
+
+// pathSimilarity compares two paths segment by segment and returns a value
+// between 0 and 1. Parameter and wildcard segments count as equal.
+func pathSimilarity(routePath, requestPath string) float64 {
+	routeSegments := strings.Split(strings.Trim(routePath, "/"), "/")
+	requestSegments := strings.Split(strings.Trim(requestPath, "/"), "/")
+
+	total := len(routeSegments)
+	if len(requestSegments) > total {
+		total = len(requestSegments)
+	}
+
+	sum := 0.0
+	for i := 0; i < len(routeSegments) && i < len(requestSegments); i++ {
+		segment := routeSegments[i]
+		if strings.Contains(segment, "{") || strings.Contains(segment, "*") {
+			sum++
+			continue
+		}
+		sum += stringSimilarity(segment, requestSegments[i])
+	}
+	return sum / float64(total)
+}
+
+// stringSimilarity returns 1 minus the edit distance normalized by the
+// longer string's length
+func stringSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// compactJSON renders a value as single-line JSON for messages
+func compactJSON(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(encoded)
+}
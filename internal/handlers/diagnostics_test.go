@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+type notFoundResponse struct {
+	Error      string     `json:"error"`
+	NearMisses []NearMiss `json:"near_misses"`
+}
+
+func getNearMisses(t *testing.T, handler *MockHandler, method, path, body string) []NearMiss {
+	w := doRequest(handler, method, path, body)
+	if w.Code != 404 {
+		t.Fatalf("Expected status 404, got %d", w.Code)
+	}
+	var response notFoundResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse not found response: %v", err)
+	}
+	return response.NearMisses
+}
+
+func TestNearMissParameter(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/api/search", Method: "GET", StatusCode: 200, Response: "ok",
+		Parameters: map[string]string{"q": "foo"},
+	})
+
+	nearMisses := getNearMisses(t, handler, "GET", "/api/search?q=bar", "")
+	if len(nearMisses) == 0 {
+		t.Fatal("Expected a near miss")
+	}
+	expected := "route GET /api/search matched method and path but parameter q expected 'foo' got 'bar'"
+	if nearMisses[0].Explanation != expected {
+		t.Errorf("Expected explanation %q, got %q", expected, nearMisses[0].Explanation)
+	}
+}
+
+func TestNearMissRanking(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/api/users/{id:int}", Method: "GET", StatusCode: 200, Response: "user",
+		Match: &config.RequestMatch{Headers: map[string]config.HeaderMatcher{
+			"Authorization": {Matches: "^Bearer "},
+		}},
+	})
+	configManager.AddRoute(config.Route{Path: "/api/users/{id:int}", Method: "DELETE", StatusCode: 204})
+	configManager.AddRoute(config.Route{Path: "/api/orders", Method: "GET", StatusCode: 200, Response: "orders"})
+
+	req := httptest.NewRequest("GET", "/api/users/42", nil)
+	req.Header.Set("Authorization", "Basic abc")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var response notFoundResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse not found response: %v", err)
+	}
+	if len(response.NearMisses) < 2 {
+		t.Fatalf("Expected at least 2 near misses, got %+v", response.NearMisses)
+	}
+
+	first := response.NearMisses[0]
+	if first.Route != "GET /api/users/{id:int}" || first.Mismatches[0] != "header Authorization expected to match /^Bearer / got 'Basic abc'" {
+		t.Errorf("Expected the header mismatch first, got %+v", first)
+	}
+	if second := response.NearMisses[1]; second.Route != "DELETE /api/users/{id:int}" || second.Mismatches[0] != "method expected DELETE got GET" {
+		t.Errorf("Expected the method mismatch second, got %+v", second)
+	}
+	for _, nearMiss := range response.NearMisses {
+		if nearMiss.Score <= 0 || nearMiss.Score > 1 {
+			t.Errorf("Expected a score between 0 and 1, got %v", nearMiss.Score)
+		}
+	}
+}
+
+func TestNearMissPathAndBody(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/api/orders", Method: "POST", StatusCode: 201, Response: "created",
+		Match: &config.RequestMatch{Body: &config.BodyMatcher{
+			PartialJSON: map[string]interface{}{"type": "express"},
+			JSONPath:    []string{"$.items[0]"},
+		}},
+	})
+
+	nearMisses := getNearMisses(t, handler, "POST", "/api/order", `{"type": "standard", "items": [1]}`)
+	if len(nearMisses) != 1 {
+		t.Fatalf("Expected 1 near miss, got %+v", nearMisses)
+	}
+	mismatches := nearMisses[0].Mismatches
+	if len(mismatches) != 2 || !strings.HasPrefix(mismatches[0], "path expected '/api/orders'") ||
+		mismatches[1] != `body expected to contain {"type":"express"} got {"items":[1],"type":"standard"}` {
+		t.Errorf("Unexpected mismatches: %v", mismatches)
+	}
+
+	// Unrelated paths are not reported
+	if nearMisses := getNearMisses(t, handler, "POST", "/health/live/check", ""); len(nearMisses) != 0 {
+		t.Errorf("Expected no near misses for an unrelated path, got %+v", nearMisses)
+	}
+}
+
+func TestPathSimilarity(t *testing.T) {
+	tests := []struct {
+		routePath   string
+		requestPath string
+		expected    float64
+	}{
+		{"/api/users", "/api/users", 1},
+		{"/api/users/{id}", "/api/users/42", 1},
+		{"/api/users", "/api/user", 0.5 + 0.5*(1-1.0/5)},
+		{"/api/users", "/api/users/42", 2.0 / 3},
+		{"/a", "/b", 0},
+	}
+
+	for _, tt := range tests {
+		if got := pathSimilarity(tt.routePath, tt.requestPath); got < tt.expected-0.001 || got > tt.expected+0.001 {
+			t.Errorf("pathSimilarity(%s, %s) = %v, expected %v", tt.routePath, tt.requestPath, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/walterfan/lazy-mock-server/internal/openapi"
+	"gopkg.in/yaml.v2"
+)
+
+// handleExportOpenAPI describes the current routes as an OpenAPI 3 document,
+// in JSON or, with ?format=yaml, in YAML
+func (h *MockHandler) handleExportOpenAPI(w http.ResponseWriter, r *http.Request) {
+	h.mutex.RLock()
+	routes := h.configManager.GetRoutes()
+	h.mutex.RUnlock()
+
+	info := openapi.Info{
+		Title:       "Lazy Mock Server",
+		Description: "Generated from the routes of " + h.configManager.GetConfigPath(),
+		Version:     "1.0.0",
+	}
+	if title := r.URL.Query().Get("title"); title != "" {
+		info.Title = title
+	}
+	if version := r.URL.Query().Get("version"); version != "" {
+		info.Version = version
+	}
+	doc := openapi.FromRoutes(routes, info)
+
+	if r.URL.Query().Get("format") == "yaml" {
+		data, err := yaml.Marshal(doc)
+		if err != nil {
+			h.logger.LogErrorWithRequest(err, r, "marshaling OpenAPI document")
+			w.WriteHeader(http.StatusInternalServerError)
+			if encErr := json.NewEncoder(w).Encode(map[string]string{"error": "Failed to generate YAML"}); encErr != nil {
+				h.logger.LogError(encErr, "encoding error response")
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		if _, err := w.Write(data); err != nil {
+			h.logger.LogErrorWithRequest(err, r, "writing OpenAPI document")
+		}
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		h.logger.LogErrorWithRequest(err, r, "encoding OpenAPI document")
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/walterfan/lazy-mock-server/internal/openapi"
+)
+
+func TestExportOpenAPI(t *testing.T) {
+	handler, configManager := createTestHandler()
+
+	for _, format := range []string{"", "?format=yaml&title=Orders"} {
+		w := doRequest(handler, "GET", "/_mock/export/openapi"+format, "")
+		if w.Code != 200 {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+
+		doc, err := openapi.Parse(w.Body.Bytes())
+		if err != nil {
+			t.Fatalf("Failed to parse exported document: %v", err)
+		}
+		if len(doc.Paths) == 0 || doc.Paths["/test/simple"].Get == nil {
+			t.Errorf("Expected the configured routes, got %v", doc.Paths)
+		}
+
+		routes, err := doc.Routes()
+		if err != nil {
+			t.Fatalf("Failed to generate routes: %v", err)
+		}
+		if len(routes) != configManager.GetRouteCount() {
+			t.Errorf("Expected %d operations, got %d", configManager.GetRouteCount(), len(routes))
+		}
+	}
+
+	w := doRequest(handler, "GET", "/_mock/export/openapi?format=yaml&title=Orders", "")
+	if doc, _ := openapi.Parse(w.Body.Bytes()); doc == nil || doc.Info.Title != "Orders" {
+		t.Errorf("Expected title Orders, got %+v", doc)
+	}
+}
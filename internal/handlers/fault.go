@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// injectFault takes over the connection and breaks it in the configured way
+func (h *MockHandler) injectFault(w http.ResponseWriter, r *http.Request, fault string, statusCode int, body []byte) {
+	conn, buf, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		// HTTP/2 and test recorders cannot be hijacked
+		h.logger.LogErrorWithRequest(err, r, "hijacking connection for fault "+fault)
+		http.Error(w, "Fault injection not supported on this connection", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	h.logger.LogDebug("Injecting fault %s for %s %s", fault, r.Method, r.URL.Path)
+
+	switch fault {
+	case config.FaultConnectionReset:
+		h.resetConnection(conn)
+	case config.FaultEmptyResponse:
+		// Close without writing anything
+	case config.FaultRandomData:
+		garbage := make([]byte, 256)
+		if _, err := rand.Read(garbage); err == nil {
+			h.writeRaw(buf, garbage)
+		}
+	case config.FaultMalformedChunk:
+		h.writeStatusLine(buf, w.Header(), statusCode, "Transfer-Encoding: chunked\r\n")
+		h.writeRaw(buf, []byte("zz\r\nnot a valid chunk\r\n"))
+	case config.FaultTruncatedBody:
+		// An empty body is announced as one byte so that it is cut short too
+		h.writeStatusLine(buf, w.Header(), statusCode, fmt.Sprintf("Content-Length: %d\r\n", max(len(body), 1)))
+		h.writeRaw(buf, body[:len(body)/2])
+	}
+}
+
+// resetConnection closes the connection with a TCP reset. TLS connections
+// are reset below the TLS layer, so no close_notify alert precedes the reset.
+func (h *MockHandler) resetConnection(conn net.Conn) {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		h.logger.LogDebug("Cannot reset a %T connection, closing it instead", conn)
+		return
+	}
+
+	// A zero linger timeout makes Close send RST instead of FIN
+	if err := tcpConn.SetLinger(0); err != nil {
+		h.logger.LogError(err, "setting linger for connection reset")
+	}
+	if err := tcpConn.Close(); err != nil {
+		h.logger.LogError(err, "resetting connection")
+	}
+}
+
+// writeStatusLine writes the status line and headers to the hijacked
+// connection's buffer
+func (h *MockHandler) writeStatusLine(buf *bufio.ReadWriter, header http.Header, statusCode int, extra string) {
+	fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", statusCode, http.StatusText(statusCode))
+	if err := header.Write(buf); err != nil {
+		h.logger.LogError(err, "writing fault headers")
+	}
+	fmt.Fprintf(buf, "Date: %s\r\n%s\r\n", time.Now().UTC().Format(http.TimeFormat), extra)
+}
+
+// writeRaw writes raw bytes to the hijacked connection
+func (h *MockHandler) writeRaw(buf *bufio.ReadWriter, data []byte) {
+	if _, err := buf.Write(data); err != nil {
+		h.logger.LogError(err, "writing fault data")
+		return
+	}
+	if err := buf.Flush(); err != nil {
+		h.logger.LogError(err, "flushing fault data")
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+func TestInjectFault(t *testing.T) {
+	handler, configManager := createTestHandler()
+	faults := []string{
+		config.FaultConnectionReset,
+		config.FaultEmptyResponse,
+		config.FaultRandomData,
+		config.FaultMalformedChunk,
+		config.FaultTruncatedBody,
+	}
+	for _, fault := range faults {
+		configManager.AddRoute(config.Route{
+			Path: "/fault/" + fault, Method: "GET", StatusCode: 200, ContentType: "text/plain",
+			Response: "a complete response body", Fault: fault,
+		})
+	}
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	for _, fault := range faults {
+		t.Run(fault, func(t *testing.T) {
+			resp, err := client.Get(server.URL + "/fault/" + fault)
+			if err == nil {
+				_, err = io.ReadAll(resp.Body)
+				resp.Body.Close()
+			}
+			if err == nil {
+				t.Errorf("Expected client error for fault %s", fault)
+			}
+		})
+	}
+
+	// Routes without a fault still work on the same server
+	resp, err := client.Get(server.URL + "/test/simple")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestConnectionResetOverTLS(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/reset", Method: "GET", StatusCode: 200, Response: "x", Fault: config.FaultConnectionReset,
+	})
+
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+
+	client := server.Client()
+	client.Transport.(*http.Transport).DisableKeepAlives = true
+	_, err := client.Get(server.URL + "/reset")
+	if !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("Expected a connection reset, got %v", err)
+	}
+}
+
+func TestTruncatedEmptyBody(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/truncated", Method: "GET", StatusCode: 200, ContentType: "text/plain",
+		Response: "{{.RawQuery}}", Fault: config.FaultTruncatedBody,
+	})
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/truncated")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected an empty body to be cut short too, got %v", err)
+	}
+}
+
+func TestInjectFaultWithoutHijacker(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/fault", Method: "GET", StatusCode: 200, Response: "x", Fault: config.FaultEmptyResponse,
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/fault", nil))
+
+	if w.Code != 500 {
+		t.Errorf("Expected status 500 when hijacking is unsupported, got %d", w.Code)
+	}
+}
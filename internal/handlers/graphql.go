@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+	"github.com/walterfan/lazy-mock-server/internal/graphql"
+)
+
+// matchesGraphQL checks if the request executes the operation described by
+// the route's GraphQL matcher
+func (h *MockHandler) matchesGraphQL(matcher *config.GraphQL, r *http.Request) bool {
+	_, failed := h.explainGraphQL(matcher, r)
+	return len(failed) == 0
+}
+
+// explainGraphQL evaluates each GraphQL check and describes the ones the
+// request fails
+func (h *MockHandler) explainGraphQL(matcher *config.GraphQL, r *http.Request) (int, []string) {
+	checks := len(matcher.Fields)
+	if matcher.OperationName != "" {
+		checks++
+	}
+	if matcher.OperationType != "" {
+		checks++
+	}
+	if matcher.Variables != nil {
+		checks++
+	}
+	// Even a matcher without checks requires a GraphQL request
+	if checks == 0 {
+		checks = 1
+	}
+
+	req, err := graphql.ReadRequest(r, h.readBody(r))
+	if err != nil {
+		return checks, []string{fmt.Sprintf("graphql expected a GraphQL request: %v", err)}
+	}
+	op, err := graphql.ParseOperation(req.Query, req.OperationName)
+	if err != nil {
+		return checks, []string{fmt.Sprintf("graphql expected a valid query: %v", err)}
+	}
+
+	var failed []string
+	if matcher.OperationName != "" && op.Name != matcher.OperationName {
+		failed = append(failed, fmt.Sprintf("graphql operation expected '%s' got '%s'", matcher.OperationName, op.Name))
+	}
+	if matcher.OperationType != "" && op.Type != matcher.OperationType {
+		failed = append(failed, fmt.Sprintf("graphql operation type expected '%s' got '%s'", matcher.OperationType, op.Type))
+	}
+	for _, field := range matcher.Fields {
+		if !containsString(op.Fields, field) {
+			failed = append(failed, fmt.Sprintf("graphql field %s expected got %s", field, strings.Join(op.Fields, ", ")))
+		}
+	}
+	if matcher.Variables != nil {
+		expected := h.normalizeJSON(matcher.Variables)
+		actual := h.normalizeJSON(req.Variables)
+		if actual == nil {
+			actual = map[string]interface{}{}
+		}
+		if !containsJSON(actual, expected) {
+			failed = append(failed, fmt.Sprintf("graphql variables expected to contain %s got %s", compactJSON(expected), compactJSON(actual)))
+		}
+	}
+	return checks, failed
+}
+
+// containsString reports whether a list contains a string
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+func addGraphQLRoutes(configManager *config.Manager) {
+	configManager.AddRoute(config.Route{
+		Path: "/graphql", Method: "POST", StatusCode: 200,
+		GraphQL: &config.GraphQL{
+			OperationName: "GetUser",
+			Variables:     map[interface{}]interface{}{"id": "42"},
+			Data:          map[interface{}]interface{}{"user": map[interface{}]interface{}{"id": "42", "name": "Alice"}},
+		},
+	})
+	configManager.AddRoute(config.Route{
+		Path: "/graphql", Method: "POST", StatusCode: 200,
+		GraphQL: &config.GraphQL{
+			OperationName: "GetUser",
+			Data:          map[interface{}]interface{}{"user": nil},
+			Errors:        []interface{}{map[interface{}]interface{}{"message": `user {{jsonPath .JSON "$.variables.id"}} not found`}},
+		},
+	})
+	configManager.AddRoute(config.Route{
+		Path: "/graphql", Method: "POST", StatusCode: 200,
+		GraphQL: &config.GraphQL{
+			OperationType: "mutation",
+			Fields:        []string{"createPost"},
+			Data:          map[interface{}]interface{}{"createPost": map[interface{}]interface{}{"id": "p-1"}},
+		},
+	})
+}
+
+func TestGraphQLOperations(t *testing.T) {
+	handler, configManager := createTestHandler()
+	addGraphQLRoutes(configManager)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			"operation and variables",
+			`{"query": "query GetUser($id: ID!) { user(id: $id) { name } }", "variables": {"id": "42", "extra": true}}`,
+			`{"data":{"user":{"id":"42","name":"Alice"}}}`,
+		},
+		{
+			"other variables",
+			`{"query": "query GetUser($id: ID!) { user(id: $id) { name } }", "variables": {"id": "7"}}`,
+			`{"data":{"user":null},"errors":[{"message":"user 7 not found"}]}`,
+		},
+		{
+			"selected operation",
+			`{"query": "query Other { a } query GetUser { user { id } }", "operationName": "GetUser", "variables": {"id": "9"}}`,
+			`{"data":{"user":null},"errors":[{"message":"user 9 not found"}]}`,
+		},
+		{
+			"root field through alias",
+			`{"query": "mutation { post: createPost(title: \"Hi\") { id } }"}`,
+			`{"data":{"createPost":{"id":"p-1"}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(handler, "POST", "/graphql", tt.body)
+			if w.Code != 200 || w.Header().Get("Content-Type") != "application/json" {
+				t.Fatalf("Expected a JSON response, got %d %v: %s", w.Code, w.Header(), w.Body.String())
+			}
+			if strings.TrimSpace(w.Body.String()) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, w.Body.String())
+			}
+		})
+	}
+
+	if w := doRequest(handler, "POST", "/graphql", `{"query": "query GetOrders { orders { id } }"}`); w.Code != 404 {
+		t.Errorf("Expected 404 for an unknown operation, got %d", w.Code)
+	}
+}
+
+func TestGraphQLGetAndRawQuery(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/graphql", Method: "GET", StatusCode: 200,
+		GraphQL: &config.GraphQL{Fields: []string{"hero"}, Variables: map[interface{}]interface{}{"episode": "JEDI"}, Data: "{{.Method}}"},
+	})
+	configManager.AddRoute(config.Route{
+		Path: "/graphql", Method: "POST", StatusCode: 200, Response: "raw",
+		GraphQL: &config.GraphQL{Fields: []string{"hero"}},
+	})
+
+	query := url.Values{"query": {"query Hero($episode: Episode) { hero(episode: $episode) { name } }"}, "variables": {`{"episode": "JEDI"}`}}
+	if w := doRequest(handler, "GET", "/graphql?"+query.Encode(), ""); strings.TrimSpace(w.Body.String()) != `{"data":"GET"}` {
+		t.Errorf("Unexpected GET response %d: %s", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest("POST", "/graphql", strings.NewReader("{ hero { name } }"))
+	req.Header.Set("Content-Type", "application/graphql")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if strings.TrimSpace(w.Body.String()) != `"raw"` {
+		t.Errorf("Expected the configured response for application/graphql, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGraphQLNearMiss(t *testing.T) {
+	handler, configManager := createTestHandler()
+	addGraphQLRoutes(configManager)
+
+	nearMisses := getNearMisses(t, handler, "POST", "/graphql", `{"query": "mutation { deletePost(id: 1) }"}`)
+	if len(nearMisses) == 0 {
+		t.Fatal("Expected near misses")
+	}
+	var explanations []string
+	for _, nearMiss := range nearMisses {
+		explanations = append(explanations, nearMiss.Explanation)
+	}
+	expected := "graphql field createPost expected got deletePost"
+	if !strings.Contains(strings.Join(explanations, "\n"), expected) {
+		t.Errorf("Expected an explanation containing %q, got %v", expected, explanations)
+	}
+
+	nearMisses = getNearMisses(t, handler, "POST", "/graphql", `{"query": "{ user "}`)
+	if len(nearMisses) == 0 || !strings.Contains(nearMisses[0].Explanation, "graphql expected a valid query") {
+		t.Errorf("Expected an invalid query explanation, got %+v", nearMisses)
+	}
+}
+
+func TestGraphQLRoutesAPI(t *testing.T) {
+	handler, configManager := createTestHandler()
+	addGraphQLRoutes(configManager)
+
+	w := doRequest(handler, "GET", "/_mock/routes", "")
+	var response struct {
+		Routes []map[string]interface{} `json:"routes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse routes: %v", err)
+	}
+	found := false
+	for _, route := range response.Routes {
+		if graphQL, ok := route["graphql"].(map[string]interface{}); ok && graphQL["operation_name"] == "GetUser" {
+			found = graphQL["variables"].(map[string]interface{})["id"] == "42"
+			break
+		}
+	}
+	if !found {
+		t.Errorf("Expected the GraphQL matcher in the routes list, got %s", w.Body.String())
+	}
+}
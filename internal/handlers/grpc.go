@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+	"github.com/walterfan/lazy-mock-server/internal/protoset"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// LoadGRPC loads the descriptors named by the gRPC settings and checks the
+// configured methods against them. Calls are answered with the checked
+// methods only, so settings changed elsewhere take effect once loaded here.
+// On error the current descriptors and methods stay in effect.
+func (h *MockHandler) LoadGRPC(cfg *config.GRPC) error {
+	var set *protoset.Set
+	var methods []config.GRPCMethod
+	if cfg != nil {
+		if err := config.ValidateGRPC(cfg); err != nil {
+			return err
+		}
+
+		importPaths := []string{cfg.ResolvePath(".")}
+		if len(cfg.ImportPaths) > 0 {
+			importPaths = resolvePaths(cfg, cfg.ImportPaths)
+		}
+
+		var err error
+		set, err = protoset.Load(resolvePaths(cfg, cfg.Protos), importPaths, resolvePaths(cfg, cfg.DescriptorSets))
+		if err != nil {
+			return err
+		}
+		if err := checkGRPCMethods(set, cfg.Methods); err != nil {
+			return err
+		}
+		methods = append(methods, cfg.Methods...)
+	}
+
+	h.mutex.Lock()
+	h.protos = set
+	h.grpcMethods = methods
+	h.mutex.Unlock()
+	return nil
+}
+
+// resolvePaths resolves paths against the directory of the gRPC settings
+func resolvePaths(cfg *config.GRPC, paths []string) []string {
+	resolved := make([]string, len(paths))
+	for i, path := range paths {
+		resolved[i] = cfg.ResolvePath(path)
+	}
+	return resolved
+}
+
+// checkGRPCMethods checks that the configured methods exist and that their
+// answers suit the kind of method
+func checkGRPCMethods(set *protoset.Set, methods []config.GRPCMethod) error {
+	for i := range methods {
+		method := &methods[i]
+		desc, err := set.FindMethod(method.FullMethod())
+		if err != nil {
+			return fmt.Errorf("grpc methods[%d]: %w", i, err)
+		}
+		switch {
+		case desc.IsStreamingClient():
+			return fmt.Errorf("grpc methods[%d]: client-streaming method %s is not supported", i, method.Method)
+		case desc.IsStreamingServer() && method.Response != nil:
+			return fmt.Errorf("grpc methods[%d]: server-streaming method %s answers with stream, not response", i, method.Method)
+		case !desc.IsStreamingServer() && len(method.Stream) > 0:
+			return fmt.Errorf("grpc methods[%d]: unary method %s answers with response, not stream", i, method.Method)
+		}
+	}
+	return nil
+}
+
+// RegisterGRPCReflection registers the server reflection services, which
+// describe the loaded services to clients such as grpcurl
+func (h *MockHandler) RegisterGRPCReflection(s *grpc.Server) {
+	opts := reflection.ServerOptions{Services: grpcServices{h}, DescriptorResolver: grpcResolver{h}}
+	reflectionv1.RegisterServerReflectionServer(s, reflection.NewServerV1(opts))
+	reflectionv1alpha.RegisterServerReflectionServer(s, reflection.NewServer(opts))
+}
+
+// getProtos returns the loaded descriptors
+func (h *MockHandler) getProtos() *protoset.Set {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.protos
+}
+
+// HandleGRPC answers calls of every gRPC method with the first configured
+// answer whose match the request satisfies. It is meant to be installed with
+// grpc.UnknownServiceHandler.
+func (h *MockHandler) HandleGRPC(_ interface{}, stream grpc.ServerStream) error {
+	fullMethod, _ := grpc.MethodFromServerStream(stream)
+	start := time.Now()
+
+	err := h.serveGRPC(stream, fullMethod)
+
+	h.logger.LogInfo("gRPC %s - %s (%s)", fullMethod, status.Code(err), time.Since(start).Round(time.Microsecond))
+	return err
+}
+
+// serveGRPC reads the request message and plays the matching answer
+func (h *MockHandler) serveGRPC(stream grpc.ServerStream, fullMethod string) error {
+	set := h.getProtos()
+	if set == nil {
+		return status.Error(codes.Unimplemented, "no gRPC services are configured")
+	}
+	desc, err := set.FindMethod(fullMethod)
+	if err != nil {
+		return status.Error(codes.Unimplemented, err.Error())
+	}
+	if desc.IsStreamingClient() {
+		return status.Errorf(codes.Unimplemented, "client-streaming method %s is not supported", fullMethod)
+	}
+
+	request := dynamicpb.NewMessage(desc.Input())
+	if err := stream.RecvMsg(request); err != nil {
+		return err
+	}
+	body, err := protojson.MarshalOptions{Resolver: set.Types, EmitUnpopulated: true}.Marshal(request)
+	if err != nil {
+		return status.Errorf(codes.Internal, "encoding request as JSON: %v", err)
+	}
+	h.logger.LogDebug("gRPC %s request: %s", fullMethod, body)
+
+	r := newGRPCRequest(stream.Context(), fullMethod, body)
+	method := h.findGRPCMethod(fullMethod, body)
+	if method == nil {
+		return status.Errorf(codes.Unimplemented, "no mock of %s matches the request", fullMethod)
+	}
+
+	if len(method.Metadata) > 0 {
+		if err := stream.SetHeader(metadata.New(method.Metadata)); err != nil {
+			return err
+		}
+	}
+	if !h.wait(r, time.Duration(method.DelayMs)*time.Millisecond) {
+		return status.FromContextError(r.Context().Err()).Err()
+	}
+
+	code := codes.OK
+	if method.Status != nil {
+		// Validated with the configuration
+		code, _ = method.Status.GRPCCode()
+	}
+
+	if desc.IsStreamingServer() {
+		for i := range method.Stream {
+			message := &method.Stream[i]
+			if !h.wait(r, time.Duration(message.DelayMs)*time.Millisecond) {
+				return status.FromContextError(r.Context().Err()).Err()
+			}
+			if err := h.sendGRPCMessage(stream, r, set, desc.Output(), message.Message); err != nil {
+				return err
+			}
+		}
+	} else if code == codes.OK {
+		if err := h.sendGRPCMessage(stream, r, set, desc.Output(), method.Response); err != nil {
+			return err
+		}
+	}
+
+	if code != codes.OK {
+		return status.Error(code, fmt.Sprint(h.processResponse(method.Status.Message, r)))
+	}
+	return nil
+}
+
+// newGRPCRequest presents a call as the POST request it is on the wire, with
+// the request message as JSON body, so that templates see it as they see
+// HTTP requests
+func newGRPCRequest(ctx context.Context, fullMethod string, body []byte) *http.Request {
+	// Method names are valid paths
+	r, _ := http.NewRequestWithContext(ctx, http.MethodPost, fullMethod, bytes.NewReader(body))
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		if strings.HasPrefix(key, ":") {
+			continue
+		}
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+	return r
+}
+
+// findGRPCMethod returns the first loaded answer for the method whose match
+// the JSON request contains
+func (h *MockHandler) findGRPCMethod(fullMethod string, body []byte) *config.GRPCMethod {
+	var request interface{}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for i := range h.grpcMethods {
+		method := &h.grpcMethods[i]
+		if method.FullMethod() != fullMethod {
+			continue
+		}
+		if method.Match != nil && !containsJSON(request, h.normalizeJSON(method.Match)) {
+			continue
+		}
+		return method
+	}
+	return nil
+}
+
+// sendGRPCMessage renders a message in its JSON mapping and sends it.
+// Strings are taken as JSON text and a missing message is sent empty.
+func (h *MockHandler) sendGRPCMessage(stream grpc.ServerStream, r *http.Request, set *protoset.Set, desc protoreflect.MessageDescriptor, value interface{}) error {
+	var data []byte
+	switch v := h.processResponse(value, r).(type) {
+	case nil:
+		data = []byte("{}")
+	case string:
+		data = []byte(v)
+	default:
+		encoded, err := json.Marshal(h.convertToJSONSafe(v))
+		if err != nil {
+			return status.Errorf(codes.Internal, "encoding %s: %v", desc.FullName(), err)
+		}
+		data = encoded
+	}
+
+	message := dynamicpb.NewMessage(desc)
+	if err := (protojson.UnmarshalOptions{Resolver: set.Types}).Unmarshal(data, message); err != nil {
+		h.logger.LogError(err, "converting mocked "+string(desc.FullName()))
+		return status.Errorf(codes.Internal, "invalid mocked %s: %v", desc.FullName(), err)
+	}
+	return stream.SendMsg(message)
+}
+
+// grpcServices lists the loaded services for server reflection
+type grpcServices struct {
+	h *MockHandler
+}
+
+// GetServiceInfo implements reflection.ServiceInfoProvider
+func (s grpcServices) GetServiceInfo() map[string]grpc.ServiceInfo {
+	info := make(map[string]grpc.ServiceInfo)
+	set := s.h.getProtos()
+	if set == nil {
+		return info
+	}
+
+	for _, service := range set.Services {
+		methods := service.Methods()
+		serviceInfo := grpc.ServiceInfo{Methods: make([]grpc.MethodInfo, methods.Len())}
+		for i := 0; i < methods.Len(); i++ {
+			method := methods.Get(i)
+			serviceInfo.Methods[i] = grpc.MethodInfo{
+				Name:           string(method.Name()),
+				IsClientStream: method.IsStreamingClient(),
+				IsServerStream: method.IsStreamingServer(),
+			}
+		}
+		info[string(service.FullName())] = serviceInfo
+	}
+	return info
+}
+
+// grpcResolver resolves descriptors for server reflection from the
+// descriptors loaded at the time of the lookup
+type grpcResolver struct {
+	h *MockHandler
+}
+
+// FindFileByPath implements protodesc.Resolver
+func (r grpcResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if set := r.h.getProtos(); set != nil {
+		return set.Resolver().FindFileByPath(path)
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+// FindDescriptorByName implements protodesc.Resolver
+func (r grpcResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if set := r.h.getProtos(); set != nil {
+		return set.Resolver().FindDescriptorByName(name)
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+	"github.com/walterfan/lazy-mock-server/internal/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const testProto = `syntax = "proto3";
+package demo.v1;
+
+service Greeter {
+  rpc SayHello (HelloRequest) returns (HelloReply);
+  rpc Count (HelloRequest) returns (stream HelloReply);
+  rpc Collect (stream HelloRequest) returns (HelloReply);
+}
+
+message HelloRequest {
+  string name = 1;
+  int32 times = 2;
+}
+
+message HelloReply {
+  string message = 1;
+  int32 index = 2;
+}
+`
+
+const testGRPCConfig = `grpc:
+  protos: ["protos/*.proto"]
+  import_paths: ["protos"]
+  methods:
+    - method: "demo.v1.Greeter/SayHello"
+      match: {name: "nobody"}
+      status: {code: "NOT_FOUND", message: "no user {{.JSON.name}}"}
+    - method: "demo.v1.Greeter/SayHello"
+      metadata: {x-mock: "greeter"}
+      response:
+        message: 'Hello {{.JSON.name}} from {{index .Headers "X-Client"}}'
+    - method: "/demo.v1.Greeter/Count"
+      stream:
+        - message: {message: "one", index: 1}
+        - message: '{"message": "two", "index": 2}'
+          delay_ms: 10
+      status: {code: "RESOURCE_EXHAUSTED"}
+routes: []
+`
+
+// newGRPCTestServer serves the test configuration's services and returns a
+// connection to them
+func newGRPCTestServer(t *testing.T) (*MockHandler, *grpc.ClientConn) {
+	t.Helper()
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "protos"), 0755)
+	os.WriteFile(filepath.Join(dir, "protos", "greeter.proto"), []byte(testProto), 0644)
+	configPath := filepath.Join(dir, "mock.yaml")
+	os.WriteFile(configPath, []byte(testGRPCConfig), 0644)
+
+	configManager := config.NewManager(configPath)
+	if err := configManager.Load(); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	handler := NewMockHandler(configManager, logger.New(logger.LogLevelError))
+	if err := handler.LoadGRPC(configManager.GetConfig().GRPC); err != nil {
+		t.Fatalf("Failed to load gRPC services: %v", err)
+	}
+
+	server := grpc.NewServer(grpc.UnknownServiceHandler(handler.HandleGRPC))
+	handler.RegisterGRPCReflection(server)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return handler, conn
+}
+
+// newMessage creates a message of the loaded descriptors from its JSON mapping
+func newMessage(t *testing.T, handler *MockHandler, name, data string) *dynamicpb.Message {
+	t.Helper()
+	desc, err := handler.getProtos().Files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		t.Fatalf("Unknown message %s: %v", name, err)
+	}
+	message := dynamicpb.NewMessage(desc.(protoreflect.MessageDescriptor))
+	if err := protojson.Unmarshal([]byte(data), message); err != nil {
+		t.Fatalf("Invalid %s: %v", name, err)
+	}
+	return message
+}
+
+// messageJSON returns the JSON mapping of a message, which protojson
+// deliberately varies in white space
+func messageJSON(message *dynamicpb.Message) string {
+	data, _ := protojson.Marshal(message)
+	var value interface{}
+	json.Unmarshal(data, &value)
+	return compactJSON(value)
+}
+
+func TestGRPCUnary(t *testing.T) {
+	handler, conn := newGRPCTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-client", "tests")
+
+	reply := newMessage(t, handler, "demo.v1.HelloReply", "{}")
+	var header metadata.MD
+	err := conn.Invoke(ctx, "/demo.v1.Greeter/SayHello", newMessage(t, handler, "demo.v1.HelloRequest", `{"name": "Alice"}`), reply, grpc.Header(&header))
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if got := messageJSON(reply); got != `{"message":"Hello Alice from tests"}` {
+		t.Errorf("Unexpected reply %s", got)
+	}
+	if values := header.Get("x-mock"); len(values) != 1 || values[0] != "greeter" {
+		t.Errorf("Expected the configured metadata, got %v", header)
+	}
+
+	err = conn.Invoke(ctx, "/demo.v1.Greeter/SayHello", newMessage(t, handler, "demo.v1.HelloRequest", `{"name": "nobody"}`), reply)
+	if status.Code(err) != codes.NotFound || status.Convert(err).Message() != "no user nobody" {
+		t.Errorf("Expected the configured NOT_FOUND status, got %v", err)
+	}
+
+	for _, method := range []string{"/demo.v1.Greeter/Missing", "/demo.v1.Other/SayHello"} {
+		err = conn.Invoke(ctx, method, newMessage(t, handler, "demo.v1.HelloRequest", `{}`), reply)
+		if status.Code(err) != codes.Unimplemented {
+			t.Errorf("Expected Unimplemented for %s, got %v", method, err)
+		}
+	}
+}
+
+func TestGRPCServerStreaming(t *testing.T) {
+	handler, conn := newGRPCTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, "/demo.v1.Greeter/Count")
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	if err := stream.SendMsg(newMessage(t, handler, "demo.v1.HelloRequest", `{"name": "Bob"}`)); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	stream.CloseSend()
+
+	var replies []string
+	for {
+		reply := newMessage(t, handler, "demo.v1.HelloReply", "{}")
+		err = stream.RecvMsg(reply)
+		if err != nil {
+			break
+		}
+		replies = append(replies, messageJSON(reply))
+	}
+	if len(replies) != 2 || replies[0] != `{"index":1,"message":"one"}` || replies[1] != `{"index":2,"message":"two"}` {
+		t.Errorf("Unexpected stream %v", replies)
+	}
+	if err == io.EOF || status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected the stream to end with RESOURCE_EXHAUSTED, got %v", err)
+	}
+}
+
+func TestGRPCReflection(t *testing.T) {
+	_, conn := newGRPCTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := reflectionv1.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatalf("Failed to open reflection stream: %v", err)
+	}
+	stream.Send(&reflectionv1.ServerReflectionRequest{MessageRequest: &reflectionv1.ServerReflectionRequest_ListServices{}})
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to list services: %v", err)
+	}
+	services := resp.GetListServicesResponse().GetService()
+	if len(services) != 1 || services[0].Name != "demo.v1.Greeter" {
+		t.Errorf("Expected the Greeter service, got %v", services)
+	}
+
+	stream.Send(&reflectionv1.ServerReflectionRequest{MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: "demo.v1.Greeter"}})
+	resp, err = stream.Recv()
+	if err != nil || len(resp.GetFileDescriptorResponse().GetFileDescriptorProto()) == 0 {
+		t.Errorf("Expected the file defining the service, got %v %v", resp, err)
+	}
+}
+
+func TestGRPCUsesLoadedMethods(t *testing.T) {
+	handler, conn := newGRPCTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sayHello := func() string {
+		t.Helper()
+		reply := newMessage(t, handler, "demo.v1.HelloReply", "{}")
+		if err := conn.Invoke(ctx, "/demo.v1.Greeter/SayHello", newMessage(t, handler, "demo.v1.HelloRequest", `{"name": "Bob"}`), reply); err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+		return messageJSON(reply)
+	}
+
+	// Methods changed without LoadGRPC, e.g. through the management API, are
+	// not checked against the descriptors and must not be answered with
+	cfg := handler.configManager.Clone()
+	cfg.GRPC.Methods = []config.GRPCMethod{{Method: "demo.v1.Greeter/SayHello", Response: map[string]interface{}{"message": "changed"}}}
+	handler.ReplaceConfig(cfg)
+	if got := sayHello(); got != `{"message":"Hello Bob from "}` {
+		t.Errorf("Expected the loaded methods to answer, got %s", got)
+	}
+
+	if err := handler.LoadGRPC(cfg.GRPC); err != nil {
+		t.Fatalf("Failed to load gRPC services: %v", err)
+	}
+	if got := sayHello(); got != `{"message":"changed"}` {
+		t.Errorf("Expected the newly loaded methods to answer, got %s", got)
+	}
+}
+
+func TestLoadGRPCErrors(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "greeter.proto"), []byte(testProto), 0644)
+	handler, _ := createTestHandler()
+
+	tests := []struct {
+		name   string
+		method config.GRPCMethod
+	}{
+		{"unknown method", config.GRPCMethod{Method: "demo.v1.Greeter/Missing"}},
+		{"client streaming", config.GRPCMethod{Method: "demo.v1.Greeter/Collect"}},
+		{"unary with stream", config.GRPCMethod{Method: "demo.v1.Greeter/SayHello", Stream: []config.GRPCMessage{{Message: "{}"}}}},
+		{"streaming with response", config.GRPCMethod{Method: "demo.v1.Greeter/Count", Response: "{}"}},
+		{"invalid status", config.GRPCMethod{Method: "demo.v1.Greeter/SayHello", Status: &config.GRPCStatus{Code: "MISSING"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.GRPC{Protos: []string{filepath.Join(dir, "greeter.proto")}, ImportPaths: []string{dir}, Methods: []config.GRPCMethod{tt.method}}
+			if err := handler.LoadGRPC(cfg); err == nil {
+				t.Errorf("Expected an error for %+v", tt.method)
+			}
+		})
+	}
+	if handler.getProtos() != nil {
+		t.Error("Expected failed loads to keep the current descriptors")
+	}
+}
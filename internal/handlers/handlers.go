@@ -1,16 +1,36 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"text/template"
 
 	"github.com/walterfan/lazy-mock-server/internal/config"
+	"github.com/walterfan/lazy-mock-server/internal/jsonpath"
 	"github.com/walterfan/lazy-mock-server/internal/logger"
+	"github.com/walterfan/lazy-mock-server/internal/protoset"
+	"github.com/walterfan/lazy-mock-server/internal/xmldoc"
+	"gopkg.in/yaml.v2"
+)
+
+// contextKey is the type of request context keys set by the handler
+type contextKey int
+
+const (
+	// pathParamsKey holds the path parameters captured by the matched route
+	pathParamsKey contextKey = iota
+	// journalEntryKey holds the journal entry of the request
+	journalEntryKey
 )
 
 // MockHandler handles HTTP requests for mock endpoints
@@ -18,6 +38,22 @@ type MockHandler struct {
 	configManager *config.Manager
 	logger        *logger.Logger
 	mutex         sync.RWMutex
+	patterns      map[string]*config.PathPattern
+	regexes       map[string]*regexp.Regexp
+	predicates    map[string]*jsonpath.Predicate
+	xpaths        map[string]*xmldoc.Expr
+	templates     map[string]*template.Template
+	specs         map[string]*loadedSpec
+	patternMutex  sync.Mutex
+	scenarios     *scenarioStore
+	sequences     *sequenceStore
+	proxy         *config.ProxyConfig
+	recorder      *recorder
+	journal       *journal
+	// protos holds the descriptors of the mocked gRPC services and
+	// grpcMethods the answers LoadGRPC checked against them
+	protos      *protoset.Set
+	grpcMethods []config.GRPCMethod
 }
 
 // NewMockHandler creates a new mock handler
@@ -25,9 +61,25 @@ func NewMockHandler(configManager *config.Manager, logger *logger.Logger) *MockH
 	return &MockHandler{
 		configManager: configManager,
 		logger:        logger,
+		patterns:      make(map[string]*config.PathPattern),
+		regexes:       make(map[string]*regexp.Regexp),
+		predicates:    make(map[string]*jsonpath.Predicate),
+		xpaths:        make(map[string]*xmldoc.Expr),
+		templates:     make(map[string]*template.Template),
+		specs:         make(map[string]*loadedSpec),
+		scenarios:     newScenarioStore(),
+		sequences:     newSequenceStore(),
+		journal:       newJournal(DefaultJournalSize),
 	}
 }
 
+// PathParams returns the path parameters captured for the request, e.g. the
+// value of {id} in /api/users/{id}
+func PathParams(r *http.Request) map[string]string {
+	params, _ := r.Context().Value(pathParamsKey).(map[string]string)
+	return params
+}
+
 // ServeHTTP handles all incoming HTTP requests
 func (h *MockHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Handle management API endpoints
@@ -36,20 +88,47 @@ func (h *MockHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Handle regular mock endpoints
-	h.handleMockEndpoint(w, r)
+	h.serveJournaled(w, r, func(w http.ResponseWriter, r *http.Request) {
+		// Forward and record traffic in record mode
+		if rec := h.recording(); rec != nil {
+			h.handleRecord(w, r, rec)
+			return
+		}
+
+		// Handle regular mock endpoints
+		h.handleMockEndpoint(w, r)
+	})
 }
 
 // handleMockEndpoint handles regular mock API requests
 func (h *MockHandler) handleMockEndpoint(w http.ResponseWriter, r *http.Request) {
-	h.mutex.RLock()
-	route := h.findMatchingRoute(r)
-	h.mutex.RUnlock()
+	if validation := h.globalValidation(); validation != nil && !h.validateRequest(w, r, validation) {
+		return
+	}
 
-	if route == nil {
+	match, valid := h.findAndAdvanceRoute(w, r)
+	if !valid {
+		return
+	}
+	if match == nil {
 		h.handleNotFound(w, r)
 		return
 	}
+	r = withPathParams(r, match)
+
+	route := h.selectResponse(match)
+
+	if route.Proxy != "" {
+		if h.waitRoute(w, r, route) {
+			h.proxyRequest(w, r, &config.ProxyConfig{Target: route.Proxy, Headers: route.ProxyHeaders})
+		}
+		return
+	}
+
+	if route.WebSocket != nil {
+		h.serveWebSocket(w, r, route)
+		return
+	}
 
 	// Set custom headers if specified
 	if route.Headers != nil {
@@ -58,10 +137,10 @@ func (h *MockHandler) handleMockEndpoint(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	// Set content type (default to application/json if not specified)
+	// Set content type (default to application/json for response bodies)
 	contentType := route.ContentType
 	if contentType == "" {
-		contentType = "application/json"
+		contentType = defaultContentType(route)
 	}
 	w.Header().Set("Content-Type", contentType)
 
@@ -70,40 +149,153 @@ func (h *MockHandler) handleMockEndpoint(w http.ResponseWriter, r *http.Request)
 	if statusCode == 0 {
 		statusCode = 200
 	}
-	w.WriteHeader(statusCode)
+
+	if route.SSE != nil {
+		h.serveEvents(w, r, route, statusCode)
+		return
+	}
+
+	if route.BodyFile != "" {
+		h.serveBodyFile(w, r, route, statusCode)
+		return
+	}
 
 	// Process response body
-	responseBody := h.processResponse(route.Response, r)
+	var body []byte
+	if route.BodyBase64 != "" {
+		decoded, err := config.DecodeBase64(route.BodyBase64)
+		if err != nil {
+			h.logger.LogErrorWithRequest(err, r, "decoding body_base64")
+			http.Error(w, "Invalid body_base64", http.StatusInternalServerError)
+			return
+		}
+		body = decoded
+	} else {
+		response := route.Response
+		if response == nil && route.GraphQL != nil && route.GraphQL.HasPayload() {
+			response = route.GraphQL.Payload()
+		}
+		responseBody := h.processResponse(response, r)
+		body = h.renderBody(contentType, responseBody)
+	}
+
+	if !h.waitRoute(w, r, route) {
+		return
+	}
+
+	if route.Fault != "" {
+		h.injectFault(w, r, route.Fault, statusCode, body)
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(statusCode)
+	h.writeBody(w, r, route.Dribble, body)
+}
+
+// defaultContentType is the content type of routes that do not set one
+func defaultContentType(route *config.Route) string {
+	switch {
+	case route.SSE != nil:
+		return "text/event-stream"
+	case route.BodyFile != "":
+		return bodyFileContentType(route.BodyFile)
+	case route.BodyBase64 != "":
+		return "application/octet-stream"
+	default:
+		return "application/json"
+	}
+}
+
+// renderBody serializes the processed response according to the content
+// type. Raw bytes are written as they are.
+func (h *MockHandler) renderBody(contentType string, responseBody interface{}) []byte {
+	if raw, ok := responseBody.([]byte); ok {
+		return raw
+	}
+
+	switch responseBody.(type) {
+	case map[string]interface{}, yaml.MapSlice:
+		if !xmldoc.IsXMLContentType(contentType) {
+			break
+		}
+		data, err := xmldoc.Marshal(responseBody)
+		if err == nil {
+			return data
+		}
+		h.logger.LogError(err, "encoding XML response")
+	}
+
+	var buf bytes.Buffer
 
 	// Write response based on content type
-	switch contentType {
-	case "application/json":
-		if str, ok := responseBody.(string); ok {
-			// If response is already a string, try to parse as JSON
-			var jsonObj interface{}
-			if err := json.Unmarshal([]byte(str), &jsonObj); err == nil {
-				if err := json.NewEncoder(w).Encode(jsonObj); err != nil {
-					h.logger.LogError(err, "encoding JSON object")
-				}
-			} else {
-				// If not valid JSON, wrap in quotes
-				if err := json.NewEncoder(w).Encode(str); err != nil {
-					h.logger.LogError(err, "encoding string response")
-				}
+	if !config.IsJSONContentType(contentType) {
+		// For text/plain and other content types, convert to string
+		fmt.Fprintf(&buf, "%v", responseBody)
+	} else if str, ok := responseBody.(string); ok {
+		// If response is already a string, try to parse as JSON
+		var jsonObj interface{}
+		if err := json.Unmarshal([]byte(str), &jsonObj); err == nil {
+			if err := json.NewEncoder(&buf).Encode(jsonObj); err != nil {
+				h.logger.LogError(err, "encoding JSON object")
 			}
 		} else {
-			// Convert to JSON-safe format before encoding
-			jsonSafeResponse := h.convertToJSONSafe(responseBody)
-			if err := json.NewEncoder(w).Encode(jsonSafeResponse); err != nil {
-				h.logger.LogError(err, "encoding response body")
+			// If not valid JSON, wrap in quotes
+			if err := json.NewEncoder(&buf).Encode(str); err != nil {
+				h.logger.LogError(err, "encoding string response")
 			}
 		}
-	default:
-		// For text/plain and other content types, convert to string
-		if _, err := fmt.Fprintf(w, "%v", responseBody); err != nil {
-			h.logger.LogError(err, "writing response body")
+	} else {
+		// Convert to JSON-safe format before encoding
+		jsonSafeResponse := h.convertToJSONSafe(responseBody)
+		if err := json.NewEncoder(&buf).Encode(jsonSafeResponse); err != nil {
+			h.logger.LogError(err, "encoding response body")
 		}
 	}
+
+	return buf.Bytes()
+}
+
+// findAndAdvanceRoute finds the matching route, validates the request
+// against the route's validation settings and then moves its scenario to the
+// route's new state, so invalid requests leave the scenario alone. It answers
+// invalid requests itself and reports false. If another request changed the
+// scenario state between matching and the transition, matching is retried.
+func (h *MockHandler) findAndAdvanceRoute(w http.ResponseWriter, r *http.Request) (*routeMatch, bool) {
+	for {
+		h.mutex.RLock()
+		match := h.findMatchingRoute(r)
+		h.mutex.RUnlock()
+
+		if match == nil {
+			return nil, true
+		}
+
+		setJournalRoute(r, match.key)
+
+		if validation := match.route.Validation; validation != nil && !h.validateRequest(w, withPathParams(r, match), validation) {
+			return nil, false
+		}
+
+		if match.route.Scenario == "" || match.route.NewState == "" {
+			return match, true
+		}
+
+		route := match.route
+		if h.scenarios.transition(route.Scenario, route.RequiredState, route.NewState) {
+			h.logger.LogDebug("Scenario %s moved to state %s", route.Scenario, route.NewState)
+			return match, true
+		}
+	}
+}
+
+// withPathParams makes the path parameters captured by a match available to
+// the rest of the request handling
+func withPathParams(r *http.Request, match *routeMatch) *http.Request {
+	if len(match.pathParams) == 0 {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), pathParamsKey, match.pathParams))
 }
 
 // convertToJSONSafe converts YAML interface{} types to JSON-compatible types
@@ -118,6 +310,15 @@ func (h *MockHandler) convertToJSONSafe(data interface{}) interface{} {
 			}
 		}
 		return result
+	case yaml.MapSlice:
+		// Convert ordered maps of XML responses to JSON objects
+		result := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			if strKey, ok := item.Key.(string); ok {
+				result[strKey] = h.convertToJSONSafe(item.Value)
+			}
+		}
+		return result
 	case []interface{}:
 		// Convert slice elements recursively
 		result := make([]interface{}, len(v))
@@ -159,6 +360,22 @@ func (h *MockHandler) handleManagementAPI(w http.ResponseWriter, r *http.Request
 		h.handleGetConfig(w, r)
 	case r.URL.Path == "/_mock/config" && r.Method == "POST":
 		h.handleSaveConfig(w, r)
+	case r.URL.Path == "/_mock/scenarios" || strings.HasPrefix(r.URL.Path, "/_mock/scenarios/"):
+		h.handleScenariosAPI(w, r)
+	case strings.HasPrefix(r.URL.Path, "/_mock/sequences"):
+		h.handleSequencesAPI(w, r)
+	case r.URL.Path == "/_mock/requests":
+		h.handleRequestsAPI(w, r)
+	case r.URL.Path == "/_mock/verify" && r.Method == "POST":
+		h.handleVerify(w, r)
+	case r.URL.Path == "/_mock/import/openapi" && r.Method == "POST":
+		h.handleImportOpenAPI(w, r)
+	case r.URL.Path == "/_mock/import/postman" && r.Method == "POST":
+		h.handleImportPostman(w, r)
+	case r.URL.Path == "/_mock/import/har" && r.Method == "POST":
+		h.handleImportHAR(w, r)
+	case r.URL.Path == "/_mock/export/openapi" && r.Method == "GET":
+		h.handleExportOpenAPI(w, r)
 	case r.URL.Path == "/_mock/ui" && r.Method == "GET":
 		h.handleWebUI(w, r)
 	default:
@@ -184,6 +401,61 @@ func (h *MockHandler) handleGetRoutes(w http.ResponseWriter, r *http.Request) {
 			"response":     route.GetJSONSafeResponse(),
 			"headers":      route.Headers,
 			"parameters":   route.Parameters,
+			"match":        route.Match,
+		}
+		if len(route.Responses) > 0 {
+			responses := make([]map[string]interface{}, len(route.Responses))
+			for j, variant := range route.Responses {
+				responses[j] = map[string]interface{}{
+					"status_code":  variant.StatusCode,
+					"content_type": variant.ContentType,
+					"response":     variant.GetJSONSafeResponse(),
+					"headers":      variant.Headers,
+					"weight":       variant.Weight,
+				}
+				if variant.BodyFile != "" {
+					responses[j]["body_file"] = variant.BodyFile
+				}
+				if variant.BodyBase64 != "" {
+					responses[j]["body_base64"] = variant.BodyBase64
+				}
+			}
+			jsonSafeRoutes[i]["responses"] = responses
+			jsonSafeRoutes[i]["response_mode"] = route.ResponseMode
+		}
+		if route.BodyFile != "" {
+			jsonSafeRoutes[i]["body_file"] = route.BodyFile
+			jsonSafeRoutes[i]["template"] = route.Template
+		}
+		if route.BodyBase64 != "" {
+			jsonSafeRoutes[i]["body_base64"] = route.BodyBase64
+		}
+		if route.SSE != nil {
+			events := make([]map[string]interface{}, len(route.SSE.Events))
+			for j, event := range route.SSE.Events {
+				events[j] = map[string]interface{}{
+					"id":       event.ID,
+					"event":    event.Event,
+					"data":     event.GetJSONSafeData(),
+					"retry":    event.Retry,
+					"delay_ms": event.DelayMs,
+				}
+			}
+			jsonSafeRoutes[i]["sse"] = map[string]interface{}{"events": events, "loop": route.SSE.Loop}
+		}
+		if route.WebSocket != nil {
+			jsonSafeRoutes[i]["websocket"] = route.WebSocket.GetJSONSafe()
+		}
+		if route.GraphQL != nil {
+			jsonSafeRoutes[i]["graphql"] = route.GraphQL.GetJSONSafe()
+		}
+		if route.Source != "" {
+			jsonSafeRoutes[i]["source"] = route.Source
+		}
+		if route.Scenario != "" {
+			jsonSafeRoutes[i]["scenario"] = route.Scenario
+			jsonSafeRoutes[i]["required_state"] = route.RequiredState
+			jsonSafeRoutes[i]["new_state"] = route.NewState
 		}
 	}
 
@@ -224,9 +496,18 @@ func (h *MockHandler) handleAddRoute(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.mutex.Lock()
-	h.configManager.AddRoute(newRoute)
+	source, err := h.configManager.ResolveSource(newRoute.Source)
+	if err == nil {
+		newRoute.Source = source
+		h.configManager.AddRoute(newRoute)
+	}
 	h.mutex.Unlock()
 
+	if err != nil {
+		h.writeBadRequest(w, err.Error())
+		return
+	}
+
 	h.logger.LogInfo("Added new route: %s %s", newRoute.Method, newRoute.Path)
 
 	w.WriteHeader(http.StatusCreated)
@@ -273,7 +554,23 @@ func (h *MockHandler) handleUpdateRoute(w http.ResponseWriter, r *http.Request)
 	}
 
 	h.mutex.Lock()
-	err := h.configManager.DeleteRouteByPath(routePath)
+	source, err := h.configManager.ResolveSource(updatedRoute.Source)
+	if err != nil {
+		h.mutex.Unlock()
+		h.writeBadRequest(w, err.Error())
+		return
+	}
+	if source == "" {
+		// Keep the route in the file it came from
+		for _, route := range h.configManager.GetRoutes() {
+			if route.Path == routePath {
+				source = route.Source
+				break
+			}
+		}
+	}
+	updatedRoute.Source = source
+	err = h.configManager.DeleteRouteByPath(routePath)
 	if err == nil {
 		h.configManager.AddRoute(updatedRoute)
 	}
@@ -385,50 +682,151 @@ func (h *MockHandler) handleWebUI(w http.ResponseWriter, r *http.Request) {
 
 // handleNotFound handles requests that don't match any route
 func (h *MockHandler) handleNotFound(w http.ResponseWriter, r *http.Request) {
+	if proxy := h.fallbackProxy(); proxy != nil && proxy.Target != "" {
+		h.proxyRequest(w, r, proxy)
+		return
+	}
+
+	h.mutex.RLock()
+	nearMisses := h.findNearMisses(r)
+	h.mutex.RUnlock()
+
+	if len(nearMisses) > 0 {
+		h.logger.LogInfo("No route matched %s %s, closest:", r.Method, r.URL.Path)
+		for _, nearMiss := range nearMisses {
+			h.logger.LogInfo("  %s", nearMiss.Explanation)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusNotFound)
-	response := map[string]string{
+	response := map[string]interface{}{
 		"error":  "Route not found",
 		"path":   r.URL.Path,
 		"method": r.Method,
 	}
+	if len(nearMisses) > 0 {
+		response["near_misses"] = nearMisses
+	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		h.logger.LogError(err, "encoding not found response")
 	}
 }
 
-// findMatchingRoute finds the first route that matches the request
-func (h *MockHandler) findMatchingRoute(r *http.Request) *config.Route {
+// routeMatch is a route matched by a request
+type routeMatch struct {
+	route      *config.Route
+	pathParams map[string]string
+	// key identifies the route across requests: method, path and the
+	// occurrence among routes sharing both
+	key string
+}
+
+// findMatchingRoute finds the first route that matches the request and
+// returns it along with the captured path parameters
+func (h *MockHandler) findMatchingRoute(r *http.Request) *routeMatch {
 	routes := h.configManager.GetRoutes()
-	for _, route := range routes {
-		if h.matchesRoute(&route, r) {
-			return &route
+	for i, route := range routes {
+		if pathParams, ok := h.matchRoute(&route, r); ok {
+			return &routeMatch{
+				route:      &route,
+				pathParams: pathParams,
+				key:        config.RouteKey(routes, i),
+			}
 		}
 	}
 	return nil
 }
 
-// matchesRoute checks if a route matches the request
-func (h *MockHandler) matchesRoute(route *config.Route, r *http.Request) bool {
+// matchRoute checks if a route matches the request and returns the
+// captured path parameters
+func (h *MockHandler) matchRoute(route *config.Route, r *http.Request) (map[string]string, bool) {
 	// Check HTTP method
 	if !strings.EqualFold(route.Method, r.Method) {
-		return false
+		return nil, false
 	}
 
 	// Check path - support exact match and pattern matching
-	if h.matchesPath(route.Path, r.URL.Path) {
-		// Check parameters if specified
-		if route.Parameters != nil {
-			return h.matchesParameters(route.Parameters, r)
-		}
-		return true
+	pathParams, ok := h.matchPath(route.Path, r.URL.Path)
+	if !ok {
+		return nil, false
 	}
 
-	return false
+	// Check scenario state if specified
+	if !h.matchesScenario(route) {
+		return nil, false
+	}
+
+	// Check parameters if specified
+	if route.Parameters != nil && !h.matchesParameters(route.Parameters, r) {
+		return nil, false
+	}
+
+	// Check headers if specified
+	if route.Match != nil && route.Match.Headers != nil && !h.matchesHeaders(route.Match.Headers, r) {
+		return nil, false
+	}
+
+	// Check SOAP action if specified
+	if route.Match != nil && route.Match.SOAPAction != "" && !matchesSOAPAction(route.Match.SOAPAction, r) {
+		return nil, false
+	}
+
+	// Check body if specified
+	if route.Match != nil && route.Match.Body != nil && !h.matchesBody(route.Match.Body, r) {
+		return nil, false
+	}
+
+	// Check GraphQL operation if specified
+	if route.GraphQL != nil && !h.matchesGraphQL(route.GraphQL, r) {
+		return nil, false
+	}
+
+	return pathParams, true
+}
+
+// matchPath checks if the route path matches the request path and returns
+// the values of named parameters such as {id} or {id:int}
+func (h *MockHandler) matchPath(routePath, requestPath string) (map[string]string, bool) {
+	if !config.HasPathParams(routePath) {
+		return nil, h.matchesPath(routePath, requestPath)
+	}
+
+	pattern, err := h.getPathPattern(routePath)
+	if err != nil {
+		h.logger.LogError(err, "compiling path pattern")
+		return nil, false
+	}
+
+	return pattern.Match(requestPath)
+}
+
+// getPathPattern returns the compiled pattern for a route path, caching it
+// for subsequent requests
+func (h *MockHandler) getPathPattern(routePath string) (*config.PathPattern, error) {
+	h.patternMutex.Lock()
+	defer h.patternMutex.Unlock()
+
+	if pattern, ok := h.patterns[routePath]; ok {
+		return pattern, nil
+	}
+
+	pattern, err := config.CompilePathPattern(routePath)
+	if err != nil {
+		return nil, err
+	}
+
+	h.patterns[routePath] = pattern
+	return pattern, nil
 }
 
 // matchesPath checks if the route path matches the request path
 func (h *MockHandler) matchesPath(routePath, requestPath string) bool {
+	if config.HasPathParams(routePath) {
+		_, ok := h.matchPath(routePath, requestPath)
+		return ok
+	}
+
 	// Remove trailing slashes for comparison
 	routePath = strings.TrimSuffix(routePath, "/")
 	requestPath = strings.TrimSuffix(requestPath, "/")
@@ -455,7 +853,7 @@ func (h *MockHandler) matchesPath(routePath, requestPath string) bool {
 
 // matchesParameters checks if request parameters match the route requirements
 func (h *MockHandler) matchesParameters(routeParams map[string]string, r *http.Request) bool {
-	if err := r.ParseForm(); err != nil {
+	if err := h.parseForm(r); err != nil {
 		h.logger.LogError(err, "parsing form parameters")
 		return false
 	}
@@ -469,26 +867,233 @@ func (h *MockHandler) matchesParameters(routeParams map[string]string, r *http.R
 	return true
 }
 
-// processResponse processes the response body, handling dynamic content
-func (h *MockHandler) processResponse(response interface{}, r *http.Request) interface{} {
-	if str, ok := response.(string); ok {
-		// Replace placeholders with request data
-		str = strings.ReplaceAll(str, "{method}", r.Method)
-		str = strings.ReplaceAll(str, "{path}", r.URL.Path)
-		str = strings.ReplaceAll(str, "{query}", r.URL.RawQuery)
+// matchesHeaders checks if request headers satisfy the route's header matchers
+func (h *MockHandler) matchesHeaders(matchers map[string]config.HeaderMatcher, r *http.Request) bool {
+	for name, matcher := range matchers {
+		if !h.matchesHeader(matcher, r.Header.Values(name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesHeader checks a single header matcher against the header's values.
+// When the header is repeated, any one value satisfying the checks is enough.
+func (h *MockHandler) matchesHeader(matcher config.HeaderMatcher, values []string) bool {
+	if matcher.Present != nil {
+		if !*matcher.Present {
+			return len(values) == 0
+		}
+		if len(values) == 0 {
+			return false
+		}
+	}
+
+	if matcher.Equals == "" && matcher.Contains == "" && matcher.Matches == "" {
+		return true
+	}
 
-		// Replace query parameters
-		for key, values := range r.URL.Query() {
-			if len(values) > 0 {
-				placeholder := fmt.Sprintf("{%s}", key)
-				str = strings.ReplaceAll(str, placeholder, values[0])
+	for _, value := range values {
+		if matcher.Equals != "" && value != matcher.Equals {
+			continue
+		}
+		if matcher.Contains != "" && !strings.Contains(value, matcher.Contains) {
+			continue
+		}
+		if matcher.Matches != "" {
+			regex, err := h.getRegex(matcher.Matches)
+			if err != nil {
+				h.logger.LogError(err, "compiling header regex")
+				return false
+			}
+			if !regex.MatchString(value) {
+				continue
 			}
 		}
+		return true
+	}
+	return false
+}
 
-		return str
+// matchesBody checks if the request body satisfies the route's body matcher:
+// JSON checks against the body as JSON and XPath checks against it as XML
+func (h *MockHandler) matchesBody(matcher *config.BodyMatcher, r *http.Request) bool {
+	data := h.readBody(r)
+	if matcher.ChecksJSON() && !h.matchesJSON(matcher, data) {
+		return false
+	}
+	return len(matcher.XPath) == 0 || h.matchesXML(matcher, data)
+}
+
+// matchesJSON checks if a JSON document satisfies a body matcher
+func (h *MockHandler) matchesJSON(matcher *config.BodyMatcher, data []byte) bool {
+	var body interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return false
+	}
+
+	if matcher.EqualToJSON != nil && !reflect.DeepEqual(h.normalizeJSON(matcher.EqualToJSON), body) {
+		return false
 	}
 
-	return response
+	if matcher.PartialJSON != nil && !containsJSON(body, h.normalizeJSON(matcher.PartialJSON)) {
+		return false
+	}
+
+	for _, expr := range matcher.JSONPath {
+		predicate, err := h.getPredicate(expr)
+		if err != nil {
+			h.logger.LogError(err, "compiling body JSONPath predicate")
+			return false
+		}
+		if !predicate.Match(body) {
+			return false
+		}
+	}
+
+	for expr, pattern := range matcher.Matches {
+		regex, err := h.getRegex(pattern)
+		if err != nil {
+			h.logger.LogError(err, "compiling body regex")
+			return false
+		}
+		value, ok := jsonpath.Get(body, expr)
+		str, isString := value.(string)
+		if !ok || !isString || !regex.MatchString(str) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// containsJSON reports whether actual contains expected as a subset: objects
+// may have extra keys and each expected array element must match some
+// element of the actual array
+func containsJSON(actual, expected interface{}) bool {
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		act, ok := actual.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range exp {
+			actualValue, ok := act[key]
+			if !ok || !containsJSON(actualValue, value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		act, ok := actual.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, value := range exp {
+			found := false
+			for _, actualValue := range act {
+				if containsJSON(actualValue, value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(actual, expected)
+	}
+}
+
+// normalizeJSON converts a YAML or JSON value into the representation
+// produced by encoding/json so it can be compared with a decoded body
+func (h *MockHandler) normalizeJSON(data interface{}) interface{} {
+	encoded, err := json.Marshal(h.convertToJSONSafe(data))
+	if err != nil {
+		return nil
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(encoded, &normalized); err != nil {
+		return nil
+	}
+	return normalized
+}
+
+// parseForm parses form values while keeping the body readable for body
+// matchers and templates, since ParseForm consumes it
+func (h *MockHandler) parseForm(r *http.Request) error {
+	body := h.readBody(r)
+	err := r.ParseForm()
+	if r.Body != nil {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return err
+}
+
+// readBody reads the request body and restores it so it can be read again
+func (h *MockHandler) readBody(r *http.Request) []byte {
+	if r.Body == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.logger.LogError(err, "reading request body")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body
+}
+
+// getPredicate returns the compiled JSONPath predicate, caching it for
+// subsequent requests
+func (h *MockHandler) getPredicate(expr string) (*jsonpath.Predicate, error) {
+	h.patternMutex.Lock()
+	defer h.patternMutex.Unlock()
+
+	if predicate, ok := h.predicates[expr]; ok {
+		return predicate, nil
+	}
+
+	predicate, err := jsonpath.ParsePredicate(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	h.predicates[expr] = predicate
+	return predicate, nil
+}
+
+// getRegex returns the compiled regular expression, caching it for
+// subsequent requests
+func (h *MockHandler) getRegex(expr string) (*regexp.Regexp, error) {
+	h.patternMutex.Lock()
+	defer h.patternMutex.Unlock()
+
+	if regex, ok := h.regexes[expr]; ok {
+		return regex, nil
+	}
+
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	h.regexes[expr] = regex
+	return regex, nil
+}
+
+// ReplaceConfig swaps in a new configuration, e.g. after the configuration
+// file changed, and returns the previous one
+func (h *MockHandler) ReplaceConfig(cfg *config.Config) *config.Config {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	previous := h.configManager.GetConfig()
+	h.configManager.SetConfig(cfg)
+	return previous
 }
 
 // GetConfigManager returns the configuration manager
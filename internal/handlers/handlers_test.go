@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -391,6 +393,9 @@ func TestMatchesPath(t *testing.T) {
 		{"/api/users", "/api/posts", false},
 		{"/api/*", "/api/anything", true},
 		{"/api/*", "/different/path", false},
+		{"/api/users/{id}", "/api/users/123", true},
+		{"/api/users/{id:int}", "/api/users/abc", false},
+		{"/api/users/{id}/orders/{orderId}", "/api/users/1/orders/2", true},
 	}
 
 	for _, tt := range tests {
@@ -403,6 +408,37 @@ func TestMatchesPath(t *testing.T) {
 	}
 }
 
+func TestPathParamsInResponse(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path:        "/api/users/{id:int}/orders/{orderId}",
+		Method:      "GET",
+		StatusCode:  200,
+		ContentType: "text/plain",
+		Response:    "user {path.id} order {path.orderId}",
+	})
+
+	req := httptest.NewRequest("GET", "/api/users/42/orders/A-7", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if w.Body.String() != "user 42 order A-7" {
+		t.Errorf("Expected captured values in response, got '%s'", w.Body.String())
+	}
+
+	// Typed constraint rejects non-numeric ids
+	req = httptest.NewRequest("GET", "/api/users/john/orders/A-7", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != 404 {
+		t.Errorf("Expected status 404 for constraint mismatch, got %d", w.Code)
+	}
+}
+
 func TestMatchesParameters(t *testing.T) {
 	handler, _ := createTestHandler()
 
@@ -436,6 +472,159 @@ func TestMatchesParameters(t *testing.T) {
 	}
 }
 
+func TestMatchesHeaders(t *testing.T) {
+	handler, configManager := createTestHandler()
+	present, absent := true, false
+
+	configManager.AddRoute(config.Route{
+		Path:        "/api/report",
+		Method:      "GET",
+		StatusCode:  200,
+		ContentType: "application/xml",
+		Response:    "<report/>",
+		Match: &config.RequestMatch{Headers: map[string]config.HeaderMatcher{
+			"Accept": {Contains: "application/xml"},
+		}},
+	})
+	configManager.AddRoute(config.Route{
+		Path:        "/api/report",
+		Method:      "GET",
+		StatusCode:  200,
+		ContentType: "text/plain",
+		Response:    "authorized",
+		Match: &config.RequestMatch{Headers: map[string]config.HeaderMatcher{
+			"Authorization": {Present: &present, Matches: "^Bearer .+$"},
+		}},
+	})
+	configManager.AddRoute(config.Route{
+		Path:        "/api/report",
+		Method:      "GET",
+		StatusCode:  401,
+		ContentType: "text/plain",
+		Response:    "unauthorized",
+		Match: &config.RequestMatch{Headers: map[string]config.HeaderMatcher{
+			"Authorization": {Present: &absent},
+		}},
+	})
+
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Accept XML", map[string]string{"Accept": "text/html, application/xml"}, 200, "<report/>"},
+		{"Bearer token", map[string]string{"Authorization": "Bearer abc"}, 200, "authorized"},
+		{"No authorization", nil, 401, "unauthorized"},
+		{"Basic token", map[string]string{"Authorization": "Basic abc"}, 404, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/report", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedBody != "" && w.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body '%s', got '%s'", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAddRouteWithHeaderMatch(t *testing.T) {
+	handler, _ := createTestHandler()
+
+	body := `{"path": "/api/xml", "method": "GET", "status_code": 200, "content_type": "text/plain",
+		"response": "xml", "match": {"headers": {"Accept": {"equals": "application/xml"}}}}`
+	req := httptest.NewRequest("POST", "/_mock/routes", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != 201 {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/xml", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != 404 {
+		t.Errorf("Expected 404 without Accept header, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/xml", nil)
+	req.Header.Set("Accept", "application/xml")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Errorf("Expected 200 with Accept header, got %d", w.Code)
+	}
+}
+
+func TestMatchesBody(t *testing.T) {
+	handler, configManager := createTestHandler()
+
+	routes := []config.Route{
+		{
+			Path: "/api/users", Method: "POST", StatusCode: 409, ContentType: "text/plain", Response: "exists",
+			Match: &config.RequestMatch{Body: &config.BodyMatcher{
+				EqualToJSON: map[interface{}]interface{}{"name": "taken"},
+			}},
+		},
+		{
+			Path: "/api/users", Method: "POST", StatusCode: 400, ContentType: "text/plain", Response: "invalid email",
+			Match: &config.RequestMatch{Body: &config.BodyMatcher{
+				Matches: map[string]string{"$.user.email": "^[^@]+$"},
+			}},
+		},
+		{
+			Path: "/api/users", Method: "POST", StatusCode: 201, ContentType: "text/plain", Response: "adult",
+			Match: &config.RequestMatch{Body: &config.BodyMatcher{
+				PartialJSON: map[string]interface{}{"user": map[string]interface{}{"role": "member"}},
+				JSONPath:    []string{"$.user.age > 18"},
+			}},
+		},
+		{
+			Path: "/api/users", Method: "POST", StatusCode: 403, ContentType: "text/plain", Response: "minor",
+		},
+	}
+	for _, route := range routes {
+		configManager.AddRoute(route)
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"Exact equality", `{"name": "taken"}`, 409},
+		{"Extra field breaks equality", `{"name": "taken", "x": 1}`, 403},
+		{"Regex on field", `{"user": {"email": "no-at-sign"}}`, 400},
+		{"Partial and JSONPath", `{"user": {"role": "member", "age": 30, "email": "a@b.c"}}`, 201},
+		{"JSONPath fails", `{"user": {"role": "member", "age": 12, "email": "a@b.c"}}`, 403},
+		{"Invalid JSON", `not json`, 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/users", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestProcessResponse(t *testing.T) {
 	handler, _ := createTestHandler()
 
@@ -530,3 +719,81 @@ func BenchmarkFindMatchingRoute(b *testing.B) {
 		handler.findMatchingRoute(req)
 	}
 }
+
+func TestRouteSources(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.yaml": "routes:\n  - path: /a\n    method: GET\n    status_code: 200\n",
+		"b.yaml": "routes:\n  - path: /b\n    method: GET\n    status_code: 200\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	configManager := config.NewManager(dir)
+	if err := configManager.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	handler := NewMockHandler(configManager, logger.New(logger.LogLevelError))
+
+	send := func(method, path, body string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w.Code
+	}
+
+	if code := send("POST", "/_mock/routes", `{"path": "/c", "method": "GET", "status_code": 200, "source": "b.yaml"}`); code != 201 {
+		t.Fatalf("Expected 201 adding a route to b.yaml, got %d", code)
+	}
+	if code := send("POST", "/_mock/routes", `{"path": "/d", "method": "GET", "status_code": 200, "source": "other.yaml"}`); code != 400 {
+		t.Errorf("Expected 400 for an unknown source, got %d", code)
+	}
+	if code := send("PUT", "/_mock/routes/b", `{"path": "/b", "method": "GET", "status_code": 204}`); code != 200 {
+		t.Fatalf("Expected 200 updating /b, got %d", code)
+	}
+
+	want := map[string]string{"/a": "a.yaml", "/b": "b.yaml", "/c": "b.yaml"}
+	for _, route := range configManager.GetRoutes() {
+		if route.Source != filepath.Join(dir, want[route.Path]) {
+			t.Errorf("Expected %s to come from %s, got %s", route.Path, want[route.Path], route.Source)
+		}
+	}
+}
+
+func TestBinaryResponses(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/firmware", Method: "GET", StatusCode: 200,
+		BodyBase64: "AAEC\n/f7/", // line breaks are ignored
+	})
+	configManager.AddRoute(config.Route{
+		Path: "/proto", Method: "POST", StatusCode: 200, ContentType: "application/x-protobuf",
+		Response: []byte{0x08, 0x96, 0x01},
+	})
+	configManager.AddRoute(config.Route{
+		Path: "/gzip", Method: "GET", StatusCode: 200, ResponseMode: config.ResponseModeCycle,
+		Headers:   map[string]string{"Content-Encoding": "gzip"},
+		Responses: []config.ResponseVariant{{BodyBase64: "H4sIAAAAAAAA/w=="}, {Response: "plain"}},
+	})
+
+	w := doRequest(handler, "GET", "/firmware", "")
+	if !bytes.Equal(w.Body.Bytes(), []byte{0x00, 0x01, 0x02, 0xfd, 0xfe, 0xff}) {
+		t.Errorf("Expected the decoded bytes, got %v", w.Body.Bytes())
+	}
+	if w.Header().Get("Content-Type") != "application/octet-stream" || w.Header().Get("Content-Length") != "6" {
+		t.Errorf("Expected application/octet-stream with length 6, got %s %s", w.Header().Get("Content-Type"), w.Header().Get("Content-Length"))
+	}
+
+	w = doRequest(handler, "POST", "/proto", "")
+	if !bytes.Equal(w.Body.Bytes(), []byte{0x08, 0x96, 0x01}) || w.Header().Get("Content-Length") != "3" {
+		t.Errorf("Expected raw bytes to be written unchanged, got %v (length %s)", w.Body.Bytes(), w.Header().Get("Content-Length"))
+	}
+
+	w = doRequest(handler, "GET", "/gzip", "")
+	if w.Body.Len() != 10 || w.Body.Bytes()[0] != 0x1f {
+		t.Errorf("Expected the gzip header bytes, got %v", w.Body.Bytes())
+	}
+	if w = doRequest(handler, "GET", "/gzip", ""); w.Body.String() != "\"plain\"\n" {
+		t.Errorf("Expected the second entry to replace the binary body, got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+	"github.com/walterfan/lazy-mock-server/internal/importers"
+	"github.com/walterfan/lazy-mock-server/internal/openapi"
+)
+
+// maxImportSize limits the size of documents posted to the import endpoints
+const maxImportSize = 32 << 20
+
+// handleImportOpenAPI generates routes from the OpenAPI document in the
+// request body. Routes are appended unless ?replace=true is given.
+func (h *MockHandler) handleImportOpenAPI(w http.ResponseWriter, r *http.Request) {
+	h.handleImport(w, r, "OpenAPI document", func(data []byte) ([]config.Route, error) {
+		doc, err := openapi.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		return doc.Routes()
+	})
+}
+
+// handleImportPostman generates routes from the Postman collection in the
+// request body
+func (h *MockHandler) handleImportPostman(w http.ResponseWriter, r *http.Request) {
+	h.handleImport(w, r, "Postman collection", importers.FromPostman)
+}
+
+// handleImportHAR generates routes from the HAR file in the request body
+func (h *MockHandler) handleImportHAR(w http.ResponseWriter, r *http.Request) {
+	h.handleImport(w, r, "HAR file", importers.FromHAR)
+}
+
+// handleImport converts the document in the request body to routes and
+// imports them
+func (h *MockHandler) handleImport(w http.ResponseWriter, r *http.Request, source string, convert func([]byte) ([]config.Route, error)) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxImportSize))
+	if err != nil {
+		h.logger.LogErrorWithRequest(err, r, "reading "+source)
+		h.writeBadRequest(w, "Failed to read request body")
+		return
+	}
+
+	routes, err := convert(data)
+	if err != nil {
+		h.logger.LogErrorWithRequest(err, r, "generating routes from "+source)
+		h.writeBadRequest(w, err.Error())
+		return
+	}
+
+	h.importRoutes(w, r, source, routes)
+}
+
+// importRoutes validates imported routes and adds them to the configuration
+func (h *MockHandler) importRoutes(w http.ResponseWriter, r *http.Request, source string, routes []config.Route) {
+	for _, route := range routes {
+		if err := h.configManager.ValidateRoute(route); err != nil {
+			h.logger.LogErrorWithRequest(err, r, "validating imported route")
+			h.writeBadRequest(w, route.Method+" "+route.Path+": "+err.Error())
+			return
+		}
+	}
+
+	replace := r.URL.Query().Get("replace") == "true"
+
+	h.mutex.Lock()
+	if cfg := h.configManager.GetConfig(); replace && cfg != nil {
+		cfg.Routes = nil
+	}
+	for _, route := range routes {
+		h.configManager.AddRoute(route)
+	}
+	h.mutex.Unlock()
+
+	h.logger.LogInfo("Imported %d routes from %s", len(routes), source)
+
+	imported := make([]string, len(routes))
+	for i, route := range routes {
+		imported[i] = route.Method + " " + route.Path
+	}
+	w.WriteHeader(http.StatusCreated)
+	response := map[string]interface{}{
+		"message": "Routes imported successfully",
+		"routes":  imported,
+		"count":   len(routes),
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.LogError(err, "encoding import response")
+	}
+}
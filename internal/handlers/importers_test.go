@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const usersSpec = `{
+  "openapi": "3.0.0",
+  "info": {"title": "Users", "version": "1"},
+  "paths": {
+    "/users/{id}": {
+      "get": {
+        "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}],
+        "responses": {
+          "200": {
+            "description": "A user",
+            "content": {"application/json": {"schema": {
+              "type": "object",
+              "properties": {"id": {"type": "integer", "example": 7}, "email": {"type": "string", "format": "email"}}
+            }}}
+          },
+          "404": {
+            "description": "Not found",
+            "content": {"application/json": {"example": {"error": "no such user"}}}
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestImportOpenAPI(t *testing.T) {
+	handler, configManager := createTestHandler()
+	before := configManager.GetRouteCount()
+
+	w := doRequest(handler, "POST", "/_mock/import/openapi", usersSpec)
+	if w.Code != 201 {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Routes []string `json:"routes"`
+		Count  int      `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse import response: %v", err)
+	}
+	if response.Count != 2 || configManager.GetRouteCount() != before+2 {
+		t.Fatalf("Expected 2 imported routes, got %+v", response)
+	}
+
+	w = doRequest(handler, "GET", "/users/42", "")
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `"email":"user@example.com"`) {
+		t.Errorf("Expected the synthesized user, got %d %s", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	req.Header.Set("Prefer", "code=404")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != 404 || !strings.Contains(w.Body.String(), "no such user") {
+		t.Errorf("Expected the documented 404 via Prefer, got %d %s", w.Code, w.Body.String())
+	}
+
+	if w := doRequest(handler, "GET", "/users/me", ""); w.Code != 404 || !strings.Contains(w.Body.String(), "Route not found") {
+		t.Errorf("Expected non-integer ids not to match, got %d", w.Code)
+	}
+}
+
+func TestImportOpenAPIReplace(t *testing.T) {
+	handler, configManager := createTestHandler()
+
+	if w := doRequest(handler, "POST", "/_mock/import/openapi?replace=true", usersSpec); w.Code != 201 {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+	if count := configManager.GetRouteCount(); count != 2 {
+		t.Errorf("Expected only the imported routes, got %d", count)
+	}
+
+	if w := doRequest(handler, "POST", "/_mock/import/openapi", "swagger: '2.0'"); w.Code != 400 {
+		t.Errorf("Expected status 400 for a Swagger 2 document, got %d", w.Code)
+	}
+}
+
+func TestImportOpenAPIProblemJSON(t *testing.T) {
+	handler, _ := createTestHandler()
+	spec := `{
+  "openapi": "3.0.0",
+  "info": {"title": "Orders", "version": "1"},
+  "paths": {"/orders": {"get": {"responses": {"503": {
+    "description": "Unavailable",
+    "content": {"application/problem+json": {"example": {"title": "down for maintenance"}}}
+  }}}}}
+}`
+
+	if w := doRequest(handler, "POST", "/_mock/import/openapi", spec); w.Code != 201 {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	w := doRequest(handler, "GET", "/orders", "")
+	if w.Code != 503 || strings.TrimSpace(w.Body.String()) != `{"title":"down for maintenance"}` {
+		t.Errorf("Expected the example as JSON, got %d %q", w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/problem+json" {
+		t.Errorf("Expected the documented content type, got %s", contentType)
+	}
+}
+
+func TestImportHAR(t *testing.T) {
+	handler, _ := createTestHandler()
+	har := `{"log": {"entries": [
+	  {"request": {"method": "GET", "url": "https://app.example.com/api/status"},
+	   "response": {"status": 503, "headers": [], "content": {"mimeType": "text/plain", "text": "starting"}}},
+	  {"request": {"method": "GET", "url": "https://app.example.com/api/status"},
+	   "response": {"status": 200, "headers": [], "content": {"mimeType": "text/plain", "text": "ready"}}}
+	]}}`
+
+	if w := doRequest(handler, "POST", "/_mock/import/har", har); w.Code != 201 {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Replaying the capture answers the responses in the captured order
+	for _, want := range []string{"starting", "ready", "ready"} {
+		if w := doRequest(handler, "GET", "/api/status", ""); w.Body.String() != want {
+			t.Errorf("Expected %q, got %d %q", want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestImportHARJSONMediaTypes(t *testing.T) {
+	handler, _ := createTestHandler()
+	har := `{"log": {"entries": [
+	  {"request": {"method": "GET", "url": "https://app.example.com/api/item"},
+	   "response": {"status": 200, "headers": [{"name": "Content-Type", "value": "application/json; charset=utf-8"}], "content": {"text": "{\"id\": 1}"}}},
+	  {"request": {"method": "GET", "url": "https://app.example.com/api/broken"},
+	   "response": {"status": 500, "headers": [], "content": {"mimeType": "application/problem+json", "text": "{\"title\": \"oops\"}"}}}
+	]}}`
+
+	if w := doRequest(handler, "POST", "/_mock/import/har", har); w.Code != 201 {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	for path, want := range map[string]string{"/api/item": `{"id":1}`, "/api/broken": `{"title":"oops"}`} {
+		if w := doRequest(handler, "GET", path, ""); strings.TrimSpace(w.Body.String()) != want {
+			t.Errorf("Expected %s for %s, got %d %q", want, path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestImportPostman(t *testing.T) {
+	handler, _ := createTestHandler()
+	postman := `{"info": {"name": "Ping"}, "item": [{"name": "Ping", "request": {"method": "GET", "url": "{{host}}/ping"},
+	  "response": [{"name": "Pong", "code": 200, "_postman_previewlanguage": "text", "body": "pong"}]}]}`
+
+	if w := doRequest(handler, "POST", "/_mock/import/postman", postman); w.Code != 201 {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doRequest(handler, "GET", "/ping", ""); w.Body.String() != "pong" {
+		t.Errorf("Expected pong, got %d %q", w.Code, w.Body.String())
+	}
+
+	if w := doRequest(handler, "POST", "/_mock/import/postman", `{"info": {}}`); w.Code != 400 {
+		t.Errorf("Expected status 400 for a collection without items, got %d", w.Code)
+	}
+}
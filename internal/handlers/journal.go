@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// DefaultJournalSize is the number of requests the journal keeps by default
+const DefaultJournalSize = 1000
+
+// MaxJournalBodySize is the number of request body bytes a journal entry
+// keeps; longer bodies are cut and flagged as truncated
+const MaxJournalBodySize = 64 << 10
+
+// JournalEntry is a request served by the mock together with its outcome.
+// Bodies longer than MaxJournalBodySize are cut and flagged as truncated.
+type JournalEntry struct {
+	ID            int64               `json:"id"`
+	Timestamp     time.Time           `json:"timestamp"`
+	Method        string              `json:"method"`
+	URL           string              `json:"url"`
+	Path          string              `json:"path"`
+	Query         string              `json:"query,omitempty"`
+	Headers       map[string][]string `json:"headers,omitempty"`
+	Body          string              `json:"body,omitempty"`
+	BodyTruncated bool                `json:"body_truncated,omitempty"`
+	RemoteAddr    string              `json:"remote_addr"`
+	Route         string              `json:"route,omitempty"`
+	StatusCode    int                 `json:"status_code"`
+	DurationMs    float64             `json:"duration_ms"`
+}
+
+// journal keeps the most recent requests in a ring buffer
+type journal struct {
+	mutex   sync.Mutex
+	entries []JournalEntry
+	next    int
+	full    bool
+	lastID  int64
+}
+
+// newJournal creates a journal holding up to size requests
+func newJournal(size int) *journal {
+	return &journal{entries: make([]JournalEntry, size)}
+}
+
+// add stores an entry, overwriting the oldest one when the journal is full
+func (j *journal) add(entry JournalEntry) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if len(j.entries) == 0 {
+		return
+	}
+
+	j.lastID++
+	entry.ID = j.lastID
+	j.entries[j.next] = entry
+	j.next = (j.next + 1) % len(j.entries)
+	if j.next == 0 {
+		j.full = true
+	}
+}
+
+// list returns the stored entries, oldest first
+func (j *journal) list() []JournalEntry {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if !j.full {
+		return append([]JournalEntry(nil), j.entries[:j.next]...)
+	}
+	entries := make([]JournalEntry, 0, len(j.entries))
+	entries = append(entries, j.entries[j.next:]...)
+	return append(entries, j.entries[:j.next]...)
+}
+
+// reset removes every entry
+func (j *journal) reset() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	clear(j.entries)
+	j.next = 0
+	j.full = false
+}
+
+// SetJournalSize replaces the journal with an empty one holding up to size
+// requests; a size of 0 or less disables the journal
+func (h *MockHandler) SetJournalSize(size int) {
+	if size < 0 {
+		size = 0
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.journal = newJournal(size)
+}
+
+// getJournal returns the current journal
+func (h *MockHandler) getJournal() *journal {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.journal
+}
+
+// journalWriter captures the status code written by the handlers
+type journalWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+// WriteHeader captures the status code
+func (w *journalWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records an implicit 200 status
+func (w *journalWriter) Write(data []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush sends buffered data to the client if the wrapped writer supports it
+func (w *journalWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (w *journalWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// serveJournaled serves the request with next and records it in the journal.
+// Hijacked connections, e.g. by faults, are recorded with status 0.
+func (h *MockHandler) serveJournaled(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	j := h.getJournal()
+	if j == nil || len(j.entries) == 0 {
+		next(w, r)
+		return
+	}
+
+	body := h.readBody(r)
+	truncated := len(body) > MaxJournalBodySize
+	if truncated {
+		body = body[:MaxJournalBodySize]
+	}
+
+	start := time.Now()
+	entry := &JournalEntry{
+		Timestamp:     start,
+		Method:        r.Method,
+		URL:           r.URL.String(),
+		Path:          r.URL.Path,
+		Query:         r.URL.RawQuery,
+		Headers:       r.Header.Clone(),
+		Body:          string(body),
+		BodyTruncated: truncated,
+		RemoteAddr:    r.RemoteAddr,
+	}
+	writer := &journalWriter{ResponseWriter: w}
+
+	next(writer, r.WithContext(context.WithValue(r.Context(), journalEntryKey, entry)))
+
+	entry.StatusCode = writer.statusCode
+	entry.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	j.add(*entry)
+}
+
+// setJournalRoute records which route answered the request
+func setJournalRoute(r *http.Request, key string) {
+	if entry, ok := r.Context().Value(journalEntryKey).(*JournalEntry); ok {
+		entry.Route = key
+	}
+}
+
+// handleRequestsAPI handles the /_mock/requests endpoints
+func (h *MockHandler) handleRequestsAPI(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		h.handleGetRequests(w, r)
+	case "DELETE":
+		if j := h.getJournal(); j != nil {
+			j.reset()
+		}
+		h.logger.LogInfo("Cleared request journal")
+		if err := json.NewEncoder(w).Encode(map[string]string{"message": "Request journal cleared successfully"}); err != nil {
+			h.logger.LogError(err, "encoding clear requests response")
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleGetRequests returns the journal, filtered by the path, method, since,
+// until and limit query parameters
+func (h *MockHandler) handleGetRequests(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	since, err := parseJournalTime(query.Get("since"))
+	if err != nil {
+		h.writeBadRequest(w, err.Error())
+		return
+	}
+	until, err := parseJournalTime(query.Get("until"))
+	if err != nil {
+		h.writeBadRequest(w, err.Error())
+		return
+	}
+	path, method := query.Get("path"), query.Get("method")
+	if path != "" {
+		check := config.Route{Path: path, Method: "GET", StatusCode: http.StatusOK}
+		if err := h.configManager.ValidateRoute(check); err != nil {
+			h.writeBadRequest(w, err.Error())
+			return
+		}
+	}
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			h.writeBadRequest(w, "limit must be a non-negative integer")
+			return
+		}
+	}
+
+	var entries []JournalEntry
+	if j := h.getJournal(); j != nil {
+		entries = j.list()
+	}
+
+	requests := make([]JournalEntry, 0, len(entries))
+	for _, entry := range entries {
+		if method != "" && !strings.EqualFold(method, entry.Method) {
+			continue
+		}
+		if path != "" {
+			if _, ok := h.matchPath(path, entry.Path); !ok {
+				continue
+			}
+		}
+		if !since.IsZero() && entry.Timestamp.Before(since) {
+			continue
+		}
+		if !until.IsZero() && entry.Timestamp.After(until) {
+			continue
+		}
+		requests = append(requests, entry)
+	}
+	if limit > 0 && len(requests) > limit {
+		requests = requests[len(requests)-limit:]
+	}
+
+	response := map[string]interface{}{
+		"requests": requests,
+		"count":    len(requests),
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.LogErrorWithRequest(err, r, "encoding requests response")
+	}
+}
+
+// parseJournalTime parses an RFC 3339 timestamp or Unix milliseconds
+func parseJournalTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected RFC 3339 or Unix milliseconds", value)
+	}
+	return t, nil
+}
+
+// VerifyRequest describes the requests expected in the journal. Requests
+// are selected with the same rules as routes; without a count at least one
+// request is expected.
+type VerifyRequest struct {
+	Method     string               `json:"method,omitempty"`
+	Path       string               `json:"path,omitempty"`
+	Parameters map[string]string    `json:"parameters,omitempty"`
+	Match      *config.RequestMatch `json:"match,omitempty"`
+	Count      *int                 `json:"count,omitempty"`
+	AtLeast    *int                 `json:"at_least,omitempty"`
+	AtMost     *int                 `json:"at_most,omitempty"`
+}
+
+// handleVerify checks how often matching requests were received. It answers
+// 200 when the expectation holds and 417 otherwise.
+func (h *MockHandler) handleVerify(w http.ResponseWriter, r *http.Request) {
+	var request VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.writeBadRequest(w, "Invalid JSON in verification request")
+		return
+	}
+
+	route := config.Route{Path: request.Path, Method: request.Method, Parameters: request.Parameters, Match: request.Match}
+	check := config.Route{Path: route.Path, Method: "GET", StatusCode: http.StatusOK, Match: route.Match}
+	if check.Path == "" {
+		check.Path = "*"
+	}
+	if err := h.configManager.ValidateRoute(check); err != nil {
+		h.writeBadRequest(w, err.Error())
+		return
+	}
+
+	var entries []JournalEntry
+	if j := h.getJournal(); j != nil {
+		entries = j.list()
+	}
+
+	matched := make([]JournalEntry, 0)
+	for _, entry := range entries {
+		if h.verifyMatches(&route, entry) {
+			matched = append(matched, entry)
+		}
+	}
+
+	count := len(matched)
+	verified := count > 0
+	if request.Count != nil || request.AtLeast != nil || request.AtMost != nil {
+		verified = (request.Count == nil || count == *request.Count) &&
+			(request.AtLeast == nil || count >= *request.AtLeast) &&
+			(request.AtMost == nil || count <= *request.AtMost)
+	}
+
+	response := map[string]interface{}{
+		"verified": verified,
+		"count":    count,
+		"requests": matched,
+	}
+	if !verified {
+		w.WriteHeader(http.StatusExpectationFailed)
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.LogErrorWithRequest(err, r, "encoding verify response")
+	}
+}
+
+// verifyMatches checks a journal entry against the verification criteria
+func (h *MockHandler) verifyMatches(route *config.Route, entry JournalEntry) bool {
+	if route.Method != "" && !strings.EqualFold(route.Method, entry.Method) {
+		return false
+	}
+	if route.Path != "" {
+		if _, ok := h.matchPath(route.Path, entry.Path); !ok {
+			return false
+		}
+	}
+
+	r, err := http.NewRequest(entry.Method, entry.URL, bytes.NewReader([]byte(entry.Body)))
+	if err != nil {
+		return false
+	}
+	r.Header = http.Header(entry.Headers).Clone()
+
+	if route.Parameters != nil && !h.matchesParameters(route.Parameters, r) {
+		return false
+	}
+	if route.Match != nil && route.Match.Headers != nil && !h.matchesHeaders(route.Match.Headers, r) {
+		return false
+	}
+	if route.Match != nil && route.Match.SOAPAction != "" && !matchesSOAPAction(route.Match.SOAPAction, r) {
+		return false
+	}
+	if route.Match != nil && route.Match.Body != nil && !h.matchesBody(route.Match.Body, r) {
+		return false
+	}
+	return true
+}
+
+// writeBadRequest answers 400 with a JSON error message
+func (h *MockHandler) writeBadRequest(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusBadRequest)
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": message}); err != nil {
+		h.logger.LogError(err, "encoding error response")
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+type journalResponse struct {
+	Requests []JournalEntry `json:"requests"`
+	Count    int            `json:"count"`
+}
+
+func getJournalEntries(t *testing.T, handler *MockHandler, query string) journalResponse {
+	w := doRequest(handler, "GET", "/_mock/requests"+query, "")
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200 for journal query, got %d: %s", w.Code, w.Body.String())
+	}
+	var response journalResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse journal: %v", err)
+	}
+	return response
+}
+
+func TestJournalRecordsRequests(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{Path: "/test/post", Method: "POST", StatusCode: 201, Response: "created"})
+
+	req := httptest.NewRequest("POST", "/test/post?x=1", strings.NewReader(`{"name": "Alice"}`))
+	req.Header.Set("X-Trace", "abc")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	doRequest(handler, "GET", "/missing", "")
+	doRequest(handler, "GET", "/_mock/routes", "")
+
+	response := getJournalEntries(t, handler, "")
+	if response.Count != 2 {
+		t.Fatalf("Expected 2 journaled requests without management calls, got %d", response.Count)
+	}
+
+	entry := response.Requests[0]
+	if entry.Method != "POST" || entry.Path != "/test/post" || entry.Query != "x=1" {
+		t.Errorf("Unexpected request line: %+v", entry)
+	}
+	if entry.Body != `{"name": "Alice"}` || entry.Headers["X-Trace"][0] != "abc" {
+		t.Errorf("Expected body and headers to be journaled, got %+v", entry)
+	}
+	if entry.Route != "POST /test/post" || entry.StatusCode != 201 {
+		t.Errorf("Expected matched route and status, got %s %d", entry.Route, entry.StatusCode)
+	}
+
+	if missing := response.Requests[1]; missing.Route != "" || missing.StatusCode != 404 {
+		t.Errorf("Expected unmatched 404 entry, got %+v", missing)
+	}
+}
+
+func TestJournalTruncatesBodies(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{Path: "/upload", Method: "POST", StatusCode: 200, ContentType: "text/plain", Response: "{{len .Body}}"})
+
+	body := strings.Repeat("x", MaxJournalBodySize+10)
+	if w := doRequest(handler, "POST", "/upload", body); w.Body.String() != strconv.Itoa(len(body)) {
+		t.Errorf("Expected the route to see the whole body, got %s", w.Body.String())
+	}
+	doRequest(handler, "POST", "/upload", "small")
+
+	response := getJournalEntries(t, handler, "")
+	if entry := response.Requests[0]; len(entry.Body) != MaxJournalBodySize || !entry.BodyTruncated {
+		t.Errorf("Expected a truncated body of %d bytes, got %d (truncated=%v)", MaxJournalBodySize, len(entry.Body), entry.BodyTruncated)
+	}
+	if entry := response.Requests[1]; entry.Body != "small" || entry.BodyTruncated {
+		t.Errorf("Expected short bodies to be kept whole, got %+v", entry)
+	}
+}
+
+func TestJournalFilters(t *testing.T) {
+	handler, _ := createTestHandler()
+	doRequest(handler, "GET", "/test/simple", "")
+	doRequest(handler, "GET", "/test/json", "")
+	doRequest(handler, "POST", "/test/post", "{}")
+
+	if response := getJournalEntries(t, handler, "?method=get"); response.Count != 2 {
+		t.Errorf("Expected 2 GET requests, got %d", response.Count)
+	}
+	if response := getJournalEntries(t, handler, "?path=/test/*&method=POST"); response.Count != 1 {
+		t.Errorf("Expected 1 POST request under /test, got %d", response.Count)
+	}
+	if response := getJournalEntries(t, handler, "?limit=1"); response.Count != 1 || response.Requests[0].Path != "/test/post" {
+		t.Errorf("Expected only the latest request, got %+v", response.Requests)
+	}
+
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	if response := getJournalEntries(t, handler, "?since="+future); response.Count != 0 {
+		t.Errorf("Expected no requests after %s, got %d", future, response.Count)
+	}
+	if w := doRequest(handler, "GET", "/_mock/requests?since=yesterday", ""); w.Code != 400 {
+		t.Errorf("Expected status 400 for invalid time, got %d", w.Code)
+	}
+	if w := doRequest(handler, "GET", "/_mock/requests?path="+url.QueryEscape("/users/{id:(}"), ""); w.Code != 400 {
+		t.Errorf("Expected status 400 for invalid path pattern, got %d", w.Code)
+	}
+
+	if w := doRequest(handler, "DELETE", "/_mock/requests", ""); w.Code != 200 {
+		t.Errorf("Expected status 200 for clearing the journal, got %d", w.Code)
+	}
+	if response := getJournalEntries(t, handler, ""); response.Count != 0 {
+		t.Errorf("Expected empty journal after clearing, got %d", response.Count)
+	}
+}
+
+func TestJournalRingBuffer(t *testing.T) {
+	handler, _ := createTestHandler()
+	handler.SetJournalSize(2)
+
+	for _, path := range []string{"/a", "/b", "/c"} {
+		doRequest(handler, "GET", path, "")
+	}
+
+	response := getJournalEntries(t, handler, "")
+	if response.Count != 2 || response.Requests[0].Path != "/b" || response.Requests[1].Path != "/c" {
+		t.Errorf("Expected the two most recent requests, got %+v", response.Requests)
+	}
+	if response.Requests[1].ID != 3 {
+		t.Errorf("Expected IDs to keep increasing, got %d", response.Requests[1].ID)
+	}
+
+	handler.SetJournalSize(0)
+	doRequest(handler, "GET", "/d", "")
+	if response := getJournalEntries(t, handler, ""); response.Count != 0 {
+		t.Errorf("Expected disabled journal to stay empty, got %d", response.Count)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	handler, _ := createTestHandler()
+	doRequest(handler, "POST", "/api/users", `{"name": "Alice", "role": "admin"}`)
+	doRequest(handler, "POST", "/api/users", `{"name": "Bob", "role": "admin"}`)
+	doRequest(handler, "POST", "/api/users", `{"name": "Carol", "role": "user"}`)
+	doRequest(handler, "GET", "/api/users", "")
+
+	tests := []struct {
+		name     string
+		request  string
+		expected int
+		count    int
+	}{
+		{"exact count", `{"method": "POST", "path": "/api/users", "match": {"body": {"partial_json": {"role": "admin"}}}, "count": 2}`, 200, 2},
+		{"wrong count", `{"method": "POST", "path": "/api/users", "count": 2}`, 417, 3},
+		{"at least", `{"path": "/api/users", "at_least": 4}`, 200, 4},
+		{"at most", `{"method": "POST", "at_most": 1, "match": {"body": {"json_path": ["$.name =~ /^[AB]/"]}}}`, 417, 2},
+		{"default expects a call", `{"method": "DELETE", "path": "/api/users"}`, 417, 0},
+		{"never called", `{"method": "DELETE", "path": "/api/users", "count": 0}`, 200, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(handler, "POST", "/_mock/verify", tt.request)
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+			var response struct {
+				Verified bool `json:"verified"`
+				Count    int  `json:"count"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse verify response: %v", err)
+			}
+			if response.Count != tt.count || response.Verified != (tt.expected == 200) {
+				t.Errorf("Expected count %d, got %+v", tt.count, response)
+			}
+		})
+	}
+
+	if w := doRequest(handler, "POST", "/_mock/verify", `{"match": {"headers": {"X-Id": {}}}}`); w.Code != 400 {
+		t.Errorf("Expected status 400 for invalid criteria, got %d", w.Code)
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// SetProxy sets the upstream for unmatched requests, overriding the proxy
+// block of the configuration file. Pass nil to fall back to the file.
+func (h *MockHandler) SetProxy(proxy *config.ProxyConfig) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.proxy = proxy
+}
+
+// fallbackProxy returns the upstream for unmatched requests, if any
+func (h *MockHandler) fallbackProxy() *config.ProxyConfig {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	if h.proxy != nil {
+		return h.proxy
+	}
+	if cfg := h.configManager.GetConfig(); cfg != nil {
+		return cfg.Proxy
+	}
+	return nil
+}
+
+// proxyRequest forwards the request to the upstream and copies its response
+func (h *MockHandler) proxyRequest(w http.ResponseWriter, r *http.Request, proxy *config.ProxyConfig) {
+	target, err := url.Parse(proxy.Target)
+	if err != nil {
+		h.logger.LogErrorWithRequest(err, r, "parsing proxy target")
+		h.writeProxyError(w, r, err)
+		return
+	}
+
+	reverseProxy := h.newReverseProxy(target, proxy)
+	h.logger.LogDebug("Proxying %s %s to %s", r.Method, r.URL.Path, proxy.Target)
+	reverseProxy.ServeHTTP(w, r)
+}
+
+// newReverseProxy creates a reverse proxy that forwards to target and
+// rewrites headers as configured
+func (h *MockHandler) newReverseProxy(target *url.URL, proxy *config.ProxyConfig) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			if proxy.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+			for key, value := range proxy.Headers {
+				if value == "" {
+					pr.Out.Header.Del(key)
+				} else {
+					pr.Out.Header.Set(key, value)
+				}
+			}
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			h.logger.LogErrorWithRequest(err, r, "proxying request to "+proxy.Target)
+			h.writeProxyError(w, r, err)
+		},
+	}
+}
+
+// writeProxyError answers with 502 when the upstream cannot be reached
+func (h *MockHandler) writeProxyError(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadGateway)
+	response := map[string]string{
+		"error":  "Bad Gateway",
+		"detail": err.Error(),
+		"path":   r.URL.Path,
+		"method": r.Method,
+	}
+	if encErr := json.NewEncoder(w).Encode(response); encErr != nil {
+		h.logger.LogError(encErr, "encoding proxy error response")
+	}
+}
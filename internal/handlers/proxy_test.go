@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+func newUpstream(t *testing.T) *httptest.Server {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Upstream", "yes")
+		w.WriteHeader(http.StatusTeapot)
+		response := map[string]string{
+			"path":      r.URL.Path,
+			"query":     r.URL.RawQuery,
+			"host":      r.Host,
+			"auth":      r.Header.Get("Authorization"),
+			"api_key":   r.Header.Get("X-Api-Key"),
+			"forwarded": r.Header.Get("X-Forwarded-Host"),
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Errorf("Failed to encode upstream response: %v", err)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func decodeUpstream(t *testing.T, w *httptest.ResponseRecorder) map[string]string {
+	var response map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse proxied response: %v", err)
+	}
+	return response
+}
+
+func TestProxyUnmatchedRequests(t *testing.T) {
+	upstream := newUpstream(t)
+	handler, _ := createTestHandler()
+	handler.SetProxy(&config.ProxyConfig{
+		Target:  upstream.URL + "/base",
+		Headers: map[string]string{"Authorization": "", "X-Api-Key": "secret"},
+	})
+
+	req := httptest.NewRequest("GET", "/missing?x=1", nil)
+	req.Host = "mock.local"
+	req.Header.Set("Authorization", "Bearer client")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusTeapot {
+		t.Fatalf("Expected upstream status 418, got %d", w.Code)
+	}
+	if w.Header().Get("X-Upstream") != "yes" {
+		t.Error("Expected upstream headers to be copied")
+	}
+
+	response := decodeUpstream(t, w)
+	if response["path"] != "/base/missing" || response["query"] != "x=1" {
+		t.Errorf("Expected /base/missing?x=1 upstream, got %s?%s", response["path"], response["query"])
+	}
+	if response["auth"] != "" {
+		t.Errorf("Expected Authorization to be removed, got %s", response["auth"])
+	}
+	if response["api_key"] != "secret" {
+		t.Errorf("Expected X-Api-Key to be set, got %s", response["api_key"])
+	}
+	if response["forwarded"] != "mock.local" {
+		t.Errorf("Expected X-Forwarded-Host mock.local, got %s", response["forwarded"])
+	}
+	if response["host"] == "mock.local" {
+		t.Error("Expected the upstream host without preserve_host")
+	}
+
+	// Matched routes are still answered by the mock
+	if w := doRequest(handler, "GET", "/test/simple", ""); w.Body.String() != "Hello World" {
+		t.Errorf("Expected mocked response, got '%s'", w.Body.String())
+	}
+}
+
+func TestProxyPreserveHost(t *testing.T) {
+	upstream := newUpstream(t)
+	handler, _ := createTestHandler()
+	handler.SetProxy(&config.ProxyConfig{Target: upstream.URL, PreserveHost: true})
+
+	req := httptest.NewRequest("GET", "/missing", nil)
+	req.Host = "mock.local"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if response := decodeUpstream(t, w); response["host"] != "mock.local" {
+		t.Errorf("Expected Host mock.local, got %s", response["host"])
+	}
+}
+
+func TestProxyFromConfig(t *testing.T) {
+	upstream := newUpstream(t)
+	handler, configManager := createTestHandler()
+	configManager.GetConfig().Proxy = &config.ProxyConfig{Target: upstream.URL}
+
+	if w := doRequest(handler, "GET", "/missing", ""); w.Code != http.StatusTeapot {
+		t.Errorf("Expected upstream status 418, got %d", w.Code)
+	}
+}
+
+func TestProxyRoute(t *testing.T) {
+	upstream := newUpstream(t)
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path:         "/live/*",
+		Method:       "GET",
+		Proxy:        upstream.URL,
+		ProxyHeaders: map[string]string{"X-Api-Key": "route-key"},
+	})
+
+	w := doRequest(handler, "GET", "/live/users", "")
+	if w.Code != http.StatusTeapot {
+		t.Fatalf("Expected upstream status 418, got %d", w.Code)
+	}
+	if response := decodeUpstream(t, w); response["path"] != "/live/users" || response["api_key"] != "route-key" {
+		t.Errorf("Expected /live/users with route header, got %+v", response)
+	}
+
+	// Without a global proxy unmatched requests still return 404
+	if w := doRequest(handler, "GET", "/missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestProxyUnreachableUpstream(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	target := upstream.URL
+	upstream.Close()
+
+	handler, _ := createTestHandler()
+	handler.SetProxy(&config.ProxyConfig{Target: target})
+
+	if w := doRequest(handler, "GET", "/missing", ""); w.Code != http.StatusBadGateway {
+		t.Errorf("Expected status 502, got %d", w.Code)
+	}
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// RecordOptions configures record mode
+type RecordOptions struct {
+	// Path is the YAML file recorded routes are written to. Routes already
+	// in the file are kept and are not recorded again.
+	Path string
+	// Headers lists the response headers to keep; when empty every header
+	// except hop-by-hop and per-response ones such as Date is kept
+	Headers []string
+}
+
+// recorder turns proxied request/response pairs into routes
+type recorder struct {
+	mutex   sync.Mutex
+	manager *config.Manager
+	headers map[string]bool
+	seen    map[string]bool
+}
+
+// StartRecording switches the handler to record mode: every request outside
+// the management API is forwarded to the proxy target and each unique
+// request/response pair is saved as a route
+func (h *MockHandler) StartRecording(opts RecordOptions) error {
+	manager := config.NewManager(opts.Path)
+	if _, err := os.Stat(opts.Path); err == nil {
+		if err := manager.Load(); err != nil {
+			return err
+		}
+	} else if os.IsNotExist(err) {
+		manager.SetConfig(&config.Config{})
+	} else {
+		return fmt.Errorf("failed to access record file %s: %w", opts.Path, err)
+	}
+
+	rec := &recorder{
+		manager: manager,
+		seen:    make(map[string]bool),
+	}
+	if len(opts.Headers) > 0 {
+		rec.headers = make(map[string]bool)
+		for _, name := range opts.Headers {
+			rec.headers[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+	for _, route := range manager.GetRoutes() {
+		rec.seen[rec.routeKey(h, route)] = true
+	}
+
+	h.mutex.Lock()
+	h.recorder = rec
+	h.mutex.Unlock()
+
+	h.logger.LogInfo("Recording to %s (%d existing routes)", opts.Path, len(rec.seen))
+	return nil
+}
+
+// recording returns the active recorder, if any
+func (h *MockHandler) recording() *recorder {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.recorder
+}
+
+// handleRecord forwards the request to the upstream and records the exchange
+func (h *MockHandler) handleRecord(w http.ResponseWriter, r *http.Request, rec *recorder) {
+	proxy := h.fallbackProxy()
+	if proxy == nil || proxy.Target == "" {
+		h.writeProxyError(w, r, fmt.Errorf("record mode needs a proxy target"))
+		return
+	}
+	target, err := url.Parse(proxy.Target)
+	if err != nil {
+		h.logger.LogErrorWithRequest(err, r, "parsing proxy target")
+		h.writeProxyError(w, r, err)
+		return
+	}
+
+	requestBody := h.readBody(r)
+
+	reverseProxy := h.newReverseProxy(target, proxy)
+	rewrite := reverseProxy.Rewrite
+	reverseProxy.Rewrite = func(pr *httputil.ProxyRequest) {
+		rewrite(pr)
+		// Let the transport negotiate compression so bodies are recorded decoded
+		pr.Out.Header.Del("Accept-Encoding")
+	}
+	reverseProxy.ModifyResponse = func(resp *http.Response) error {
+		responseBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+		h.recordExchange(rec, r, requestBody, resp, responseBody)
+		return nil
+	}
+
+	h.logger.LogDebug("Recording %s %s via %s", r.Method, r.URL.Path, proxy.Target)
+	reverseProxy.ServeHTTP(w, r)
+}
+
+// recordExchange saves the request/response pair unless an equivalent
+// request was recorded before
+func (h *MockHandler) recordExchange(rec *recorder, r *http.Request, requestBody []byte, resp *http.Response, responseBody []byte) {
+	route := config.Route{
+		Path:       r.URL.Path,
+		Method:     r.Method,
+		StatusCode: resp.StatusCode,
+	}
+
+	if query := r.URL.Query(); len(query) > 0 {
+		route.Parameters = firstValues(query)
+	}
+
+	// Only JSON bodies are matched on, so only they tell requests apart
+	var requestDocument interface{}
+	if len(requestBody) > 0 && json.Unmarshal(requestBody, &requestDocument) == nil && requestDocument != nil {
+		route.Match = &config.RequestMatch{Body: &config.BodyMatcher{EqualToJSON: requestDocument}}
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	route.ContentType = contentType
+	route.Response = string(responseBody)
+	if !utf8.Valid(responseBody) {
+		// Binary bodies would not survive a round trip through YAML strings
+		route.Response = nil
+		route.BodyBase64 = base64.StdEncoding.EncodeToString(responseBody)
+	} else if config.IsJSONContentType(contentType) {
+		var document interface{}
+		if err := json.Unmarshal(responseBody, &document); err == nil {
+			route.Response = document
+		}
+	}
+
+	for name, values := range resp.Header {
+		if rec.keepHeader(name) && len(values) > 0 {
+			if route.Headers == nil {
+				route.Headers = make(map[string]string)
+			}
+			route.Headers[name] = values[0]
+		}
+	}
+
+	key := config.RequestKey(r.Method, r.URL.Path, r.URL.Query(), requestDocument)
+
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+
+	if rec.seen[key] {
+		h.logger.LogDebug("Already recorded %s %s", r.Method, r.URL.Path)
+		return
+	}
+	rec.seen[key] = true
+
+	rec.manager.AddRoute(route)
+	if err := rec.manager.Save(); err != nil {
+		h.logger.LogErrorWithRequest(err, r, "saving recorded route")
+		return
+	}
+	h.logger.LogInfo("Recorded %s %s -> %d", r.Method, r.URL.Path, resp.StatusCode)
+}
+
+// keepHeader reports whether a response header should be recorded
+func (rec *recorder) keepHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	if config.IsPerResponseHeader(name) {
+		return false
+	}
+	if rec.headers != nil {
+		return rec.headers[name]
+	}
+	return true
+}
+
+// routeKey computes the dedupe key of a route loaded from the record file
+func (rec *recorder) routeKey(h *MockHandler, route config.Route) string {
+	query := url.Values{}
+	for name, value := range route.Parameters {
+		query.Set(name, value)
+	}
+
+	var body interface{}
+	if route.Match != nil && route.Match.Body != nil && route.Match.Body.EqualToJSON != nil {
+		body = h.normalizeJSON(route.Match.Body.EqualToJSON)
+	}
+	return config.RequestKey(route.Method, route.Path, query, body)
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+func newRecordingHandler(t *testing.T, path string, headers []string) (*MockHandler, *int32) {
+	var calls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "abc")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"path": "` + r.URL.Path + `", "id": 7}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	handler, _ := createTestHandler()
+	handler.SetProxy(&config.ProxyConfig{Target: upstream.URL})
+	if err := handler.StartRecording(RecordOptions{Path: path, Headers: headers}); err != nil {
+		t.Fatalf("Failed to start recording: %v", err)
+	}
+	return handler, &calls
+}
+
+func loadRecorded(t *testing.T, path string) []config.Route {
+	manager := config.NewManager(path)
+	if err := manager.Load(); err != nil {
+		t.Fatalf("Failed to load recorded routes: %v", err)
+	}
+	return manager.GetRoutes()
+}
+
+func TestRecordDedupe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recorded.yaml")
+	handler, calls := newRecordingHandler(t, path, nil)
+
+	requests := []struct{ method, path, body string }{
+		{"GET", "/api/users?page=1&sort=name", ""},
+		{"GET", "/api/users?sort=name&page=1", ""},
+		{"GET", "/api/users?page=2", ""},
+		{"POST", "/api/users", `{"name": "Alice", "age": 30}`},
+		{"POST", "/api/users", `{"age": 30, "name": "Alice"}`},
+		{"POST", "/api/users", `{"name": "Bob"}`},
+		// Matched routes are recorded too: record mode always forwards
+		{"GET", "/test/simple", ""},
+	}
+	for _, request := range requests {
+		if w := doRequest(handler, request.method, request.path, request.body); w.Code != http.StatusCreated {
+			t.Fatalf("Expected upstream status 201 for %s %s, got %d", request.method, request.path, w.Code)
+		}
+	}
+	if atomic.LoadInt32(calls) != int32(len(requests)) {
+		t.Errorf("Expected every request to be forwarded, got %d", *calls)
+	}
+
+	routes := loadRecorded(t, path)
+	if len(routes) != 5 {
+		t.Fatalf("Expected 5 unique recorded routes, got %d", len(routes))
+	}
+
+	first := routes[0]
+	if first.Method != "GET" || first.Path != "/api/users" || first.StatusCode != 201 {
+		t.Errorf("Unexpected first route: %+v", first)
+	}
+	if first.Parameters["page"] != "1" || first.Parameters["sort"] != "name" {
+		t.Errorf("Expected query parameters to be recorded, got %v", first.Parameters)
+	}
+	if response, ok := first.GetJSONSafeResponse().(map[string]interface{}); !ok || response["id"] != 7 {
+		t.Errorf("Expected JSON response to be recorded as a document, got %#v", first.Response)
+	}
+	if first.Headers["X-Request-Id"] != "abc" || first.Headers["Date"] != "" || first.Headers["Content-Length"] != "" {
+		t.Errorf("Unexpected recorded headers: %v", first.Headers)
+	}
+
+	post := routes[2]
+	if post.Match == nil || post.Match.Body == nil || post.Match.Body.EqualToJSON == nil {
+		t.Fatalf("Expected a JSON body matcher, got %+v", post.Match)
+	}
+
+	// Replaying the recording serves the same responses
+	replay := NewMockHandler(config.NewManager(path), handler.logger)
+	if err := replay.configManager.Load(); err != nil {
+		t.Fatalf("Failed to load recording: %v", err)
+	}
+	w := doRequest(replay, "POST", "/api/users", `{"name": "Bob"}`)
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"/api/users"`) {
+		t.Errorf("Expected recorded response on replay, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRecordKeepsExistingRoutes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recorded.yaml")
+	handler, _ := newRecordingHandler(t, path, []string{"cache-control"})
+	doRequest(handler, "POST", "/api/users", `{"name": "Alice"}`)
+
+	routes := loadRecorded(t, path)
+	if len(routes) != 1 {
+		t.Fatalf("Expected 1 recorded route, got %d", len(routes))
+	}
+	if len(routes[0].Headers) != 1 || routes[0].Headers["Cache-Control"] != "no-cache" {
+		t.Errorf("Expected only Cache-Control to be recorded, got %v", routes[0].Headers)
+	}
+
+	// A second session does not record the same request again
+	handler, _ = newRecordingHandler(t, path, nil)
+	doRequest(handler, "POST", "/api/users", `{"name":"Alice"}`)
+	doRequest(handler, "DELETE", "/api/users/1", "")
+
+	if routes := loadRecorded(t, path); len(routes) != 2 {
+		t.Errorf("Expected 2 recorded routes after second session, got %d", len(routes))
+	}
+}
+
+func TestRecordWithoutProxy(t *testing.T) {
+	handler, _ := createTestHandler()
+	if err := handler.StartRecording(RecordOptions{Path: filepath.Join(t.TempDir(), "recorded.yaml")}); err != nil {
+		t.Fatalf("Failed to start recording: %v", err)
+	}
+
+	if w := doRequest(handler, "GET", "/test/simple", ""); w.Code != http.StatusBadGateway {
+		t.Errorf("Expected status 502 without a proxy target, got %d", w.Code)
+	}
+}
+
+func TestRecordBinary(t *testing.T) {
+	image := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(image)
+	}))
+	t.Cleanup(upstream.Close)
+
+	path := filepath.Join(t.TempDir(), "recorded.yaml")
+	handler, _ := createTestHandler()
+	handler.SetProxy(&config.ProxyConfig{Target: upstream.URL})
+	if err := handler.StartRecording(RecordOptions{Path: path}); err != nil {
+		t.Fatalf("Failed to start recording: %v", err)
+	}
+	doRequest(handler, "GET", "/logo.png", "")
+
+	routes := loadRecorded(t, path)
+	if len(routes) != 1 || routes[0].Response != nil || routes[0].BodyBase64 == "" {
+		t.Fatalf("Expected the binary body to be recorded base64 encoded, got %+v", routes)
+	}
+
+	replay := NewMockHandler(config.NewManager(path), handler.logger)
+	if err := replay.configManager.Load(); err != nil {
+		t.Fatalf("Failed to load recording: %v", err)
+	}
+	if w := doRequest(replay, "GET", "/logo.png", ""); w.Body.String() != string(image) {
+		t.Errorf("Expected the recorded bytes on replay, got %v", w.Body.Bytes())
+	}
+}
+
+func TestRecordReplayJSONWithCharset(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"id": 1, "name": "x"}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	path := filepath.Join(t.TempDir(), "recorded.yaml")
+	handler, _ := createTestHandler()
+	handler.SetProxy(&config.ProxyConfig{Target: upstream.URL})
+	if err := handler.StartRecording(RecordOptions{Path: path}); err != nil {
+		t.Fatalf("Failed to start recording: %v", err)
+	}
+	doRequest(handler, "GET", "/api/item", "")
+
+	replay := NewMockHandler(config.NewManager(path), handler.logger)
+	if err := replay.configManager.Load(); err != nil {
+		t.Fatalf("Failed to load recording: %v", err)
+	}
+	w := doRequest(replay, "GET", "/api/item", "")
+	if strings.TrimSpace(w.Body.String()) != `{"id":1,"name":"x"}` {
+		t.Errorf("Expected the recorded JSON on replay, got %s", w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("Expected the recorded content type, got %s", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestRecordIgnoresUnmatchedBodies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recorded.yaml")
+	handler, calls := newRecordingHandler(t, path, nil)
+
+	doRequest(handler, "POST", "/api/login", "user=alice")
+	doRequest(handler, "POST", "/api/login", "user=bob")
+	if atomic.LoadInt32(calls) != 2 {
+		t.Errorf("Expected both requests to be forwarded, got %d", *calls)
+	}
+
+	// Form bodies are not matched on, so a second route could never match
+	routes := loadRecorded(t, path)
+	if len(routes) != 1 || routes[0].Match != nil {
+		t.Fatalf("Expected a single route without body matcher, got %+v", routes)
+	}
+
+	// Nor are they recorded again in a later session
+	handler, _ = newRecordingHandler(t, path, nil)
+	doRequest(handler, "POST", "/api/login", "user=carol")
+	if routes := loadRecorded(t, path); len(routes) != 1 {
+		t.Errorf("Expected the existing route to cover the request, got %d routes", len(routes))
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// scenarioStore tracks the current state of each scenario
+type scenarioStore struct {
+	mutex  sync.Mutex
+	states map[string]string
+}
+
+// newScenarioStore creates an empty scenario store
+func newScenarioStore() *scenarioStore {
+	return &scenarioStore{
+		states: make(map[string]string),
+	}
+}
+
+// state returns the current state of a scenario
+func (s *scenarioStore) state(name string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if state, ok := s.states[name]; ok {
+		return state
+	}
+	return config.ScenarioStarted
+}
+
+// transition moves a scenario to newState if it is still in requiredState.
+// It returns false when another request changed the state in the meantime.
+func (s *scenarioStore) transition(name, requiredState, newState string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, ok := s.states[name]
+	if !ok {
+		current = config.ScenarioStarted
+	}
+	if requiredState != "" && current != requiredState {
+		return false
+	}
+
+	s.states[name] = newState
+	return true
+}
+
+// set forces a scenario into the given state
+func (s *scenarioStore) set(name, state string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.states[name] = state
+}
+
+// reset returns every scenario to its initial state
+func (s *scenarioStore) reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.states = make(map[string]string)
+}
+
+// matchesScenario checks if the route's required state is the scenario's
+// current state
+func (h *MockHandler) matchesScenario(route *config.Route) bool {
+	if route.Scenario == "" || route.RequiredState == "" {
+		return true
+	}
+	return h.scenarios.state(route.Scenario) == route.RequiredState
+}
+
+// handleScenariosAPI handles the /_mock/scenarios endpoints
+func (h *MockHandler) handleScenariosAPI(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/_mock/scenarios"), "/")
+
+	switch {
+	case name == "" && r.Method == "GET":
+		h.handleGetScenarios(w, r)
+	case name == "reset" && r.Method == "POST":
+		h.scenarios.reset()
+		h.logger.LogInfo("Reset all scenarios")
+		if err := json.NewEncoder(w).Encode(map[string]string{"message": "Scenarios reset successfully"}); err != nil {
+			h.logger.LogError(err, "encoding reset scenarios response")
+		}
+	case name != "" && r.Method == "PUT":
+		h.handleSetScenarioState(w, r, name)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleGetScenarios returns every scenario referenced by a route with its
+// current and possible states
+func (h *MockHandler) handleGetScenarios(w http.ResponseWriter, r *http.Request) {
+	h.mutex.RLock()
+	routes := h.configManager.GetRoutes()
+	h.mutex.RUnlock()
+
+	possibleStates := make(map[string]map[string]bool)
+	for _, route := range routes {
+		if route.Scenario == "" {
+			continue
+		}
+		if possibleStates[route.Scenario] == nil {
+			possibleStates[route.Scenario] = map[string]bool{config.ScenarioStarted: true}
+		}
+		for _, state := range []string{route.RequiredState, route.NewState} {
+			if state != "" {
+				possibleStates[route.Scenario][state] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(possibleStates))
+	for name := range possibleStates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	scenarios := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		states := make([]string, 0, len(possibleStates[name]))
+		for state := range possibleStates[name] {
+			states = append(states, state)
+		}
+		sort.Strings(states)
+
+		scenarios = append(scenarios, map[string]interface{}{
+			"name":            name,
+			"state":           h.scenarios.state(name),
+			"possible_states": states,
+		})
+	}
+
+	response := map[string]interface{}{
+		"scenarios": scenarios,
+		"count":     len(scenarios),
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.LogErrorWithRequest(err, r, "encoding scenarios response")
+	}
+}
+
+// handleSetScenarioState forces a scenario into the requested state
+func (h *MockHandler) handleSetScenarioState(w http.ResponseWriter, r *http.Request, name string) {
+	var request struct {
+		State string `json:"state"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.State == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		if encErr := json.NewEncoder(w).Encode(map[string]string{"error": "Expected JSON body with a state"}); encErr != nil {
+			h.logger.LogError(encErr, "encoding error response")
+		}
+		return
+	}
+
+	h.scenarios.set(name, request.State)
+	h.logger.LogInfo("Scenario %s set to state %s", name, request.State)
+
+	response := map[string]string{
+		"message": "Scenario state updated successfully",
+		"name":    name,
+		"state":   request.State,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.LogError(err, "encoding set scenario response")
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+func addOrderScenario(configManager *config.Manager) {
+	routes := []config.Route{
+		{
+			Path: "/api/order", Method: "GET", StatusCode: 200, ContentType: "text/plain",
+			Response: "PENDING", Scenario: "order", RequiredState: config.ScenarioStarted,
+		},
+		{
+			Path: "/api/order/ship", Method: "POST", StatusCode: 202, ContentType: "text/plain",
+			Response: "shipping", Scenario: "order", RequiredState: config.ScenarioStarted, NewState: "SHIPPED",
+		},
+		{
+			Path: "/api/order", Method: "GET", StatusCode: 200, ContentType: "text/plain",
+			Response: "SHIPPED", Scenario: "order", RequiredState: "SHIPPED",
+		},
+	}
+	for _, route := range routes {
+		configManager.AddRoute(route)
+	}
+}
+
+func doRequest(handler *MockHandler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestScenarioTransitions(t *testing.T) {
+	handler, configManager := createTestHandler()
+	addOrderScenario(configManager)
+
+	if w := doRequest(handler, "GET", "/api/order", ""); w.Body.String() != "PENDING" {
+		t.Errorf("Expected PENDING before shipping, got '%s'", w.Body.String())
+	}
+
+	if w := doRequest(handler, "POST", "/api/order/ship", ""); w.Code != 202 {
+		t.Errorf("Expected status 202 for ship, got %d", w.Code)
+	}
+
+	if w := doRequest(handler, "GET", "/api/order", ""); w.Body.String() != "SHIPPED" {
+		t.Errorf("Expected SHIPPED after shipping, got '%s'", w.Body.String())
+	}
+
+	// Shipping again no longer matches the required state
+	if w := doRequest(handler, "POST", "/api/order/ship", ""); w.Code != 404 {
+		t.Errorf("Expected status 404 for second ship, got %d", w.Code)
+	}
+}
+
+func TestScenarioTransitionIsAtomic(t *testing.T) {
+	handler, configManager := createTestHandler()
+	addOrderScenario(configManager)
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- doRequest(handler, "POST", "/api/order/ship", "").Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	shipped := 0
+	for code := range codes {
+		if code == 202 {
+			shipped++
+		}
+	}
+	if shipped != 1 {
+		t.Errorf("Expected exactly one request to ship the order, got %d", shipped)
+	}
+}
+
+func TestScenariosAPI(t *testing.T) {
+	handler, configManager := createTestHandler()
+	addOrderScenario(configManager)
+
+	w := doRequest(handler, "PUT", "/_mock/scenarios/order", `{"state": "SHIPPED"}`)
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200 when setting state, got %d", w.Code)
+	}
+
+	w = doRequest(handler, "GET", "/_mock/scenarios", "")
+	var response struct {
+		Scenarios []struct {
+			Name           string   `json:"name"`
+			State          string   `json:"state"`
+			PossibleStates []string `json:"possible_states"`
+		} `json:"scenarios"`
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Count != 1 || response.Scenarios[0].Name != "order" {
+		t.Fatalf("Expected the order scenario, got %+v", response)
+	}
+	if response.Scenarios[0].State != "SHIPPED" {
+		t.Errorf("Expected state SHIPPED, got %s", response.Scenarios[0].State)
+	}
+	if len(response.Scenarios[0].PossibleStates) != 2 {
+		t.Errorf("Expected 2 possible states, got %v", response.Scenarios[0].PossibleStates)
+	}
+
+	if w := doRequest(handler, "POST", "/_mock/scenarios/reset", ""); w.Code != 200 {
+		t.Errorf("Expected status 200 for reset, got %d", w.Code)
+	}
+	if w := doRequest(handler, "GET", "/api/order", ""); w.Body.String() != "PENDING" {
+		t.Errorf("Expected PENDING after reset, got '%s'", w.Body.String())
+	}
+
+	if w := doRequest(handler, "PUT", "/_mock/scenarios/order", `{}`); w.Code != 400 {
+		t.Errorf("Expected status 400 without a state, got %d", w.Code)
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"sync"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// sequenceStore counts calls per route to select entries of a responses list
+type sequenceStore struct {
+	mutex    sync.Mutex
+	counters map[string]int
+}
+
+// newSequenceStore creates an empty sequence store
+func newSequenceStore() *sequenceStore {
+	return &sequenceStore{
+		counters: make(map[string]int),
+	}
+}
+
+// next returns the number of previous calls for the key and increments it
+func (s *sequenceStore) next(key string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := s.counters[key]
+	s.counters[key] = count + 1
+	return count
+}
+
+// snapshot returns a copy of the counters
+func (s *sequenceStore) snapshot() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counters := make(map[string]int, len(s.counters))
+	for key, count := range s.counters {
+		counters[key] = count
+	}
+	return counters
+}
+
+// reset clears all counters
+func (s *sequenceStore) reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.counters = make(map[string]int)
+}
+
+// selectResponse returns the route to serve for a match. For routes with a
+// responses list, the selected entry overrides the route's own values.
+func (h *MockHandler) selectResponse(match *routeMatch) *config.Route {
+	route := match.route
+	if len(route.Responses) == 0 {
+		return route
+	}
+
+	count := h.sequences.next(match.key)
+
+	var index int
+	switch route.ResponseMode {
+	case config.ResponseModeCycle:
+		index = count % len(route.Responses)
+	case config.ResponseModeRandom:
+		index = weightedIndex(route.Responses)
+	default:
+		index = count
+		if index >= len(route.Responses) {
+			index = len(route.Responses) - 1
+		}
+	}
+
+	variant := route.Responses[index]
+	selected := *route
+	if variant.StatusCode != 0 {
+		selected.StatusCode = variant.StatusCode
+	}
+	if variant.ContentType != "" {
+		selected.ContentType = variant.ContentType
+	}
+	if variant.Response != nil || variant.BodyFile != "" || variant.BodyBase64 != "" {
+		selected.Response, selected.BodyFile, selected.BodyBase64 = variant.Response, variant.BodyFile, variant.BodyBase64
+	}
+	if len(variant.Headers) > 0 {
+		selected.Headers = make(map[string]string, len(route.Headers)+len(variant.Headers))
+		for key, value := range route.Headers {
+			selected.Headers[key] = value
+		}
+		for key, value := range variant.Headers {
+			selected.Headers[key] = value
+		}
+	}
+	return &selected
+}
+
+// weightedIndex picks a random entry, treating a zero weight as 1. Entries
+// are picked uniformly when no weight is positive.
+func weightedIndex(variants []config.ResponseVariant) int {
+	total := 0
+	for _, variant := range variants {
+		total += variantWeight(variant)
+	}
+	if total <= 0 {
+		return rand.Intn(len(variants))
+	}
+
+	pick := rand.Intn(total)
+	for i, variant := range variants {
+		pick -= variantWeight(variant)
+		if pick < 0 {
+			return i
+		}
+	}
+	return len(variants) - 1
+}
+
+// variantWeight returns the effective weight of a responses entry; negative
+// weights count as 0
+func variantWeight(variant config.ResponseVariant) int {
+	if variant.Weight == 0 {
+		return 1
+	}
+	return max(variant.Weight, 0)
+}
+
+// handleSequencesAPI handles the /_mock/sequences endpoints
+func (h *MockHandler) handleSequencesAPI(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/_mock/sequences" && r.Method == "GET":
+		counters := h.sequences.snapshot()
+		response := map[string]interface{}{
+			"counters": counters,
+			"count":    len(counters),
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			h.logger.LogErrorWithRequest(err, r, "encoding sequences response")
+		}
+	case r.URL.Path == "/_mock/sequences/reset" && r.Method == "POST":
+		h.sequences.reset()
+		h.logger.LogInfo("Reset all response sequences")
+		if err := json.NewEncoder(w).Encode(map[string]string{"message": "Sequences reset successfully"}); err != nil {
+			h.logger.LogError(err, "encoding reset sequences response")
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+func TestResponseSequenceStick(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/api/jobs/1", Method: "GET", StatusCode: 200, ContentType: "text/plain",
+		Headers: map[string]string{"X-Job": "1"},
+		Responses: []config.ResponseVariant{
+			{StatusCode: 202, Response: "running", Headers: map[string]string{"Retry-After": "1"}},
+			{StatusCode: 202, Response: "running"},
+			{Response: "done"},
+		},
+	})
+
+	expected := []struct {
+		code int
+		body string
+	}{
+		{202, "running"}, {202, "running"}, {200, "done"}, {200, "done"},
+	}
+
+	for i, exp := range expected {
+		w := doRequest(handler, "GET", "/api/jobs/1", "")
+		if w.Code != exp.code || w.Body.String() != exp.body {
+			t.Errorf("Call %d: expected %d '%s', got %d '%s'", i+1, exp.code, exp.body, w.Code, w.Body.String())
+		}
+		if w.Header().Get("X-Job") != "1" {
+			t.Errorf("Call %d: expected route headers to be kept", i+1)
+		}
+		if i == 0 && w.Header().Get("Retry-After") != "1" {
+			t.Errorf("Expected entry headers to be merged on first call")
+		}
+	}
+}
+
+func TestResponseSequenceCycleAndReset(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/api/flaky", Method: "GET", StatusCode: 200, ContentType: "text/plain",
+		ResponseMode: config.ResponseModeCycle,
+		Responses: []config.ResponseVariant{
+			{Response: "ok"},
+			{StatusCode: 503, Response: "unavailable"},
+		},
+	})
+
+	for i, expected := range []string{"ok", "unavailable", "ok", "unavailable"} {
+		if w := doRequest(handler, "GET", "/api/flaky", ""); w.Body.String() != expected {
+			t.Errorf("Call %d: expected '%s', got '%s'", i+1, expected, w.Body.String())
+		}
+	}
+
+	w := doRequest(handler, "GET", "/_mock/sequences", "")
+	var response struct {
+		Counters map[string]int `json:"counters"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Counters["GET /api/flaky"] != 4 {
+		t.Errorf("Expected counter 4, got %v", response.Counters)
+	}
+
+	doRequest(handler, "POST", "/_mock/sequences/reset", "")
+	doRequest(handler, "GET", "/api/flaky", "")
+	if w := doRequest(handler, "GET", "/api/flaky", ""); w.Code != 503 {
+		t.Errorf("Expected sequence to restart after reset, got %d", w.Code)
+	}
+}
+
+func TestResponseSequenceRandom(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/api/random", Method: "GET", StatusCode: 200, ContentType: "text/plain",
+		ResponseMode: config.ResponseModeRandom,
+		Responses: []config.ResponseVariant{
+			{Response: "never", Weight: 0},
+			{Response: "always", Weight: 1000000},
+		},
+	})
+
+	counts := map[string]int{}
+	for i := 0; i < 50; i++ {
+		counts[doRequest(handler, "GET", "/api/random", "").Body.String()]++
+	}
+	if counts["always"] < 45 {
+		t.Errorf("Expected heavily weighted entry to dominate, got %v", counts)
+	}
+}
+
+func TestWeightedIndexNonPositiveWeights(t *testing.T) {
+	variants := []config.ResponseVariant{{Weight: -1}, {Weight: -5}}
+	for i := 0; i < 20; i++ {
+		if index := weightedIndex(variants); index < 0 || index >= len(variants) {
+			t.Fatalf("Expected an index in range, got %d", index)
+		}
+	}
+
+	variants = append(variants, config.ResponseVariant{Weight: 2})
+	for i := 0; i < 20; i++ {
+		if index := weightedIndex(variants); index != 2 {
+			t.Fatalf("Expected only the positive weight to be picked, got %d", index)
+		}
+	}
+}
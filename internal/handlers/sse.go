@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// serveEvents streams the route's server-sent events, flushing each one as
+// it is sent. Looping streams run until the client disconnects.
+func (h *MockHandler) serveEvents(w http.ResponseWriter, r *http.Request, route *config.Route, statusCode int) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	if !h.waitRoute(w, r, route) {
+		return
+	}
+
+	// Streams outlive the server's write timeout; recorders do not support
+	// deadlines, which is fine
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(statusCode)
+	flusher.Flush()
+
+	if len(route.SSE.Events) == 0 {
+		return
+	}
+
+	sent := 0
+	for {
+		if r.Context().Err() != nil {
+			h.logger.LogDebug("Client closed event stream %s %s after %d events", r.Method, r.URL.Path, sent)
+			return
+		}
+		for i := range route.SSE.Events {
+			event := &route.SSE.Events[i]
+			if !h.wait(r, time.Duration(event.DelayMs)*time.Millisecond) {
+				h.logger.LogDebug("Client closed event stream %s %s after %d events", r.Method, r.URL.Path, sent)
+				return
+			}
+			if _, err := w.Write(h.formatEvent(event, r)); err != nil {
+				h.logger.LogError(err, "writing server-sent event")
+				return
+			}
+			flusher.Flush()
+			sent++
+		}
+		if !route.SSE.Loop {
+			return
+		}
+	}
+}
+
+// formatEvent renders an event in the text/event-stream format. The id and
+// data are rendered as templates; data with line breaks is split over
+// several data fields.
+func (h *MockHandler) formatEvent(event *config.SSEEvent, r *http.Request) []byte {
+	var buf bytes.Buffer
+	if event.ID != "" {
+		fmt.Fprintf(&buf, "id: %v\n", h.processResponse(event.ID, r))
+	}
+	if event.Event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", event.Event)
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&buf, "retry: %d\n", event.Retry)
+	}
+
+	if event.Data != nil {
+		var data string
+		switch v := h.processResponse(event.Data, r).(type) {
+		case string:
+			data = v
+		default:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				h.logger.LogError(err, "encoding server-sent event data")
+			}
+			data = string(encoded)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(data, "\n"), "\n") {
+			fmt.Fprintf(&buf, "data: %s\n", line)
+		}
+	}
+
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+func TestServeEvents(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/events/{room}", Method: "GET", StatusCode: 200,
+		SSE: &config.SSE{Events: []config.SSEEvent{
+			{ID: "1", Event: "joined", Data: "room {{.PathParams.room}}", Retry: 3000},
+			{ID: "2", Data: map[interface{}]interface{}{"price": 42}, DelayMs: 20},
+			{Data: "line one\nline two\n"},
+		}},
+	})
+
+	start := time.Now()
+	w := doRequest(handler, "GET", "/events/lobby", "")
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected the event delay to be applied, took %v", elapsed)
+	}
+	if w.Header().Get("Content-Type") != "text/event-stream" || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("Unexpected headers: %v", w.Header())
+	}
+	want := "id: 1\nevent: joined\nretry: 3000\ndata: room lobby\n\n" +
+		"id: 2\ndata: {\"price\":42}\n\n" +
+		"data: line one\ndata: line two\n\n"
+	if w.Body.String() != want {
+		t.Errorf("Expected events:\n%q\ngot:\n%q", want, w.Body.String())
+	}
+}
+
+func TestServeEventsLoop(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/ticks", Method: "GET", StatusCode: 200,
+		SSE: &config.SSE{Loop: true, Events: []config.SSEEvent{{ID: "{{uuid}}", Event: "tick", Data: "{{uuid}}", DelayMs: 5}}},
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/ticks", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Events arrive one at a time, each with freshly rendered data
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && len(seen) < 5 {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			seen[data] = true
+		}
+	}
+	if len(seen) < 5 {
+		t.Errorf("Expected 5 distinct looping events, got %v", seen)
+	}
+}
+
+func TestServeEventsEmptyLoop(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/events/none", Method: "GET", StatusCode: 200,
+		SSE: &config.SSE{Loop: true},
+	})
+
+	done := make(chan int)
+	go func() { done <- doRequest(handler, "GET", "/events/none", "").Code }()
+	select {
+	case code := <-done:
+		if code != 200 {
+			t.Errorf("Expected status 200, got %d", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a looping stream without events to end")
+	}
+}
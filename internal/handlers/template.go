@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/walterfan/lazy-mock-server/internal/jsonpath"
+	"github.com/walterfan/lazy-mock-server/internal/xmldoc"
+	"gopkg.in/yaml.v2"
+)
+
+// RequestData is the request context exposed to response templates, e.g.
+// {{.Method}}, {{.PathParams.id}}, {{.Headers.Authorization}} or
+// {{jsonPath .JSON "$.request.id"}}
+type RequestData struct {
+	Method     string
+	URL        string
+	Path       string
+	RawQuery   string
+	Query      map[string]string
+	Headers    map[string]string
+	Cookies    map[string]string
+	PathParams map[string]string
+	Form       map[string]string
+	Body       string
+	JSON       interface{}
+}
+
+// templateFuncs are the helper functions available to response templates
+var templateFuncs = template.FuncMap{
+	"uuid":         newUUID,
+	"now":          time.Now,
+	"date":         formatDate,
+	"timestamp":    func() int64 { return time.Now().Unix() },
+	"randomInt":    randomInt,
+	"base64Encode": func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"base64Decode": base64Decode,
+	"jsonPath":     jsonPathLookup,
+	"xpath":        xpathLookup,
+	"toJSON":       toJSON,
+	"upper":        strings.ToUpper,
+	"lower":        strings.ToLower,
+	"default":      defaultValue,
+}
+
+// processResponse processes the response body, handling dynamic content.
+// Strings nested in maps and lists are rendered as well, both for the
+// legacy {placeholder} syntax and for Go templates.
+func (h *MockHandler) processResponse(response interface{}, r *http.Request) interface{} {
+	var data *RequestData
+	return h.renderValue(response, r, &data)
+}
+
+// renderValue walks a response value and renders every string in it. The
+// request data is built lazily since most responses contain no templates.
+func (h *MockHandler) renderValue(value interface{}, r *http.Request, data **RequestData) interface{} {
+	switch v := value.(type) {
+	case string:
+		return h.renderString(v, r, data)
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if strKey, ok := key.(string); ok {
+				result[strKey] = h.renderValue(item, r, data)
+			}
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = h.renderValue(item, r, data)
+		}
+		return result
+	case yaml.MapSlice:
+		// Ordered maps of XML responses keep their order
+		result := make(yaml.MapSlice, len(v))
+		for i, item := range v {
+			result[i] = yaml.MapItem{Key: item.Key, Value: h.renderValue(item.Value, r, data)}
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = h.renderValue(item, r, data)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// renderString executes Go templates and then replaces legacy
+// placeholders. Only the configured string is parsed as a template, so
+// request data substituted for placeholders is never executed.
+func (h *MockHandler) renderString(str string, r *http.Request, data **RequestData) string {
+	if !strings.Contains(str, "{{") {
+		return h.replacePlaceholders(str, r)
+	}
+
+	tmpl, err := h.getTemplate(str)
+	if err != nil {
+		h.logger.LogErrorWithRequest(err, r, "parsing response template")
+		return h.replacePlaceholders(str, r)
+	}
+
+	if *data == nil {
+		*data = h.newRequestData(r)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, *data); err != nil {
+		h.logger.LogErrorWithRequest(err, r, "executing response template")
+		return h.replacePlaceholders(str, r)
+	}
+	return h.replacePlaceholders(buf.String(), r)
+}
+
+// replacePlaceholders replaces the {method}, {path}, {query}, {path.name}
+// and {queryParam} placeholders
+func (h *MockHandler) replacePlaceholders(str string, r *http.Request) string {
+	if !strings.Contains(str, "{") {
+		return str
+	}
+
+	// Replace placeholders with request data
+	str = strings.ReplaceAll(str, "{method}", r.Method)
+	str = strings.ReplaceAll(str, "{path}", r.URL.Path)
+	str = strings.ReplaceAll(str, "{query}", r.URL.RawQuery)
+
+	// Replace captured path parameters
+	for key, value := range PathParams(r) {
+		str = strings.ReplaceAll(str, fmt.Sprintf("{path.%s}", key), value)
+	}
+
+	// Replace query parameters
+	for key, values := range r.URL.Query() {
+		if len(values) > 0 {
+			placeholder := fmt.Sprintf("{%s}", key)
+			str = strings.ReplaceAll(str, placeholder, values[0])
+		}
+	}
+
+	return str
+}
+
+// getTemplate returns the parsed template, caching it for subsequent requests
+func (h *MockHandler) getTemplate(text string) (*template.Template, error) {
+	h.patternMutex.Lock()
+	defer h.patternMutex.Unlock()
+
+	if tmpl, ok := h.templates[text]; ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := template.New("response").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	h.templates[text] = tmpl
+	return tmpl, nil
+}
+
+// newRequestData collects the request context exposed to templates
+func (h *MockHandler) newRequestData(r *http.Request) *RequestData {
+	data := &RequestData{
+		Method:     r.Method,
+		URL:        r.URL.String(),
+		Path:       r.URL.Path,
+		RawQuery:   r.URL.RawQuery,
+		Query:      firstValues(r.URL.Query()),
+		Headers:    firstValues(r.Header),
+		Cookies:    make(map[string]string),
+		PathParams: PathParams(r),
+	}
+
+	if data.PathParams == nil {
+		data.PathParams = make(map[string]string)
+	}
+
+	for _, cookie := range r.Cookies() {
+		data.Cookies[cookie.Name] = cookie.Value
+	}
+
+	body := h.readBody(r)
+	data.Body = string(body)
+	if len(body) > 0 {
+		var parsed interface{}
+		if err := json.Unmarshal(body, &parsed); err == nil {
+			data.JSON = parsed
+		}
+	}
+
+	if err := h.parseForm(r); err != nil {
+		h.logger.LogErrorWithRequest(err, r, "parsing form for template")
+	}
+	data.Form = firstValues(r.PostForm)
+
+	return data
+}
+
+// firstValues flattens multi-valued maps to their first value
+func firstValues(values map[string][]string) map[string]string {
+	result := make(map[string]string, len(values))
+	for key, list := range values {
+		if len(list) > 0 {
+			result[key] = list[0]
+		}
+	}
+	return result
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// formatDate formats a time with a Go layout or one of the names
+// "iso8601", "rfc1123" and "unix"
+func formatDate(layout string, t time.Time) string {
+	switch strings.ToLower(layout) {
+	case "iso8601", "rfc3339":
+		return t.Format(time.RFC3339)
+	case "rfc1123":
+		return t.UTC().Format(http.TimeFormat)
+	case "unix":
+		return fmt.Sprintf("%d", t.Unix())
+	}
+	return t.Format(layout)
+}
+
+// randomInt returns a random integer in [min, max]
+func randomInt(min, max int) int {
+	if max <= min {
+		return min
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max-min+1)))
+	if err != nil {
+		return min
+	}
+	return min + int(n.Int64())
+}
+
+// base64Decode decodes standard base64, returning an empty string on error
+func base64Decode(s string) string {
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return ""
+	}
+	return string(decoded)
+}
+
+// jsonPathLookup returns the value selected by expr, or nil when missing
+func jsonPathLookup(doc interface{}, expr string) interface{} {
+	value, _ := jsonpath.Get(doc, expr)
+	return value
+}
+
+// xpathLookup returns the text of the first node expr selects in an XML
+// document, e.g. {{xpath .Body "//AccountId"}}; prefixes cannot be bound, but
+// unprefixed names match any namespace
+func xpathLookup(document, expr string) string {
+	doc, err := xmldoc.Parse([]byte(document))
+	if err != nil {
+		return ""
+	}
+	value, _ := xmldoc.Get(doc, expr)
+	return value
+}
+
+// toJSON encodes a value as compact JSON
+func toJSON(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// defaultValue returns fallback when value is nil or an empty string
+func defaultValue(fallback, value interface{}) interface{} {
+	if value == nil {
+		return fallback
+	}
+	if str, ok := value.(string); ok && str == "" {
+		return fallback
+	}
+	return value
+}
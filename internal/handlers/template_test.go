@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+func TestRenderTemplates(t *testing.T) {
+	handler, _ := createTestHandler()
+
+	req := httptest.NewRequest("POST", "/api/users/42?lang=en", strings.NewReader(`{"request": {"id": "req-7"}, "items": [1, 2]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Trace", "trace-1")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	req = req.WithContext(context.WithValue(req.Context(), pathParamsKey, map[string]string{"id": "42"}))
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"Method and path", "{{.Method}} {{.Path}}", "POST /api/users/42"},
+		{"Query", "{{.Query.lang}}", "en"},
+		{"Header", "{{.Headers.Accept}}", "application/json"},
+		{"Header via index", `{{index .Headers "X-Trace"}}`, "trace-1"},
+		{"Cookie", "{{.Cookies.session}}", "abc"},
+		{"Path param", "{{.PathParams.id}}", "42"},
+		{"JSON body", `{{jsonPath .JSON "$.request.id"}}`, "req-7"},
+		{"JSON body field", "{{.JSON.request.id}}", "req-7"},
+		{"toJSON", `{{toJSON (jsonPath .JSON "$.items")}}`, "[1,2]"},
+		{"Base64", `{{base64Encode "hi"}}/{{base64Decode "aGk="}}`, "aGk=/hi"},
+		{"Default", `{{default "none" (jsonPath .JSON "$.missing")}}`, "none"},
+		{"Upper", `{{upper .Query.lang}}`, "EN"},
+		{"Legacy placeholder", "{method} {path.id} {lang}", "POST 42 en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := handler.processResponse(tt.template, req)
+			if result != tt.expected {
+				t.Errorf("processResponse(%s) = %v, expected %v", tt.template, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRenderTemplateHelpers(t *testing.T) {
+	handler, _ := createTestHandler()
+	req := httptest.NewRequest("GET", "/", nil)
+
+	uuid := handler.processResponse("{{uuid}}", req).(string)
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(uuid) {
+		t.Errorf("Expected a v4 UUID, got '%s'", uuid)
+	}
+
+	date := handler.processResponse(`{{date "2006-01-02" now}}`, req)
+	if date != time.Now().Format("2006-01-02") {
+		t.Errorf("Expected today's date, got '%v'", date)
+	}
+
+	for i := 0; i < 20; i++ {
+		value := handler.processResponse(`{{randomInt 5 7}}`, req)
+		if value != "5" && value != "6" && value != "7" {
+			t.Fatalf("Expected random int in [5, 7], got '%v'", value)
+		}
+	}
+
+	// Invalid templates are returned unchanged
+	if result := handler.processResponse("{{.Method", req); result != "{{.Method" {
+		t.Errorf("Expected invalid template to be returned unchanged, got '%v'", result)
+	}
+}
+
+func TestRenderNestedResponse(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path:        "/api/orders/{id}",
+		Method:      "POST",
+		StatusCode:  201,
+		ContentType: "application/json",
+		Response: map[interface{}]interface{}{
+			"id":        "{path.id}",
+			"requestId": `{{jsonPath .JSON "$.meta.requestId"}}`,
+			"items": []interface{}{
+				map[interface{}]interface{}{"method": "{{.Method}}"},
+				"static",
+			},
+			"count": 3,
+		},
+	})
+
+	req := httptest.NewRequest("POST", "/api/orders/9", strings.NewReader(`{"meta": {"requestId": "r-1"}}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response["id"] != "9" {
+		t.Errorf("Expected id '9', got %v", response["id"])
+	}
+	if response["requestId"] != "r-1" {
+		t.Errorf("Expected requestId 'r-1', got %v", response["requestId"])
+	}
+	items := response["items"].([]interface{})
+	if items[0].(map[string]interface{})["method"] != "POST" || items[1] != "static" {
+		t.Errorf("Unexpected items: %v", items)
+	}
+	if response["count"] != float64(3) {
+		t.Errorf("Expected count 3, got %v", response["count"])
+	}
+}
+
+func TestRenderFormData(t *testing.T) {
+	handler, _ := createTestHandler()
+
+	req := httptest.NewRequest("POST", "/login", strings.NewReader("user=john&remember=1"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	result := handler.processResponse("{{.Form.user}} {{.Body}}", req)
+	if result != "john user=john&remember=1" {
+		t.Errorf("Expected form value and raw body, got '%v'", result)
+	}
+}
+
+func TestRenderPlaceholdersNotExecuted(t *testing.T) {
+	handler, _ := createTestHandler()
+	req := httptest.NewRequest("GET", "/greet?name="+url.QueryEscape("{{.Headers}}"), nil)
+
+	result := handler.processResponse("Hi {name} at {{.Method}}", req)
+	if result != "Hi {{.Headers}} at GET" {
+		t.Errorf("Expected the query value back literally, got %v", result)
+	}
+	if len(handler.templates) != 1 {
+		t.Errorf("Expected only the configured string to be cached, got %d templates", len(handler.templates))
+	}
+}
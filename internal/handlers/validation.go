@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+	"github.com/walterfan/lazy-mock-server/internal/openapi"
+)
+
+// loadedSpec is a parsed OpenAPI document, its compiled paths and the
+// modification time of the file it was read from
+type loadedSpec struct {
+	doc     *openapi.Document
+	paths   *openapi.PathMatcher
+	modTime time.Time
+}
+
+// globalValidation returns the validation applied to every request, if any
+func (h *MockHandler) globalValidation() *config.Validation {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	if cfg := h.configManager.GetConfig(); cfg != nil {
+		return cfg.Validation
+	}
+	return nil
+}
+
+// validateRequest checks the request against the OpenAPI document of the
+// validation settings. It answers invalid requests itself and returns false;
+// requests for operations the document does not describe pass.
+func (h *MockHandler) validateRequest(w http.ResponseWriter, r *http.Request, validation *config.Validation) bool {
+	spec, err := h.getSpec(validation.SpecPath())
+	if err != nil {
+		h.logger.LogErrorWithRequest(err, r, "loading OpenAPI document for validation")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		if encErr := json.NewEncoder(w).Encode(map[string]string{"error": err.Error()}); encErr != nil {
+			h.logger.LogError(encErr, "encoding error response")
+		}
+		return false
+	}
+
+	operation := spec.paths.FindOperation(r.Method, r.URL.Path)
+	if operation == nil {
+		h.logger.LogDebug("%s %s is not described by %s, skipping validation", r.Method, r.URL.Path, validation.Spec)
+		return true
+	}
+
+	errors := spec.doc.ValidateRequest(operation, r, h.readBody(r))
+	if len(errors) == 0 {
+		return true
+	}
+
+	for _, validationErr := range errors {
+		h.logger.LogInfo("Invalid request %s %s: %s", r.Method, r.URL.Path, validationErr.Error())
+	}
+	h.writeValidationErrors(w, validation, operation, errors)
+	return false
+}
+
+// writeValidationErrors answers an invalid request with the configured status
+// code and body
+func (h *MockHandler) writeValidationErrors(w http.ResponseWriter, validation *config.Validation, operation *openapi.OperationMatch, errors []openapi.ValidationError) {
+	statusCode := validation.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusBadRequest
+	}
+
+	var body interface{} = map[string]interface{}{
+		"error":     "Request validation failed",
+		"operation": operation.Method + " " + operation.Path,
+		"errors":    errors,
+	}
+	if validation.Response != nil {
+		body = h.convertToJSONSafe(validation.Response)
+		if object, ok := body.(map[string]interface{}); ok {
+			object["errors"] = errors
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger.LogError(err, "encoding validation response")
+	}
+}
+
+// getSpec returns the loaded OpenAPI document, re-reading it when the file
+// changes. Relative paths are resolved against the configuration directory.
+func (h *MockHandler) getSpec(path string) (*loadedSpec, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(h.configManager.BaseDir(), path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenAPI document %s: %w", path, err)
+	}
+
+	h.patternMutex.Lock()
+	defer h.patternMutex.Unlock()
+
+	if spec, ok := h.specs[path]; ok && spec.modTime.Equal(info.ModTime()) {
+		return spec, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenAPI document %s: %w", path, err)
+	}
+	doc, err := openapi.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document %s: %w", path, err)
+	}
+	spec := &loadedSpec{doc: doc, paths: doc.CompilePaths(), modTime: info.ModTime()}
+	h.specs[path] = spec
+	return spec, nil
+}
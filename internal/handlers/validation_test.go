@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+const ordersSpec = `
+openapi: 3.0.3
+info: {title: Orders, version: 1.0.0}
+paths:
+  /api/orders:
+    post:
+      parameters:
+        - name: X-Tenant
+          in: header
+          required: true
+          schema: {type: string, enum: [acme, globex]}
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [sku, quantity]
+              properties:
+                sku: {type: string}
+                quantity: {type: integer, minimum: 1}
+      responses:
+        '201': {description: Created}
+`
+
+// createValidationHandler writes the orders spec next to the test
+// configuration and adds a route for it
+func createValidationHandler(t *testing.T, validation *config.Validation, routeValidation *config.Validation) *MockHandler {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "orders.yaml"), []byte(ordersSpec), 0644); err != nil {
+		t.Fatalf("Failed to write spec: %v", err)
+	}
+
+	handler, configManager := createTestHandler()
+	configManager.SetConfigPath(filepath.Join(dir, "mock.yaml"))
+	configManager.GetConfig().Validation = validation
+	configManager.AddRoute(config.Route{
+		Path: "/api/orders", Method: "POST", StatusCode: 201,
+		Response: map[string]interface{}{"id": 1}, Validation: routeValidation,
+	})
+	return handler
+}
+
+func postOrder(handler *MockHandler, tenant, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/orders", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if tenant != "" {
+		req.Header.Set("X-Tenant", tenant)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestGlobalValidation(t *testing.T) {
+	handler := createValidationHandler(t, &config.Validation{Spec: "orders.yaml"}, nil)
+
+	if w := postOrder(handler, "acme", `{"sku": "A-1", "quantity": 2}`); w.Code != 201 {
+		t.Fatalf("Expected a valid order to be answered with 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := postOrder(handler, "initech", `{"sku": "A-1", "quantity": 0}`)
+	if w.Code != 400 {
+		t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Error     string `json:"error"`
+		Operation string `json:"operation"`
+		Errors    []struct {
+			Location string `json:"location"`
+			Name     string `json:"name"`
+			Message  string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse validation response: %v", err)
+	}
+	if response.Operation != "POST /api/orders" || len(response.Errors) != 2 {
+		t.Fatalf("Unexpected validation response: %s", w.Body.String())
+	}
+	if e := response.Errors[0]; e.Location != "header" || e.Name != "X-Tenant" {
+		t.Errorf("Expected the tenant header error first, got %+v", e)
+	}
+	if e := response.Errors[1]; e.Location != "body" || e.Name != "/quantity" || e.Message != "must be at least 1" {
+		t.Errorf("Expected the quantity error, got %+v", e)
+	}
+
+	// Operations the document does not describe are not validated
+	if w := doRequest(handler, "GET", "/test/simple", ""); w.Code != 200 {
+		t.Errorf("Expected undocumented route to pass, got %d", w.Code)
+	}
+}
+
+func TestRouteValidation(t *testing.T) {
+	validation := &config.Validation{
+		Spec:       "orders.yaml",
+		StatusCode: 422,
+		Response:   map[interface{}]interface{}{"code": "INVALID_ORDER"},
+	}
+	handler := createValidationHandler(t, nil, validation)
+
+	w := postOrder(handler, "acme", `{"sku": "A-1"}`)
+	if w.Code != 422 {
+		t.Fatalf("Expected status 422, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `"code":"INVALID_ORDER"`) || !strings.Contains(body, `"name":"/quantity"`) {
+		t.Errorf("Expected the custom body with errors, got %s", body)
+	}
+}
+
+func TestValidationMissingSpec(t *testing.T) {
+	handler := createValidationHandler(t, &config.Validation{Spec: "missing.yaml"}, nil)
+
+	if w := postOrder(handler, "acme", `{"sku": "A-1", "quantity": 1}`); w.Code != 500 {
+		t.Errorf("Expected status 500 for a missing spec, got %d", w.Code)
+	}
+}
+
+func TestRouteValidationKeepsScenarioState(t *testing.T) {
+	handler := createValidationHandler(t, nil, nil)
+	route := config.Route{
+		Path: "/api/orders", Method: "POST", StatusCode: 201,
+		Validation: &config.Validation{Spec: "orders.yaml"}, Scenario: "checkout", NewState: "ORDERED",
+	}
+	if err := handler.configManager.UpdateRoute("/api/orders", "POST", route); err != nil {
+		t.Fatalf("Failed to update route: %v", err)
+	}
+
+	if w := postOrder(handler, "acme", `{"sku": "A-1"}`); w.Code != 400 {
+		t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if state := handler.scenarios.state("checkout"); state != config.ScenarioStarted {
+		t.Errorf("Expected an invalid request to leave the scenario alone, got %s", state)
+	}
+
+	if w := postOrder(handler, "acme", `{"sku": "A-1", "quantity": 1}`); w.Code != 201 {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if state := handler.scenarios.state("checkout"); state != "ORDERED" {
+		t.Errorf("Expected a valid request to advance the scenario, got %s", state)
+	}
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// wsUpgrader accepts connections from any origin, like the CORS headers of
+// the management API
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// hijackableWriter lets the upgrader take over connections whose response
+// writer is wrapped, e.g. by the logging middleware
+type hijackableWriter struct {
+	http.ResponseWriter
+}
+
+// Hijack takes over the connection of the wrapped response writer
+func (w hijackableWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// wsSession is an upgraded connection playing a route's script
+type wsSession struct {
+	h      *MockHandler
+	conn   *websocket.Conn
+	r      *http.Request
+	script *config.WebSocket
+	// data is the upgrade request exposed to message templates
+	data *RequestData
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	// writeMutex serializes writes, which the connection does not allow
+	// concurrently
+	writeMutex sync.Mutex
+}
+
+// serveWebSocket upgrades the connection and plays the route's script until
+// either side closes the connection
+func (h *MockHandler) serveWebSocket(w http.ResponseWriter, r *http.Request, route *config.Route) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Upgrade", "websocket")
+		w.WriteHeader(http.StatusUpgradeRequired)
+		if err := json.NewEncoder(w).Encode(map[string]string{"error": "WebSocket upgrade required"}); err != nil {
+			h.logger.LogError(err, "encoding error response")
+		}
+		return
+	}
+
+	if !h.waitRoute(w, r, route) {
+		return
+	}
+
+	// Read the request before the connection is taken over
+	data := h.newRequestData(r)
+
+	header := make(http.Header)
+	for key, value := range route.Headers {
+		header.Set(key, value)
+	}
+	conn, err := wsUpgrader.Upgrade(hijackableWriter{w}, r, header)
+	if err != nil {
+		// The upgrader has answered the request already
+		h.logger.LogErrorWithRequest(err, r, "upgrading WebSocket connection")
+		return
+	}
+	defer conn.Close()
+
+	h.logger.LogInfo("WebSocket connected: %s", r.URL.Path)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &wsSession{h: h, conn: conn, r: r, script: route.WebSocket, data: data, ctx: ctx, cancel: cancel}
+
+	go s.readLoop()
+	s.play()
+	<-ctx.Done()
+	h.logger.LogInfo("WebSocket disconnected: %s", r.URL.Path)
+}
+
+// play sends the on_connect messages and starts the periodic messages and
+// the scheduled close
+func (s *wsSession) play() {
+	for i := range s.script.OnConnect {
+		if !s.send(&s.script.OnConnect[i], nil) {
+			return
+		}
+	}
+	for i := range s.script.Periodic {
+		go s.repeat(&s.script.Periodic[i])
+	}
+	if s.script.Close != nil {
+		go s.close(s.script.Close)
+	}
+}
+
+// readLoop answers incoming messages until the connection is closed
+func (s *wsSession) readLoop() {
+	defer s.cancel()
+
+	for {
+		messageType, message, err := s.conn.ReadMessage()
+		if err != nil {
+			s.h.logger.LogDebug("WebSocket %s closed: %v", s.r.URL.Path, err)
+			return
+		}
+		s.h.logger.LogWebSocketMessage(s.r, "<-", messageType == websocket.BinaryMessage, message)
+
+		reply := s.findReply(message)
+		if reply == nil {
+			continue
+		}
+		for i := range reply.Messages {
+			if !s.send(&reply.Messages[i], message) {
+				return
+			}
+		}
+		if reply.Close != nil {
+			s.close(reply.Close)
+			return
+		}
+	}
+}
+
+// findReply returns the first reply whose conditions match the message
+func (s *wsSession) findReply(message []byte) *config.WebSocketReply {
+	for i := range s.script.Replies {
+		reply := &s.script.Replies[i]
+		if reply.Matches != "" {
+			regex, err := s.h.getRegex(reply.Matches)
+			if err != nil {
+				s.h.logger.LogError(err, "compiling WebSocket reply regex")
+				continue
+			}
+			if !regex.Match(message) {
+				continue
+			}
+		}
+		if reply.JSON != nil && !s.h.matchesJSON(reply.JSON, message) {
+			continue
+		}
+		return reply
+	}
+	return nil
+}
+
+// repeat sends a periodic message until the connection is closed or the
+// message has been sent Count times. Messages without a positive interval
+// are skipped, since the ticker cannot run them.
+func (s *wsSession) repeat(periodic *config.WebSocketPeriodic) {
+	if periodic.IntervalMs <= 0 {
+		s.h.logger.LogErrorWithRequest(fmt.Errorf("periodic message interval must be positive, got %d ms", periodic.IntervalMs), s.r, "starting WebSocket periodic message")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(periodic.IntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	for sent := 0; periodic.Count == 0 || sent < periodic.Count; sent++ {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !s.send(&periodic.WebSocketMessage, nil) {
+			return
+		}
+	}
+}
+
+// send renders and writes a message after its delay. Replies can refer to
+// the incoming message as {{.Body}} and {{.JSON}}.
+func (s *wsSession) send(message *config.WebSocketMessage, incoming []byte) bool {
+	if !s.sleep(time.Duration(message.DelayMs) * time.Millisecond) {
+		return false
+	}
+
+	messageType, payload, err := s.render(message, incoming)
+	if err != nil {
+		s.h.logger.LogErrorWithRequest(err, s.r, "rendering WebSocket message")
+		return true
+	}
+
+	s.writeMutex.Lock()
+	err = s.conn.WriteMessage(messageType, payload)
+	s.writeMutex.Unlock()
+	if err != nil {
+		s.h.logger.LogDebug("WebSocket %s write failed: %v", s.r.URL.Path, err)
+		s.cancel()
+		return false
+	}
+	s.h.logger.LogWebSocketMessage(s.r, "->", messageType == websocket.BinaryMessage, payload)
+	return true
+}
+
+// render returns the frame type and payload of a message
+func (s *wsSession) render(message *config.WebSocketMessage, incoming []byte) (int, []byte, error) {
+	if message.DataBase64 != "" {
+		payload, err := config.DecodeBase64(message.DataBase64)
+		return websocket.BinaryMessage, payload, err
+	}
+
+	data := *s.data
+	if incoming != nil {
+		data.Body = string(incoming)
+		data.JSON = nil
+		var parsed interface{}
+		if err := json.Unmarshal(incoming, &parsed); err == nil {
+			data.JSON = parsed
+		}
+	}
+	dataPtr := &data
+
+	switch v := s.h.renderValue(message.Data, s.r, &dataPtr).(type) {
+	case string:
+		return websocket.TextMessage, []byte(v), nil
+	case nil:
+		return websocket.TextMessage, nil, nil
+	default:
+		payload, err := json.Marshal(v)
+		return websocket.TextMessage, payload, err
+	}
+}
+
+// close sends a close frame after the configured delay and ends the session
+func (s *wsSession) close(close *config.WebSocketClose) {
+	if !s.sleep(time.Duration(close.AfterMs) * time.Millisecond) {
+		return
+	}
+
+	code := close.Code
+	if code == 0 {
+		code = websocket.CloseNormalClosure
+	}
+	s.writeMutex.Lock()
+	err := s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, close.Reason), time.Now().Add(time.Second))
+	s.writeMutex.Unlock()
+	if err != nil {
+		s.h.logger.LogDebug("WebSocket %s close failed: %v", s.r.URL.Path, err)
+	}
+
+	s.h.logger.LogInfo("WebSocket %s closed with code %d", s.r.URL.Path, code)
+	s.cancel()
+}
+
+// sleep waits for the duration unless the session ends first
+func (s *wsSession) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return s.ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// dialWebSocket connects to a path of the test server
+func dialWebSocket(t *testing.T, server *httptest.Server, path string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + path
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to %s: %v", path, err)
+	}
+	if resp.Header.Get("X-Mock") != "chat" {
+		t.Errorf("Expected route headers on the upgrade response, got %v", resp.Header)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	return conn
+}
+
+// readText reads the next message and fails unless it is the expected text
+func readText(t *testing.T, conn *websocket.Conn, want string) {
+	t.Helper()
+	messageType, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Expected %q, got error: %v", want, err)
+	}
+	if messageType != websocket.TextMessage || string(message) != want {
+		t.Errorf("Expected text %q, got type %d %q", want, messageType, message)
+	}
+}
+
+func newWebSocketServer(t *testing.T) *httptest.Server {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/chat/{room}", Method: "GET", StatusCode: 200,
+		Headers: map[string]string{"X-Mock": "chat"},
+		WebSocket: &config.WebSocket{
+			OnConnect: []config.WebSocketMessage{{Data: "welcome to {{.PathParams.room}}"}},
+			Replies: []config.WebSocketReply{
+				{Matches: "^ping$", Messages: []config.WebSocketMessage{{Data: "pong"}}},
+				{
+					JSON:     &config.BodyMatcher{PartialJSON: map[string]interface{}{"type": "order"}},
+					Messages: []config.WebSocketMessage{{Data: map[string]interface{}{"accepted": `{{jsonPath .JSON "$.id"}}`}}},
+				},
+				{Matches: "^bye$", Messages: []config.WebSocketMessage{{DataBase64: "AAE="}}, Close: &config.WebSocketClose{Code: 4000, Reason: "done"}},
+			},
+		},
+	})
+	configManager.AddRoute(config.Route{
+		Path: "/ticker", Method: "GET", StatusCode: 200,
+		Headers: map[string]string{"X-Mock": "chat"},
+		WebSocket: &config.WebSocket{
+			Periodic: []config.WebSocketPeriodic{{IntervalMs: 10, Count: 3, WebSocketMessage: config.WebSocketMessage{Data: "tick"}}},
+			Close:    &config.WebSocketClose{AfterMs: 100},
+		},
+	})
+
+	// The logging middleware wraps the response writer the upgrader takes over
+	server := httptest.NewServer(handler.logger.Middleware(handler))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWebSocketConversation(t *testing.T) {
+	server := newWebSocketServer(t)
+	conn := dialWebSocket(t, server, "/chat/lobby")
+
+	readText(t, conn, "welcome to lobby")
+
+	conn.WriteMessage(websocket.TextMessage, []byte("ping"))
+	readText(t, conn, "pong")
+
+	// Unmatched messages are ignored
+	conn.WriteMessage(websocket.TextMessage, []byte("hello?"))
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "order", "id": "A-7"}`))
+	readText(t, conn, `{"accepted":"A-7"}`)
+
+	conn.WriteMessage(websocket.TextMessage, []byte("bye"))
+	messageType, message, err := conn.ReadMessage()
+	if err != nil || messageType != websocket.BinaryMessage || string(message) != "\x00\x01" {
+		t.Errorf("Expected the binary reply, got type %d %v %v", messageType, message, err)
+	}
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, 4000) {
+		t.Errorf("Expected close code 4000, got %v", err)
+	}
+}
+
+func TestWebSocketPeriodic(t *testing.T) {
+	server := newWebSocketServer(t)
+	conn := dialWebSocket(t, server, "/ticker")
+
+	for i := 0; i < 3; i++ {
+		readText(t, conn, "tick")
+	}
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("Expected a normal close after the ticks, got %v", err)
+	}
+}
+
+func TestWebSocketInvalidPeriodic(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/broken", Method: "GET", StatusCode: 200,
+		Headers: map[string]string{"X-Mock": "chat"},
+		WebSocket: &config.WebSocket{
+			OnConnect: []config.WebSocketMessage{{Data: "hello"}},
+			Periodic:  []config.WebSocketPeriodic{{IntervalMs: 0, WebSocketMessage: config.WebSocketMessage{Data: "hi"}}},
+		},
+	})
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	// The zero interval is skipped instead of crashing the server
+	conn := dialWebSocket(t, server, "/broken")
+	readText(t, conn, "hello")
+}
+
+func TestWebSocketRequiresUpgrade(t *testing.T) {
+	server := newWebSocketServer(t)
+	resp, err := http.Get(server.URL + "/chat/lobby")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("Expected 426 for a plain request, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+	"github.com/walterfan/lazy-mock-server/internal/xmldoc"
+)
+
+// soapAction returns the SOAP action of a request: the SOAPAction header of
+// SOAP 1.1 without its quotes, or the action parameter of the SOAP 1.2
+// content type
+func soapAction(r *http.Request) (string, bool) {
+	if values := r.Header.Values("SOAPAction"); len(values) > 0 {
+		return strings.Trim(strings.TrimSpace(values[0]), `"`), true
+	}
+	if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil {
+		if action, ok := params["action"]; ok {
+			return action, true
+		}
+	}
+	return "", false
+}
+
+// matchesSOAPAction checks the SOAP action of a request
+func matchesSOAPAction(expected string, r *http.Request) bool {
+	action, ok := soapAction(r)
+	return ok && action == expected
+}
+
+// matchesXML checks if an XML document satisfies the XPath checks of a body
+// matcher
+func (h *MockHandler) matchesXML(matcher *config.BodyMatcher, data []byte) bool {
+	doc, err := xmldoc.Parse(data)
+	if err != nil {
+		return false
+	}
+
+	for _, expr := range matcher.XPath {
+		compiled, err := h.getXPath(expr, matcher.Namespaces)
+		if err != nil {
+			h.logger.LogError(err, "compiling body XPath")
+			return false
+		}
+		if !compiled.Match(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+// explainXML evaluates the XPath checks of a body matcher
+func (h *MockHandler) explainXML(matcher *config.BodyMatcher, data []byte) (int, []string) {
+	checks := len(matcher.XPath)
+	doc, err := xmldoc.Parse(data)
+	if err != nil {
+		return checks, []string{"body expected XML got an invalid or empty document"}
+	}
+
+	var failed []string
+	for _, expr := range matcher.XPath {
+		compiled, err := h.getXPath(expr, matcher.Namespaces)
+		if err != nil || !compiled.Match(doc) {
+			failed = append(failed, fmt.Sprintf("body expected %s", expr))
+		}
+	}
+	return checks, failed
+}
+
+// getXPath returns the compiled XPath expression, caching it for subsequent
+// requests. The cache key includes the namespace bindings, which give the
+// expression its meaning.
+func (h *MockHandler) getXPath(expr string, namespaces map[string]string) (*xmldoc.Expr, error) {
+	key := expr
+	for _, prefix := range config.SortedKeys(namespaces) {
+		key += "\x00" + prefix + "=" + namespaces[prefix]
+	}
+
+	h.patternMutex.Lock()
+	defer h.patternMutex.Unlock()
+
+	if compiled, ok := h.xpaths[key]; ok {
+		return compiled, nil
+	}
+
+	compiled, err := xmldoc.Compile(expr, namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	h.xpaths[key] = compiled
+	return compiled, nil
+}
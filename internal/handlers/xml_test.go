@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+const getBalanceRequest = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:b="urn:billing">
+  <soap:Body>
+    <b:GetBalance><b:AccountId>%s</b:AccountId></b:GetBalance>
+  </soap:Body>
+</soap:Envelope>`
+
+func addSOAPRoutes(configManager *config.Manager) {
+	envelope := func(body map[interface{}]interface{}) map[interface{}]interface{} {
+		return map[interface{}]interface{}{
+			"soap:Envelope": map[interface{}]interface{}{
+				"@xmlns:soap": "http://schemas.xmlsoap.org/soap/envelope/",
+				"soap:Body":   body,
+			},
+		}
+	}
+	configManager.AddRoute(config.Route{
+		Path: "/billing", Method: "POST", StatusCode: 200, ContentType: "text/xml; charset=utf-8",
+		Match: &config.RequestMatch{
+			SOAPAction: "urn:GetBalance",
+			Body: &config.BodyMatcher{
+				XPath:      []string{"//b:GetBalance/b:AccountId = '42'"},
+				Namespaces: map[string]string{"b": "urn:billing"},
+			},
+		},
+		Response: envelope(map[interface{}]interface{}{
+			"b:GetBalanceResponse": map[interface{}]interface{}{
+				"@xmlns:b":  "urn:billing",
+				"b:Balance": map[interface{}]interface{}{"@currency": "EUR", "#text": "12.50"},
+			},
+		}),
+	})
+	configManager.AddRoute(config.Route{
+		Path: "/billing", Method: "POST", StatusCode: 500, ContentType: "text/xml",
+		Match: &config.RequestMatch{SOAPAction: "urn:GetBalance"},
+		Response: envelope(map[interface{}]interface{}{
+			"soap:Fault": map[interface{}]interface{}{
+				"faultcode":   "soap:Client",
+				"faultstring": `Unknown account {{xpath .Body "//AccountId"}}`,
+			},
+		}),
+	})
+}
+
+// doSOAPRequest posts a GetBalance request with a SOAP 1.1 action
+func doSOAPRequest(handler *MockHandler, action, accountID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/billing", strings.NewReader(strings.Replace(getBalanceRequest, "%s", accountID, 1)))
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set("SOAPAction", `"`+action+`"`)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestSOAPRoutes(t *testing.T) {
+	handler, configManager := createTestHandler()
+	addSOAPRoutes(configManager)
+
+	w := doSOAPRequest(handler, "urn:GetBalance", "42")
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` +
+		`<b:GetBalanceResponse xmlns:b="urn:billing"><b:Balance currency="EUR">12.50</b:Balance></b:GetBalanceResponse>` +
+		`</soap:Body></soap:Envelope>`
+	if w.Code != 200 || w.Body.String() != expected {
+		t.Errorf("Unexpected response %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "text/xml; charset=utf-8" {
+		t.Errorf("Expected the configured content type, got %s", w.Header().Get("Content-Type"))
+	}
+
+	w = doSOAPRequest(handler, "urn:GetBalance", "7")
+	if w.Code != 500 || !strings.Contains(w.Body.String(), "<faultstring>Unknown account 7</faultstring>") {
+		t.Errorf("Expected the fault for other accounts, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := doSOAPRequest(handler, "urn:GetInvoices", "42"); w.Code != 404 {
+		t.Errorf("Expected 404 for another action, got %d", w.Code)
+	}
+}
+
+func TestSOAP12Action(t *testing.T) {
+	handler, configManager := createTestHandler()
+	addSOAPRoutes(configManager)
+
+	req := httptest.NewRequest("POST", "/billing", strings.NewReader(strings.Replace(getBalanceRequest, "%s", "42", 1)))
+	req.Header.Set("Content-Type", `application/soap+xml; charset=utf-8; action="urn:GetBalance"`)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != 200 || !strings.Contains(w.Body.String(), "12.50") {
+		t.Errorf("Expected the action from the content type to match, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSOAPNearMiss(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/billing", Method: "POST", StatusCode: 200, ContentType: "text/xml", Response: "<ok/>",
+		Match: &config.RequestMatch{
+			SOAPAction: "urn:GetBalance",
+			Body:       &config.BodyMatcher{XPath: []string{"//AccountId = '42'", "//GetBalance"}},
+		},
+	})
+
+	nearMisses := getNearMisses(t, handler, "POST", "/billing", strings.Replace(getBalanceRequest, "%s", "7", 1))
+	if len(nearMisses) != 1 {
+		t.Fatalf("Expected one near miss, got %+v", nearMisses)
+	}
+	explanation := nearMisses[0].Explanation
+	for _, expected := range []string{"soap action expected 'urn:GetBalance' got nothing", "body expected //AccountId = '42'"} {
+		if !strings.Contains(explanation, expected) {
+			t.Errorf("Expected the explanation to contain %q, got %s", expected, explanation)
+		}
+	}
+	if strings.Contains(explanation, "//GetBalance") {
+		t.Errorf("Expected only failed checks in the explanation, got %s", explanation)
+	}
+
+	nearMisses = getNearMisses(t, handler, "POST", "/billing", `{"not": "xml"}`)
+	if len(nearMisses) != 1 || !strings.Contains(nearMisses[0].Explanation, "body expected XML") {
+		t.Errorf("Expected an invalid XML explanation, got %+v", nearMisses)
+	}
+}
+
+func TestXMLResponseOrder(t *testing.T) {
+	handler, configManager := createTestHandler()
+	err := configManager.LoadFromBytes([]byte(`
+routes:
+  - path: "/billing"
+    method: "POST"
+    content_type: "text/xml"
+    response:
+      soap:Envelope:
+        "@xmlns:soap": "http://schemas.xmlsoap.org/soap/envelope/"
+        soap:Header:
+          Session: "{{.Method}}"
+        soap:Body:
+          Total: "12.50"
+          Currency: EUR
+`))
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	w := doRequest(handler, "POST", "/billing", "")
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">` +
+		`<soap:Header><Session>POST</Session></soap:Header>` +
+		`<soap:Body><Total>12.50</Total><Currency>EUR</Currency></soap:Body></soap:Envelope>`
+	if w.Body.String() != expected {
+		t.Errorf("Expected elements in their configured order, got %s", w.Body.String())
+	}
+
+	// The order survives copies of the configuration
+	configManager.SetConfig(configManager.Clone())
+	if w := doRequest(handler, "POST", "/billing", ""); w.Body.String() != expected {
+		t.Errorf("Expected the order to survive a clone, got %s", w.Body.String())
+	}
+}
+
+func TestXMLResponseFallback(t *testing.T) {
+	handler, configManager := createTestHandler()
+	configManager.AddRoute(config.Route{
+		Path: "/xml/raw", Method: "GET", StatusCode: 200, ContentType: "application/xml", Response: "<raw>{{.Method}}</raw>",
+	})
+
+	if w := doRequest(handler, "GET", "/xml/raw", ""); w.Body.String() != "<raw>GET</raw>" {
+		t.Errorf("Expected string responses to be sent as they are, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,189 @@
+package importers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// harMethods are the request methods routes can match
+var harMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "DELETE": true,
+	"PATCH": true, "HEAD": true, "OPTIONS": true,
+}
+
+// harFile is an HTTP Archive as saved by browsers and proxies
+type harFile struct {
+	Log *struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+// harEntry is a captured request/response pair
+type harEntry struct {
+	Request struct {
+		Method   string `json:"method"`
+		URL      string `json:"url"`
+		PostData *struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status  int      `json:"status"`
+		Headers []header `json:"headers"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+// FromHAR generates routes from the entries of a HAR file. Entries with the
+// same method, path, query and body become a single route answering with
+// the captured responses in order, so replaying the capture reproduces it.
+// Aborted requests and non-HTTP URLs are skipped; binary responses are kept
+// base64 encoded.
+func FromHAR(data []byte) ([]config.Route, error) {
+	var archive harFile
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, fmt.Errorf("invalid HAR file: %w", err)
+	}
+	if archive.Log == nil {
+		return nil, fmt.Errorf("invalid HAR file: missing log")
+	}
+
+	var routes []config.Route
+	indexes := make(map[string]int)
+	for i := range archive.Log.Entries {
+		entry := &archive.Log.Entries[i]
+		method := strings.ToUpper(entry.Request.Method)
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !harMethods[method] || entry.Response.Status == 0 {
+			continue
+		}
+		body, binary, ok := harResponseBody(entry)
+		if !ok {
+			continue
+		}
+
+		headers, contentType := responseHeaders(entry.Response.Headers)
+		if contentType == "" {
+			contentType = entry.Response.Content.MimeType
+		}
+		variant := config.ResponseVariant{
+			StatusCode:  entry.Response.Status,
+			ContentType: contentType,
+			Headers:     headers,
+		}
+		if binary != nil {
+			variant.BodyBase64 = base64.StdEncoding.EncodeToString(binary)
+		} else {
+			variant.Response = responseBody(contentType, body)
+		}
+
+		path := u.Path
+		if path == "" {
+			path = "/"
+		}
+		requestBody := ""
+		if entry.Request.PostData != nil {
+			requestBody = entry.Request.PostData.Text
+		}
+
+		requestDocument, _ := jsonBody(requestBody)
+		key := config.RequestKey(method, path, u.Query(), requestDocument)
+		if index, ok := indexes[key]; ok {
+			addHARResponse(&routes[index], variant)
+			continue
+		}
+		indexes[key] = len(routes)
+
+		route := config.Route{
+			Path:        path,
+			Method:      method,
+			StatusCode:  variant.StatusCode,
+			ContentType: variant.ContentType,
+			Response:    variant.Response,
+			BodyBase64:  variant.BodyBase64,
+			Headers:     variant.Headers,
+		}
+		if query := u.Query(); len(query) > 0 {
+			route.Parameters = make(map[string]string, len(query))
+			for name := range query {
+				route.Parameters[name] = query.Get(name)
+			}
+		}
+		if requestDocument != nil {
+			route.Match = &config.RequestMatch{Body: &config.BodyMatcher{EqualToJSON: requestDocument}}
+		}
+		routes = append(routes, route)
+	}
+
+	// Routes without query or body conditions would shadow those with some
+	sort.SliceStable(routes, func(i, j int) bool {
+		return harConditions(routes[i]) > harConditions(routes[j])
+	})
+	return routes, nil
+}
+
+// harConditions counts the request conditions of a route
+func harConditions(route config.Route) int {
+	count := len(route.Parameters)
+	if route.Match != nil {
+		count++
+	}
+	return count
+}
+
+// addHARResponse adds a further captured response to a route, turning the
+// route's own response into the first entry of its responses list
+func addHARResponse(route *config.Route, variant config.ResponseVariant) {
+	if len(route.Responses) == 0 {
+		first := config.ResponseVariant{
+			StatusCode:  route.StatusCode,
+			ContentType: route.ContentType,
+			Response:    route.Response,
+			BodyBase64:  route.BodyBase64,
+			Headers:     route.Headers,
+		}
+		if harVariantsEqual(first, variant) {
+			return
+		}
+		route.Responses = []config.ResponseVariant{first}
+		route.ResponseMode = config.ResponseModeStick
+		route.ContentType, route.Response, route.BodyBase64, route.Headers = "", nil, "", nil
+	}
+	route.Responses = append(route.Responses, variant)
+}
+
+// harVariantsEqual reports whether two captured responses are the same
+func harVariantsEqual(a, b config.ResponseVariant) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
+
+// harResponseBody returns the text of the captured response, or its bytes
+// when base64 encoded content is binary
+func harResponseBody(entry *harEntry) (string, []byte, bool) {
+	content := entry.Response.Content
+	if content.Encoding != "base64" {
+		return content.Text, nil, true
+	}
+	decoded, err := base64.StdEncoding.DecodeString(content.Text)
+	if err != nil {
+		return "", nil, false
+	}
+	if !utf8.Valid(decoded) {
+		return "", decoded, true
+	}
+	return string(decoded), nil, true
+}
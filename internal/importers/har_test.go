@@ -0,0 +1,98 @@
+package importers
+
+import (
+	"testing"
+)
+
+const capture = `{
+  "log": {
+    "version": "1.2",
+    "entries": [
+      {
+        "request": {"method": "GET", "url": "https://app.example.com/api/cart"},
+        "response": {
+          "status": 200,
+          "headers": [{"name": "content-type", "value": "application/json"}, {"name": "content-encoding", "value": "gzip"}],
+          "content": {"mimeType": "application/json", "text": "{\"items\": []}"}
+        }
+      },
+      {
+        "request": {
+          "method": "POST", "url": "https://app.example.com/api/cart/items",
+          "postData": {"mimeType": "application/json", "text": "{\"sku\": \"A-1\", \"qty\": 1}"}
+        },
+        "response": {"status": 201, "headers": [], "content": {"mimeType": "application/json", "text": "{\"ok\": true}"}}
+      },
+      {
+        "request": {"method": "GET", "url": "https://app.example.com/api/cart"},
+        "response": {
+          "status": 200,
+          "headers": [{"name": "Content-Type", "value": "application/json"}],
+          "content": {"mimeType": "application/json", "text": "{\"items\": [\"A-1\"]}"}
+        }
+      },
+      {
+        "request": {"method": "GET", "url": "https://app.example.com/api/cart?coupon=SAVE"},
+        "response": {"status": 200, "headers": [], "content": {"mimeType": "text/plain", "encoding": "base64", "text": "ZGlzY291bnRlZA=="}}
+      },
+      {
+        "request": {"method": "GET", "url": "https://app.example.com/logo.png"},
+        "response": {"status": 200, "headers": [], "content": {"mimeType": "image/png", "encoding": "base64", "text": "iVBORw0KGgo="}}
+      },
+      {
+        "request": {"method": "GET", "url": "https://app.example.com/api/blocked"},
+        "response": {"status": 0, "headers": [], "content": {}}
+      },
+      {
+        "request": {"method": "GET", "url": "data:text/plain,hello"},
+        "response": {"status": 200, "headers": [], "content": {"text": "hello"}}
+      }
+    ]
+  }
+}`
+
+func TestFromHAR(t *testing.T) {
+	routes, err := FromHAR([]byte(capture))
+	if err != nil {
+		t.Fatalf("Failed to import HAR: %v", err)
+	}
+	if len(routes) != 4 {
+		t.Fatalf("Expected 4 routes, got %d: %+v", len(routes), routes)
+	}
+
+	// Routes with conditions come first so they are not shadowed
+	create, coupon, cart, logo := routes[0], routes[1], routes[2], routes[3]
+	if create.Method != "POST" || create.Path != "/api/cart/items" || create.Match == nil || create.Match.Body == nil {
+		t.Fatalf("Expected the POST route with a body condition first, got %+v", create)
+	}
+	if body, ok := create.Match.Body.EqualToJSON.(map[string]interface{}); !ok || body["sku"] != "A-1" {
+		t.Errorf("Expected the captured request body, got %#v", create.Match.Body.EqualToJSON)
+	}
+
+	if coupon.Parameters["coupon"] != "SAVE" || coupon.Response != "discounted" {
+		t.Errorf("Expected the decoded coupon response, got %+v", coupon)
+	}
+
+	if cart.Path != "/api/cart" || len(cart.Responses) != 2 || cart.ResponseMode != "stick" {
+		t.Fatalf("Expected the repeated request to answer both responses in order, got %+v", cart)
+	}
+	first, ok := cart.Responses[0].Response.(map[string]interface{})
+	if !ok || len(first["items"].([]interface{})) != 0 {
+		t.Errorf("Expected the empty cart first, got %#v", cart.Responses[0].Response)
+	}
+	if cart.Responses[0].ContentType != "application/json" || cart.Responses[0].Headers != nil {
+		t.Errorf("Expected skipped headers to be dropped, got %+v", cart.Responses[0])
+	}
+
+	if logo.Path != "/logo.png" || logo.Response != nil || logo.BodyBase64 != "iVBORw0KGgo=" {
+		t.Errorf("Expected the binary response to be kept base64 encoded, got %+v", logo)
+	}
+}
+
+func TestFromHARErrors(t *testing.T) {
+	for _, input := range []string{`[`, `{"entries": []}`} {
+		if _, err := FromHAR([]byte(input)); err == nil {
+			t.Errorf("Expected error for %s", input)
+		}
+	}
+}
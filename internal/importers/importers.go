@@ -0,0 +1,77 @@
+// Package importers converts API collections and traffic captures, such as
+// Postman collections and HAR files, into routes
+package importers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// header is a name/value pair as found in collections and captures
+type header struct {
+	Name     string `json:"name"`
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// name returns the header name; Postman calls it key, HAR calls it name
+func (h header) name() string {
+	if h.Key != "" {
+		return h.Key
+	}
+	return h.Name
+}
+
+// responseHeaders converts the headers worth replaying to a route's headers
+// and returns the content type separately
+func responseHeaders(headers []header) (map[string]string, string) {
+	var result map[string]string
+	contentType := ""
+	for _, h := range headers {
+		name := http.CanonicalHeaderKey(h.name())
+		if h.Disabled || name == "" || strings.HasPrefix(name, ":") {
+			continue
+		}
+		if name == "Content-Type" {
+			contentType = h.Value
+		}
+		if config.IsPerResponseHeader(name) {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]string)
+		}
+		if _, ok := result[name]; !ok {
+			result[name] = h.Value
+		}
+	}
+	return result, contentType
+}
+
+// responseBody returns JSON bodies as documents and everything else as text
+func responseBody(contentType, body string) interface{} {
+	if config.IsJSONContentType(contentType) {
+		var document interface{}
+		if err := json.Unmarshal([]byte(body), &document); err == nil {
+			return document
+		}
+	}
+	return body
+}
+
+// jsonBody decodes a request body that is a JSON object or array
+func jsonBody(body string) (interface{}, bool) {
+	trimmed := strings.TrimSpace(body)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil, false
+	}
+	var document interface{}
+	if err := json.Unmarshal([]byte(trimmed), &document); err != nil {
+		return nil, false
+	}
+	return document, true
+}
@@ -0,0 +1,279 @@
+package importers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// ResponseNameHeader selects one of several saved examples of a Postman
+// request, like the header of the same name understood by Postman's mock servers
+const ResponseNameHeader = "X-Mock-Response-Name"
+
+// postmanVariable matches {{name}} variable references
+var postmanVariable = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// previewLanguages maps Postman's preview language to a content type for
+// examples that do not save their Content-Type header
+var previewLanguages = map[string]string{
+	"json": "application/json",
+	"xml":  "application/xml",
+	"html": "text/html",
+	"text": "text/plain",
+}
+
+// postmanCollection is a Postman v2.0/v2.1 collection
+type postmanCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item []postmanItem `json:"item"`
+}
+
+// postmanItem is a request or a folder of items
+type postmanItem struct {
+	Name     string            `json:"name"`
+	Item     []postmanItem     `json:"item"`
+	Request  *postmanRequest   `json:"request"`
+	Response []postmanResponse `json:"response"`
+}
+
+// postmanRequest is a saved request; collections may shorten it to a URL
+type postmanRequest struct {
+	Method string     `json:"method"`
+	Header []header   `json:"header"`
+	URL    postmanURL `json:"url"`
+}
+
+// UnmarshalJSON accepts a request object or a plain URL
+func (r *postmanRequest) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*r = postmanRequest{Method: http.MethodGet, URL: postmanURL{Raw: raw}}
+		return nil
+	}
+	type plain postmanRequest
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// postmanURL is a request URL; collections may shorten it to its raw form
+type postmanURL struct {
+	Raw   string      `json:"raw"`
+	Path  interface{} `json:"path"`
+	Query []header    `json:"query"`
+}
+
+// UnmarshalJSON accepts a URL object or a plain URL
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*u = postmanURL{Raw: raw}
+		return nil
+	}
+	type plain postmanURL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+// postmanResponse is an example response saved with a request
+type postmanResponse struct {
+	Name            string          `json:"name"`
+	OriginalRequest *postmanRequest `json:"originalRequest"`
+	Code            int             `json:"code"`
+	Header          []header        `json:"header"`
+	Body            string          `json:"body"`
+	PreviewLanguage string          `json:"_postman_previewlanguage"`
+}
+
+// FromPostman generates routes from a Postman v2.0 or v2.1 collection. Each
+// saved example becomes a route answering with the example response; requests
+// without examples answer 200 with an empty body. When several examples share
+// a method, path and query, the first one is the default and the others are
+// selected with the X-Mock-Response-Name header.
+func FromPostman(data []byte) ([]config.Route, error) {
+	var collection postmanCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("invalid Postman collection: %w", err)
+	}
+	if collection.Item == nil {
+		return nil, fmt.Errorf("invalid Postman collection: no items found")
+	}
+	if schema := collection.Info.Schema; schema != "" && !strings.Contains(schema, "v2.") {
+		return nil, fmt.Errorf("unsupported Postman collection schema %s, expected v2.0 or v2.1", schema)
+	}
+
+	var routes []config.Route
+	if err := collectPostmanRoutes(collection.Item, &routes); err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+// collectPostmanRoutes appends the routes of the items and their folders
+func collectPostmanRoutes(items []postmanItem, routes *[]config.Route) error {
+	for _, item := range items {
+		if item.Request == nil {
+			if err := collectPostmanRoutes(item.Item, routes); err != nil {
+				return err
+			}
+			continue
+		}
+		itemRoutes, err := postmanItemRoutes(item)
+		if err != nil {
+			return fmt.Errorf("%s: %w", item.Name, err)
+		}
+		*routes = append(*routes, itemRoutes...)
+	}
+	return nil
+}
+
+// postmanItemRoutes generates the routes of a request and its examples
+func postmanItemRoutes(item postmanItem) ([]config.Route, error) {
+	if len(item.Response) == 0 {
+		route, err := postmanRoute(item.Request)
+		if err != nil {
+			return nil, err
+		}
+		route.StatusCode = http.StatusOK
+		return []config.Route{route}, nil
+	}
+
+	var selected, defaults []config.Route
+	seen := make(map[string]bool)
+	for _, example := range item.Response {
+		request := example.OriginalRequest
+		if request == nil {
+			request = item.Request
+		}
+		route, err := postmanRoute(request)
+		if err != nil {
+			return nil, err
+		}
+
+		route.StatusCode = example.Code
+		if route.StatusCode == 0 {
+			route.StatusCode = http.StatusOK
+		}
+		route.Headers, route.ContentType = responseHeaders(example.Header)
+		if route.ContentType == "" {
+			route.ContentType = previewLanguages[example.PreviewLanguage]
+		}
+		route.Response = responseBody(route.ContentType, example.Body)
+
+		key := route.Method + " " + route.Path + "?" + toValues(route.Parameters).Encode()
+		if seen[key] && example.Name != "" {
+			route.Match = &config.RequestMatch{Headers: map[string]config.HeaderMatcher{
+				ResponseNameHeader: {Equals: example.Name},
+			}}
+			selected = append(selected, route)
+			continue
+		}
+		seen[key] = true
+		defaults = append(defaults, route)
+	}
+
+	// Routes without query parameters would shadow those requiring some
+	sort.SliceStable(defaults, func(i, j int) bool {
+		return len(defaults[i].Parameters) > len(defaults[j].Parameters)
+	})
+	return append(selected, defaults...), nil
+}
+
+// postmanRoute creates a route matching the request's method, path and query
+func postmanRoute(request *postmanRequest) (config.Route, error) {
+	method := strings.ToUpper(request.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	route := config.Route{Method: method, Path: postmanPath(request.URL)}
+
+	query := request.URL.Query
+	if query == nil {
+		if idx := strings.Index(request.URL.Raw, "?"); idx >= 0 {
+			values, err := url.ParseQuery(strings.SplitN(request.URL.Raw[idx+1:], "#", 2)[0])
+			if err != nil {
+				return route, fmt.Errorf("invalid query in %s: %w", request.URL.Raw, err)
+			}
+			for name := range values {
+				query = append(query, header{Key: name, Value: values.Get(name)})
+			}
+		}
+	}
+	for _, parameter := range query {
+		// Values taken from variables can't be known in advance
+		if parameter.Disabled || parameter.name() == "" || strings.Contains(parameter.Value, "{{") {
+			continue
+		}
+		if route.Parameters == nil {
+			route.Parameters = make(map[string]string)
+		}
+		route.Parameters[parameter.name()] = parameter.Value
+	}
+	return route, nil
+}
+
+// postmanPath converts a Postman URL to a route path. Path variables such as
+// :id and {{id}} become {id} parameters.
+func postmanPath(u postmanURL) string {
+	var segments []string
+	switch path := u.Path.(type) {
+	case string:
+		segments = strings.Split(strings.TrimPrefix(path, "/"), "/")
+	case []interface{}:
+		for _, segment := range path {
+			switch s := segment.(type) {
+			case string:
+				segments = append(segments, s)
+			case map[string]interface{}:
+				segments = append(segments, fmt.Sprint(s["value"]))
+			}
+		}
+	default:
+		raw := strings.SplitN(strings.SplitN(u.Raw, "?", 2)[0], "#", 2)[0]
+		// Drop the scheme and host, which are often a {{baseUrl}} variable
+		if idx := strings.Index(raw, "://"); idx >= 0 {
+			raw = raw[idx+3:]
+		}
+		if idx := strings.Index(raw, "/"); idx >= 0 {
+			raw = raw[idx:]
+		} else {
+			raw = ""
+		}
+		segments = strings.Split(strings.TrimPrefix(raw, "/"), "/")
+	}
+
+	seen := make(map[string]int)
+	param := func(name string) string {
+		seen[name]++
+		if seen[name] > 1 {
+			name = fmt.Sprintf("%s%d", name, seen[name])
+		}
+		return "{" + name + "}"
+	}
+
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") && len(segment) > 1 {
+			segments[i] = param(segment[1:])
+			continue
+		}
+		segments[i] = postmanVariable.ReplaceAllStringFunc(segment, func(match string) string {
+			return param(postmanVariable.FindStringSubmatch(match)[1])
+		})
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// toValues converts route parameters to url.Values for building keys
+func toValues(parameters map[string]string) url.Values {
+	values := make(url.Values, len(parameters))
+	for name, value := range parameters {
+		values[name] = []string{value}
+	}
+	return values
+}
@@ -0,0 +1,140 @@
+package importers
+
+import (
+	"testing"
+)
+
+const collection = `{
+  "info": {
+    "name": "Users",
+    "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+  },
+  "item": [
+    {
+      "name": "users",
+      "item": [
+        {
+          "name": "Get user",
+          "request": {
+            "method": "GET",
+            "url": {"raw": "{{baseUrl}}/api/users/:id", "host": ["{{baseUrl}}"], "path": ["api", "users", ":id"]}
+          },
+          "response": [
+            {
+              "name": "Found",
+              "code": 200,
+              "header": [
+                {"key": "Content-Type", "value": "application/json"},
+                {"key": "Content-Length", "value": "27"},
+                {"key": "X-Request-Id", "value": "abc"}
+              ],
+              "body": "{\"id\": 1, \"name\": \"Alice\"}"
+            },
+            {
+              "name": "Missing",
+              "code": 404,
+              "_postman_previewlanguage": "json",
+              "body": "{\"error\": \"not found\"}"
+            }
+          ]
+        },
+        {
+          "name": "Search users",
+          "request": {
+            "method": "GET",
+            "url": "{{baseUrl}}/api/users?q=al&token={{token}}"
+          },
+          "response": [
+            {
+              "name": "Results",
+              "originalRequest": {"method": "GET", "url": "{{baseUrl}}/api/users"},
+              "code": 200,
+              "_postman_previewlanguage": "text",
+              "body": "everyone"
+            },
+            {
+              "name": "Page 2",
+              "originalRequest": {
+                "method": "GET",
+                "url": {"raw": "{{baseUrl}}/api/users?page=2", "path": ["api", "users"], "query": [{"key": "page", "value": "2"}, {"key": "debug", "value": "1", "disabled": true}]}
+              },
+              "code": 200,
+              "body": "second page"
+            }
+          ]
+        }
+      ]
+    },
+    {
+      "name": "Create order",
+      "request": "https://shop.example.com/orders/{{orderId}}/items"
+    }
+  ]
+}`
+
+func TestFromPostman(t *testing.T) {
+	routes, err := FromPostman([]byte(collection))
+	if err != nil {
+		t.Fatalf("Failed to import collection: %v", err)
+	}
+
+	want := []struct {
+		method string
+		path   string
+		status int
+		header string
+		params int
+	}{
+		{"GET", "/api/users/{id}", 404, "Missing", 0},
+		{"GET", "/api/users/{id}", 200, "", 0},
+		{"GET", "/api/users", 200, "", 1},
+		{"GET", "/api/users", 200, "", 0},
+		{"GET", "/orders/{orderId}/items", 200, "", 0},
+	}
+	if len(routes) != len(want) {
+		t.Fatalf("Expected %d routes, got %d: %+v", len(want), len(routes), routes)
+	}
+
+	for i, w := range want {
+		route := routes[i]
+		if route.Method != w.method || route.Path != w.path || route.StatusCode != w.status || len(route.Parameters) != w.params {
+			t.Errorf("Route %d: expected %s %s %d with %d parameters, got %s %s %d %v",
+				i, w.method, w.path, w.status, w.params, route.Method, route.Path, route.StatusCode, route.Parameters)
+		}
+		header := ""
+		if route.Match != nil {
+			header = route.Match.Headers[ResponseNameHeader].Equals
+		}
+		if header != w.header {
+			t.Errorf("Route %d: expected response name %q, got %q", i, w.header, header)
+		}
+	}
+
+	found := routes[1]
+	if body, ok := found.Response.(map[string]interface{}); !ok || body["name"] != "Alice" {
+		t.Errorf("Expected the JSON example as document, got %#v", found.Response)
+	}
+	if found.ContentType != "application/json" || found.Headers["X-Request-Id"] != "abc" || found.Headers["Content-Length"] != "" {
+		t.Errorf("Unexpected headers: %s %v", found.ContentType, found.Headers)
+	}
+	if routes[0].ContentType != "application/json" {
+		t.Errorf("Expected the content type from the preview language, got %q", routes[0].ContentType)
+	}
+	if routes[2].Parameters["page"] != "2" {
+		t.Errorf("Expected page=2 parameter, got %v", routes[2].Parameters)
+	}
+}
+
+func TestFromPostmanErrors(t *testing.T) {
+	tests := []string{
+		`not json`,
+		`{"info": {"name": "empty"}}`,
+		`{"info": {"schema": "https://schema.getpostman.com/json/collection/v1.0.0/collection.json"}, "item": []}`,
+	}
+
+	for _, input := range tests {
+		if _, err := FromPostman([]byte(input)); err == nil {
+			t.Errorf("Expected error for %s", input)
+		}
+	}
+}
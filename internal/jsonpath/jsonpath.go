@@ -0,0 +1,198 @@
+// Package jsonpath implements the subset of JSONPath used for request
+// matching and response templating: $.a.b, $['a'], $.items[0], $.items[*],
+// $..name and simple predicates such as "$.user.age > 18".
+package jsonpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// stepKind identifies the kind of a path step
+type stepKind int
+
+const (
+	stepField stepKind = iota
+	stepIndex
+	stepWildcard
+	stepRecursive
+)
+
+// step is a single element of a compiled path
+type step struct {
+	kind  stepKind
+	field string
+	index int
+}
+
+// Path is a compiled JSONPath expression
+type Path struct {
+	expr  string
+	steps []step
+}
+
+// Compile parses a JSONPath expression. The leading '$' is optional.
+func Compile(expr string) (*Path, error) {
+	expr = strings.TrimSpace(expr)
+	rest := expr
+	if strings.HasPrefix(rest, "$") {
+		rest = rest[1:]
+	} else if rest != "" && rest[0] != '.' && rest[0] != '[' {
+		rest = "." + rest
+	}
+
+	var steps []step
+	for len(rest) > 0 {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			rest = rest[2:]
+			name, remaining := readName(rest)
+			if name == "" {
+				return nil, fmt.Errorf("expected field name after '..' in %s", expr)
+			}
+			steps = append(steps, step{kind: stepRecursive, field: name})
+			rest = remaining
+		case rest[0] == '.':
+			rest = rest[1:]
+			if strings.HasPrefix(rest, "*") {
+				steps = append(steps, step{kind: stepWildcard})
+				rest = rest[1:]
+				continue
+			}
+			name, remaining := readName(rest)
+			if name == "" {
+				return nil, fmt.Errorf("expected field name after '.' in %s", expr)
+			}
+			steps = append(steps, step{kind: stepField, field: name})
+			rest = remaining
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed '[' in %s", expr)
+			}
+			s, err := parseBracket(strings.TrimSpace(rest[1:end]))
+			if err != nil {
+				return nil, fmt.Errorf("%w in %s", err, expr)
+			}
+			steps = append(steps, s)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected character %q in %s", rest[0], expr)
+		}
+	}
+
+	return &Path{expr: expr, steps: steps}, nil
+}
+
+// readName reads a dotted field name up to the next '.' or '['
+func readName(s string) (string, string) {
+	end := strings.IndexAny(s, ".[")
+	if end < 0 {
+		return s, ""
+	}
+	return s[:end], s[end:]
+}
+
+// parseBracket parses the contents of a [...] step
+func parseBracket(content string) (step, error) {
+	if content == "*" {
+		return step{kind: stepWildcard}, nil
+	}
+
+	if len(content) >= 2 && (content[0] == '\'' || content[0] == '"') && content[len(content)-1] == content[0] {
+		return step{kind: stepField, field: content[1 : len(content)-1]}, nil
+	}
+
+	index, err := strconv.Atoi(content)
+	if err != nil {
+		return step{}, fmt.Errorf("invalid index %q", content)
+	}
+	return step{kind: stepIndex, index: index}, nil
+}
+
+// String returns the source expression
+func (p *Path) String() string {
+	return p.expr
+}
+
+// Find returns all values in doc selected by the path
+func (p *Path) Find(doc interface{}) []interface{} {
+	current := []interface{}{doc}
+	for _, s := range p.steps {
+		var next []interface{}
+		for _, node := range current {
+			next = append(next, apply(s, node)...)
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// apply evaluates a single step against a node
+func apply(s step, node interface{}) []interface{} {
+	switch s.kind {
+	case stepField:
+		if m, ok := node.(map[string]interface{}); ok {
+			if value, ok := m[s.field]; ok {
+				return []interface{}{value}
+			}
+		}
+	case stepIndex:
+		if list, ok := node.([]interface{}); ok {
+			index := s.index
+			if index < 0 {
+				index += len(list)
+			}
+			if index >= 0 && index < len(list) {
+				return []interface{}{list[index]}
+			}
+		}
+	case stepWildcard:
+		return children(node)
+	case stepRecursive:
+		var results []interface{}
+		if m, ok := node.(map[string]interface{}); ok {
+			if value, ok := m[s.field]; ok {
+				results = append(results, value)
+			}
+		}
+		for _, child := range children(node) {
+			results = append(results, apply(s, child)...)
+		}
+		return results
+	}
+	return nil
+}
+
+// children returns the direct child values of an object or array
+func children(node interface{}) []interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		results := make([]interface{}, 0, len(v))
+		for _, value := range v {
+			results = append(results, value)
+		}
+		return results
+	case []interface{}:
+		return v
+	}
+	return nil
+}
+
+// Get returns the first value selected by expr
+func Get(doc interface{}, expr string) (interface{}, bool) {
+	path, err := Compile(expr)
+	if err != nil {
+		return nil, false
+	}
+
+	values := path.Find(doc)
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
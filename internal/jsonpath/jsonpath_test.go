@@ -0,0 +1,74 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func parseDoc(t *testing.T, data string) interface{} {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		t.Fatalf("Failed to parse test document: %v", err)
+	}
+	return doc
+}
+
+func TestFind(t *testing.T) {
+	doc := parseDoc(t, `{
+		"user": {"id": "u-1", "name": "John", "age": 30, "tags": ["a", "b"]},
+		"items": [{"sku": "A1", "price": 5}, {"sku": "B2", "price": 15}],
+		"odd key": true
+	}`)
+
+	tests := []struct {
+		path     string
+		expected []interface{}
+	}{
+		{"$.user.name", []interface{}{"John"}},
+		{"user.name", []interface{}{"John"}},
+		{"$['user']['id']", []interface{}{"u-1"}},
+		{"$[\"odd key\"]", []interface{}{true}},
+		{"$.user.tags[1]", []interface{}{"b"}},
+		{"$.user.tags[-1]", []interface{}{"b"}},
+		{"$.items[*].sku", []interface{}{"A1", "B2"}},
+		{"$..price", []interface{}{float64(5), float64(15)}},
+		{"$.user.missing", nil},
+		{"$.user.tags[5]", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, err := Compile(tt.path)
+			if err != nil {
+				t.Fatalf("Failed to compile path: %v", err)
+			}
+			result := path.Find(doc)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Find(%s) = %v, expected %v", tt.path, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	invalid := []string{"$.items[", "$.items[abc]", "$.", "$..", "$x"}
+	for _, expr := range invalid {
+		if _, err := Compile(expr); err == nil {
+			t.Errorf("Expected error for path %s", expr)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	doc := parseDoc(t, `{"request": {"id": "req-42"}}`)
+
+	value, ok := Get(doc, "$.request.id")
+	if !ok || value != "req-42" {
+		t.Errorf("Expected 'req-42', got %v (found=%v)", value, ok)
+	}
+
+	if _, ok := Get(doc, "$.request.missing"); ok {
+		t.Error("Expected missing value not to be found")
+	}
+}
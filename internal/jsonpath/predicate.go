@@ -0,0 +1,173 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// operators are checked longest first so ">=" is not read as ">"
+var operators = []string{"==", "!=", ">=", "<=", "=~", ">", "<"}
+
+// Predicate is a compiled expression such as "$.user.age > 18",
+// "$.status == 'active'" or "$.email =~ /@example\.com$/". A bare path
+// checks that the value exists.
+type Predicate struct {
+	path  *Path
+	op    string
+	value interface{}
+	regex *regexp.Regexp
+}
+
+// ParsePredicate compiles a predicate expression
+func ParsePredicate(expr string) (*Predicate, error) {
+	pathExpr, op, literal := splitPredicate(expr)
+
+	path, err := Compile(pathExpr)
+	if err != nil {
+		return nil, err
+	}
+
+	predicate := &Predicate{path: path, op: op}
+	if op == "" {
+		return predicate, nil
+	}
+
+	if op == "=~" {
+		pattern := literal
+		if len(pattern) >= 2 && pattern[0] == '/' && pattern[len(pattern)-1] == '/' {
+			pattern = pattern[1 : len(pattern)-1]
+		} else if value, err := parseLiteral(literal); err == nil {
+			if str, ok := value.(string); ok {
+				pattern = str
+			}
+		}
+		predicate.regex, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex in %s: %w", expr, err)
+		}
+		return predicate, nil
+	}
+
+	predicate.value, err = parseLiteral(literal)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value in %s: %w", expr, err)
+	}
+	return predicate, nil
+}
+
+// splitPredicate splits an expression into path, operator and literal,
+// ignoring operators inside brackets or quotes
+func splitPredicate(expr string) (string, string, string) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '\'' || c == '"':
+			quote = c
+			continue
+		case c == '[':
+			depth++
+			continue
+		case c == ']':
+			depth--
+			continue
+		}
+
+		if depth > 0 {
+			continue
+		}
+		for _, op := range operators {
+			if strings.HasPrefix(expr[i:], op) {
+				return strings.TrimSpace(expr[:i]), op, strings.TrimSpace(expr[i+len(op):])
+			}
+		}
+	}
+	return strings.TrimSpace(expr), "", ""
+}
+
+// parseLiteral parses a JSON literal, also accepting single-quoted strings
+func parseLiteral(literal string) (interface{}, error) {
+	if len(literal) >= 2 && literal[0] == '\'' && literal[len(literal)-1] == '\'' {
+		return literal[1 : len(literal)-1], nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(literal), &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Match reports whether any value selected by the predicate's path
+// satisfies the comparison
+func (p *Predicate) Match(doc interface{}) bool {
+	values := p.path.Find(doc)
+	if p.op == "" {
+		return len(values) > 0
+	}
+
+	if p.op == "!=" && len(values) == 0 {
+		return true
+	}
+
+	for _, value := range values {
+		if p.compare(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare applies the operator to a single value
+func (p *Predicate) compare(value interface{}) bool {
+	switch p.op {
+	case "==":
+		return reflect.DeepEqual(value, p.value)
+	case "!=":
+		return !reflect.DeepEqual(value, p.value)
+	case "=~":
+		str, ok := value.(string)
+		return ok && p.regex.MatchString(str)
+	}
+
+	if a, ok := value.(float64); ok {
+		if b, ok := p.value.(float64); ok {
+			return compareOrdered(a, b, p.op)
+		}
+	}
+	if a, ok := value.(string); ok {
+		if b, ok := p.value.(string); ok {
+			return compareOrdered(a, b, p.op)
+		}
+	}
+	return false
+}
+
+// compareOrdered applies an ordering operator
+func compareOrdered[T float64 | string](a, b T, op string) bool {
+	switch op {
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
+
+// String returns the path of the predicate
+func (p *Predicate) String() string {
+	return p.path.String()
+}
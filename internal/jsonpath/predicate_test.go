@@ -0,0 +1,59 @@
+package jsonpath
+
+import (
+	"testing"
+)
+
+func TestPredicateMatch(t *testing.T) {
+	doc := parseDoc(t, `{
+		"user": {"name": "John", "age": 30, "email": "john@example.com", "active": true},
+		"items": [{"price": 5}, {"price": 15}]
+	}`)
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{"$.user.age > 18", true},
+		{"$.user.age >= 30", true},
+		{"$.user.age < 30", false},
+		{"$.user.age <= 29", false},
+		{"$.user.age == 30", true},
+		{"$.user.name == 'John'", true},
+		{`$.user.name == "Jane"`, false},
+		{"$.user.name != 'Jane'", true},
+		{"$.user.active == true", true},
+		{"$.user.email =~ /@example\\.com$/", true},
+		{"$.user.email =~ '^jane'", false},
+		{"$.items[*].price > 10", true},
+		{"$.items[*].price > 20", false},
+		{"$.user.name", true},
+		{"$.user.phone", false},
+		{"$.user.phone != 'x'", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			predicate, err := ParsePredicate(tt.expr)
+			if err != nil {
+				t.Fatalf("Failed to parse predicate: %v", err)
+			}
+			if result := predicate.Match(doc); result != tt.expected {
+				t.Errorf("Match(%s) = %v, expected %v", tt.expr, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParsePredicateInvalid(t *testing.T) {
+	invalid := []string{
+		"$.user.age > abc",
+		"$.user.email =~ /[a-/",
+		"$.items[ == 1",
+	}
+	for _, expr := range invalid {
+		if _, err := ParsePredicate(expr); err == nil {
+			t.Errorf("Expected error for predicate %s", expr)
+		}
+	}
+}
@@ -115,6 +115,27 @@ func (l *Logger) LogResponse(req *http.Request, statusCode int, responseBody []b
 	}
 }
 
+// LogWebSocketMessage logs a message received ("<-") or sent ("->") on a
+// WebSocket connection. The content is logged in debug mode.
+func (l *Logger) LogWebSocketMessage(req *http.Request, direction string, binary bool, data []byte) {
+	if l.level > LogLevelInfo {
+		return
+	}
+
+	l.infoLogger.Printf("WebSocket: %s %s (%d bytes)", req.URL.Path, direction, len(data))
+
+	if l.level <= LogLevelDebug {
+		switch {
+		case binary:
+			l.infoLogger.Printf("WebSocket Message: [BINARY: %d bytes]", len(data))
+		case len(data) >= 10240:
+			l.infoLogger.Printf("WebSocket Message: [LARGE MESSAGE: %d bytes]", len(data))
+		default:
+			l.infoLogger.Printf("WebSocket Message: %s", data)
+		}
+	}
+}
+
 // LogError logs an error with context
 func (l *Logger) LogError(err error, context string) {
 	if l.level > LogLevelError {
@@ -319,6 +340,19 @@ func (w *responseWriterWrapper) Write(data []byte) (int, error) {
 	return w.ResponseWriter.Write(data)
 }
 
+// Flush sends buffered data to the client if the wrapped writer supports it
+func (w *responseWriterWrapper) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer so http.ResponseController can reach
+// optional interfaces such as http.Hijacker
+func (w *responseWriterWrapper) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // SetLogLevel sets the logging level
 func (l *Logger) SetLogLevel(level LogLevel) {
 	l.level = level
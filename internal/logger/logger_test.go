@@ -3,6 +3,7 @@ package logger
 import (
 	"bytes"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -128,6 +129,23 @@ func TestLogResponse(t *testing.T) {
 	}
 }
 
+func TestLogWebSocketMessage(t *testing.T) {
+	var infoBuf, errBuf bytes.Buffer
+	logger := NewWithWriters(LogLevelDebug, &infoBuf, &errBuf)
+
+	req := httptest.NewRequest("GET", "/chat", nil)
+	logger.LogWebSocketMessage(req, "<-", false, []byte(`{"type": "ping"}`))
+	logger.LogWebSocketMessage(req, "->", true, []byte{0x00, 0x01})
+
+	output := infoBuf.String()
+	if !strings.Contains(output, "WebSocket: /chat <- (16 bytes)") || !strings.Contains(output, `{"type": "ping"}`) {
+		t.Errorf("Expected the received text message to be logged, got:\n%s", output)
+	}
+	if !strings.Contains(output, "[BINARY: 2 bytes]") {
+		t.Errorf("Expected binary content to be summarized, got:\n%s", output)
+	}
+}
+
 func TestLogError(t *testing.T) {
 	var infoBuf, errBuf bytes.Buffer
 	logger := NewWithWriters(LogLevelError, &infoBuf, &errBuf)
@@ -313,6 +331,41 @@ func TestResponseWriterWrapper(t *testing.T) {
 	}
 }
 
+func TestResponseWriterWrapperFlush(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	wrapper := &responseWriterWrapper{
+		ResponseWriter: recorder,
+		statusCode:     200,
+		body:           &bytes.Buffer{},
+	}
+
+	var _ http.Flusher = wrapper
+	wrapper.Flush()
+	if !recorder.Flushed {
+		t.Error("Expected Flush to reach the wrapped writer")
+	}
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	logger := NewWithWriters(LogLevelError, io.Discard, io.Discard)
+	handler := logger.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Expected wrapped writer to support hijacking: %v", err)
+			return
+		}
+		conn.Close()
+	}))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	if resp, err := http.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Error("Expected client error after the connection was hijacked and closed")
+	}
+}
+
 func TestCreateRequestLog(t *testing.T) {
 	logger := New(LogLevelDebug)
 
@@ -0,0 +1,142 @@
+package openapi
+
+import (
+	"sort"
+)
+
+// maxExampleDepth stops synthesizing deeply nested schemas
+const maxExampleDepth = 16
+
+// formatExamples are the values synthesized for string formats
+var formatExamples = map[string]string{
+	"date":      "2024-01-01",
+	"date-time": "2024-01-01T12:00:00Z",
+	"time":      "12:00:00",
+	"email":     "user@example.com",
+	"uuid":      "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+	"uri":       "https://example.com",
+	"url":       "https://example.com",
+	"hostname":  "example.com",
+	"ipv4":      "192.0.2.1",
+	"ipv6":      "2001:db8::1",
+	"byte":      "ZXhhbXBsZQ==",
+	"password":  "********",
+}
+
+// MediaTypeExample returns the example of a media type: its example, the
+// first of its named examples or a value synthesized from its schema
+func (d *Document) MediaTypeExample(media *MediaType) interface{} {
+	if media == nil {
+		return nil
+	}
+	if media.Example != nil {
+		return media.Example
+	}
+	if len(media.Examples) > 0 {
+		names := make([]string, 0, len(media.Examples))
+		for name := range media.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if example, err := d.ResolveExample(media.Examples[names[0]]); err == nil && example.Value != nil {
+			return example.Value
+		}
+	}
+	return d.SchemaExample(media.Schema)
+}
+
+// SchemaExample synthesizes a value that satisfies the schema, preferring
+// the schema's own example, default and enum values
+func (d *Document) SchemaExample(schema *Schema) interface{} {
+	return d.schemaExample(schema, map[string]bool{}, 0)
+}
+
+// schemaExample synthesizes a value for the schema. References already being
+// expanded are skipped so that recursive schemas terminate.
+func (d *Document) schemaExample(schema *Schema, expanding map[string]bool, depth int) interface{} {
+	if schema == nil || depth > maxExampleDepth {
+		return nil
+	}
+	if ref := schema.Ref; ref != "" {
+		if expanding[ref] {
+			return nil
+		}
+		expanding[ref] = true
+		defer delete(expanding, ref)
+	}
+	schema, err := d.ResolveSchema(schema)
+	if err != nil || schema == nil {
+		return nil
+	}
+
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	case len(schema.AllOf) > 0:
+		merged := make(map[string]interface{})
+		for _, part := range schema.AllOf {
+			if object, ok := d.schemaExample(part, expanding, depth+1).(map[string]interface{}); ok {
+				for key, value := range object {
+					merged[key] = value
+				}
+			}
+		}
+		return merged
+	case len(schema.OneOf) > 0:
+		return d.schemaExample(schema.OneOf[0], expanding, depth+1)
+	case len(schema.AnyOf) > 0:
+		return d.schemaExample(schema.AnyOf[0], expanding, depth+1)
+	}
+
+	typ := schema.Type.Primary()
+	if typ == "" {
+		switch {
+		case schema.Properties != nil:
+			typ = "object"
+		case schema.Items != nil:
+			typ = "array"
+		}
+	}
+
+	switch typ {
+	case "object":
+		object := make(map[string]interface{}, len(schema.Properties))
+		for name, property := range schema.Properties {
+			if value := d.schemaExample(property, expanding, depth+1); value != nil {
+				object[name] = value
+			}
+		}
+		return object
+	case "array":
+		if schema.Items == nil {
+			return []interface{}{}
+		}
+		item := d.schemaExample(schema.Items, expanding, depth+1)
+		if item == nil {
+			return []interface{}{}
+		}
+		return []interface{}{item}
+	case "string":
+		if value, ok := formatExamples[schema.Format]; ok {
+			return value
+		}
+		return "string"
+	case "integer":
+		if schema.Minimum != nil {
+			return int(*schema.Minimum)
+		}
+		return 0
+	case "number":
+		if schema.Minimum != nil {
+			return *schema.Minimum
+		}
+		return 0.0
+	case "boolean":
+		return true
+	}
+	return nil
+}
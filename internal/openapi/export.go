@@ -0,0 +1,341 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// uuidPattern recognizes UUID strings when inferring formats
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// constraintSchemas maps named path constraints to parameter schemas
+var constraintSchemas = map[string]*Schema{
+	"int":   {Type: TypeSet{"integer"}},
+	"uint":  {Type: TypeSet{"integer"}, Minimum: new(float64)},
+	"alpha": {Type: TypeSet{"string"}, Pattern: "^[a-zA-Z]+$"},
+	"alnum": {Type: TypeSet{"string"}, Pattern: "^[a-zA-Z0-9]+$"},
+	"uuid":  {Type: TypeSet{"string"}, Format: "uuid"},
+}
+
+// FromRoutes builds an OpenAPI 3 document describing the routes. Routes
+// sharing a method and path become a single operation documenting every
+// status code they answer with; response schemas are inferred from the
+// configured bodies.
+func FromRoutes(routes []config.Route, info Info) *Document {
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+	}
+
+	for i := range routes {
+		route := &routes[i]
+		path, parameters := exportPath(route.Path)
+
+		item := doc.Paths[path]
+		if item == nil {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		operation := item.Operations()[strings.ToUpper(route.Method)]
+		if operation == nil {
+			operation = &Operation{
+				Summary:    route.Method + " " + route.Path,
+				Parameters: parameters,
+				Responses:  make(map[string]*Response),
+			}
+			item.SetOperation(route.Method, operation)
+		}
+
+		addRequestParameters(operation, route)
+		addRequestBody(operation, route)
+		for _, variant := range routeResponses(route) {
+			addResponse(operation, route, variant)
+		}
+	}
+	return doc
+}
+
+// exportPath converts a route path to an OpenAPI path and its path
+// parameters. Constraints become schemas and '*' wildcards become
+// parameters named wildcardN.
+func exportPath(routePath string) (string, []*Parameter) {
+	var path strings.Builder
+	var parameters []*Parameter
+	wildcards := 0
+
+	for i := 0; i < len(routePath); i++ {
+		switch routePath[i] {
+		case '{':
+			end := config.MatchingBrace(routePath, i)
+			if end < 0 {
+				path.WriteString(routePath[i:])
+				return path.String(), parameters
+			}
+			name, constraint := routePath[i+1:end], ""
+			if idx := strings.Index(name, ":"); idx >= 0 {
+				name, constraint = name[:idx], name[idx+1:]
+			}
+			schema := &Schema{Type: TypeSet{"string"}}
+			if named, ok := constraintSchemas[constraint]; ok {
+				copied := *named
+				schema = &copied
+			} else if constraint != "" {
+				schema.Pattern = "^" + constraint + "$"
+			}
+			parameters = append(parameters, &Parameter{Name: name, In: "path", Required: true, Schema: schema})
+			path.WriteString("{" + name + "}")
+			i = end
+		case '*':
+			wildcards++
+			name := fmt.Sprintf("wildcard%d", wildcards)
+			parameters = append(parameters, &Parameter{
+				Name: name, In: "path", Required: true,
+				Description: "Matches any text, including '/'",
+				Schema:      &Schema{Type: TypeSet{"string"}},
+			})
+			path.WriteString("{" + name + "}")
+		default:
+			path.WriteByte(routePath[i])
+		}
+	}
+	return path.String(), parameters
+}
+
+// addRequestParameters documents the query parameters and headers the route
+// requires
+func addRequestParameters(operation *Operation, route *config.Route) {
+	for _, name := range config.SortedKeys(route.Parameters) {
+		addParameter(operation, &Parameter{
+			Name: name, In: "query", Required: true,
+			Schema:  &Schema{Type: TypeSet{"string"}},
+			Example: route.Parameters[name],
+		})
+	}
+
+	if route.Match == nil {
+		return
+	}
+	names := make([]string, 0, len(route.Match.Headers))
+	for name := range route.Match.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		matcher := route.Match.Headers[name]
+		if matcher.Present != nil && !*matcher.Present {
+			continue
+		}
+		schema := &Schema{Type: TypeSet{"string"}}
+		if matcher.Matches != "" {
+			schema.Pattern = matcher.Matches
+		}
+		parameter := &Parameter{Name: name, In: "header", Required: true, Schema: schema}
+		if matcher.Equals != "" {
+			parameter.Example = matcher.Equals
+		}
+		addParameter(operation, parameter)
+	}
+}
+
+// addParameter adds a parameter unless the operation already has it
+func addParameter(operation *Operation, parameter *Parameter) {
+	for _, existing := range operation.Parameters {
+		if existing.In == parameter.In && strings.EqualFold(existing.Name, parameter.Name) {
+			return
+		}
+	}
+	operation.Parameters = append(operation.Parameters, parameter)
+}
+
+// addRequestBody documents the JSON body the route expects
+func addRequestBody(operation *Operation, route *config.Route) {
+	if operation.RequestBody != nil || route.Match == nil || route.Match.Body == nil {
+		return
+	}
+	example := route.Match.Body.EqualToJSON
+	if example == nil {
+		example = route.Match.Body.PartialJSON
+	}
+	example = normalize(example)
+
+	media := &MediaType{Schema: &Schema{Type: TypeSet{"object"}}}
+	if example != nil {
+		media.Schema = InferSchema(example)
+		media.Example = example
+	}
+	operation.RequestBody = &RequestBody{
+		Required: true,
+		Content:  map[string]*MediaType{"application/json": media},
+	}
+}
+
+// responseVariant is a response a route can answer with
+type responseVariant struct {
+	statusCode  int
+	contentType string
+	headers     map[string]string
+	body        interface{}
+}
+
+// routeResponses lists the responses of a route, including every entry of
+// a responses list
+func routeResponses(route *config.Route) []responseVariant {
+	if len(route.Responses) == 0 {
+		return []responseVariant{{route.StatusCode, route.ContentType, route.Headers, route.GetJSONSafeResponse()}}
+	}
+
+	variants := make([]responseVariant, 0, len(route.Responses))
+	for i := range route.Responses {
+		variant := &route.Responses[i]
+		statusCode, contentType, headers := variant.StatusCode, variant.ContentType, variant.Headers
+		if statusCode == 0 {
+			statusCode = route.StatusCode
+		}
+		if contentType == "" {
+			contentType = route.ContentType
+		}
+		if headers == nil {
+			headers = route.Headers
+		}
+		variants = append(variants, responseVariant{statusCode, contentType, headers, variant.GetJSONSafeResponse()})
+	}
+	return variants
+}
+
+// addResponse documents a response, adding it as a further example when the
+// operation already has one for the status code and content type
+func addResponse(operation *Operation, route *config.Route, variant responseVariant) {
+	status := variant.statusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	key := strconv.Itoa(status)
+
+	response := operation.Responses[key]
+	if response == nil {
+		response = &Response{Description: http.StatusText(status)}
+		if route.Proxy != "" {
+			response.Description = "Proxied to " + route.Proxy
+		}
+		operation.Responses[key] = response
+	}
+
+	for name, value := range variant.headers {
+		if strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		if response.Headers == nil {
+			response.Headers = make(map[string]*Header)
+		}
+		if response.Headers[name] == nil {
+			response.Headers[name] = &Header{Schema: &Schema{Type: TypeSet{"string"}}, Example: value}
+		}
+	}
+
+	if route.Proxy != "" || variant.body == nil || variant.body == "" {
+		return
+	}
+
+	contentType := variant.contentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	body := variant.body
+	if config.IsJSONContentType(contentType) {
+		if text, ok := body.(string); ok {
+			var document interface{}
+			if err := json.Unmarshal([]byte(text), &document); err == nil {
+				body = document
+			}
+		}
+	}
+
+	if response.Content == nil {
+		response.Content = make(map[string]*MediaType)
+	}
+	media := response.Content[contentType]
+	if media == nil {
+		schema := &Schema{Type: TypeSet{"string"}}
+		if config.IsJSONContentType(contentType) {
+			schema = InferSchema(body)
+		}
+		response.Content[contentType] = &MediaType{Schema: schema, Example: body}
+		return
+	}
+
+	// Several routes answer with this status: list each body as an example
+	if media.Examples == nil {
+		media.Examples = map[string]*Example{"example1": {Value: media.Example}}
+		media.Example = nil
+	}
+	name := fmt.Sprintf("example%d", len(media.Examples)+1)
+	media.Examples[name] = &Example{Summary: route.Method + " " + route.Path, Value: body}
+}
+
+// InferSchema derives a JSON schema from an example value
+func InferSchema(value interface{}) *Schema {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		schema := &Schema{Type: TypeSet{"object"}, Properties: make(map[string]*Schema, len(v))}
+		for name, property := range v {
+			schema.Properties[name] = InferSchema(property)
+			schema.Required = append(schema.Required, name)
+		}
+		sort.Strings(schema.Required)
+		return schema
+	case []interface{}:
+		schema := &Schema{Type: TypeSet{"array"}}
+		if len(v) > 0 {
+			schema.Items = InferSchema(v[0])
+		} else {
+			schema.Items = &Schema{}
+		}
+		return schema
+	case string:
+		schema := &Schema{Type: TypeSet{"string"}}
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			schema.Format = "date-time"
+		} else if uuidPattern.MatchString(v) {
+			schema.Format = "uuid"
+		}
+		return schema
+	case bool:
+		return &Schema{Type: TypeSet{"boolean"}}
+	case int, int64, int32, uint, uint64:
+		return &Schema{Type: TypeSet{"integer"}}
+	case float64:
+		if v == float64(int64(v)) {
+			return &Schema{Type: TypeSet{"integer"}}
+		}
+		return &Schema{Type: TypeSet{"number"}}
+	case float32:
+		return &Schema{Type: TypeSet{"number"}}
+	case nil:
+		return &Schema{Nullable: true}
+	default:
+		return &Schema{}
+	}
+}
+
+// MarshalYAML lets yaml.Marshal write the document with its OpenAPI field
+// names
+func (d *Document) MarshalYAML() (interface{}, error) {
+	encoded, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	var document interface{}
+	if err := json.Unmarshal(encoded, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
@@ -0,0 +1,133 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+	"gopkg.in/yaml.v2"
+)
+
+func TestFromRoutes(t *testing.T) {
+	present := true
+	routes := []config.Route{
+		{
+			Path: "/api/users/{id:int}", Method: "GET", StatusCode: 200, ContentType: "application/json",
+			Headers:  map[string]string{"X-Version": "2"},
+			Response: map[interface{}]interface{}{"id": 1, "name": "Alice", "tags": []interface{}{"admin"}, "score": 9.5},
+		},
+		{
+			Path: "/api/users/{id:int}", Method: "GET", StatusCode: 200, ContentType: "application/json",
+			Response: map[string]interface{}{"id": 2, "name": "Bob", "tags": []interface{}{}},
+			Match: &config.RequestMatch{Headers: map[string]config.HeaderMatcher{
+				"Authorization": {Present: &present},
+			}},
+		},
+		{
+			Path: "/api/users", Method: "POST", StatusCode: 201, ContentType: "application/json",
+			Response: `{"created": "2024-01-01T00:00:00Z"}`,
+			Match: &config.RequestMatch{Body: &config.BodyMatcher{
+				PartialJSON: map[interface{}]interface{}{"name": "Alice"},
+			}},
+			Responses: []config.ResponseVariant{
+				{Response: `{"id": "3fa85f64-5717-4562-b3fc-2c963f66afa6"}`},
+				{StatusCode: 409, ContentType: "text/plain", Response: "exists"},
+			},
+		},
+		{Path: "/files/*", Method: "GET", StatusCode: 200, ContentType: "text/plain", Response: "file",
+			Parameters: map[string]string{"download": "true"}},
+		{Path: "/live/{rest:.+}", Method: "GET", Proxy: "https://api.example.com"},
+	}
+
+	doc := FromRoutes(routes, Info{Title: "Test", Version: "1"})
+	if doc.OpenAPI != "3.0.3" || len(doc.Paths) != 4 {
+		t.Fatalf("Expected 4 paths, got %v", doc.Paths)
+	}
+
+	get := doc.Paths["/api/users/{id}"].Get
+	if get == nil || len(get.Parameters) != 2 {
+		t.Fatalf("Expected path and header parameters, got %+v", get)
+	}
+	if id := get.Parameters[0]; id.In != "path" || !id.Schema.Type.Is("integer") {
+		t.Errorf("Expected an integer path parameter, got %+v", id)
+	}
+	if auth := get.Parameters[1]; auth.In != "header" || auth.Name != "Authorization" {
+		t.Errorf("Expected the Authorization header, got %+v", auth)
+	}
+
+	ok := get.Responses["200"]
+	if ok.Description != "OK" || ok.Headers["X-Version"] == nil {
+		t.Errorf("Unexpected 200 response: %+v", ok)
+	}
+	media := ok.Content["application/json"]
+	if len(media.Examples) != 2 || media.Example != nil {
+		t.Errorf("Expected both bodies as examples, got %+v", media)
+	}
+	schema := media.Schema
+	if !schema.Type.Is("object") || !schema.Properties["id"].Type.Is("integer") ||
+		!schema.Properties["score"].Type.Is("number") || !schema.Properties["tags"].Items.Type.Is("string") {
+		t.Errorf("Unexpected inferred schema: %+v", schema)
+	}
+
+	post := doc.Paths["/api/users"].Post
+	if post.RequestBody == nil || post.RequestBody.Content["application/json"].Example.(map[string]interface{})["name"] != "Alice" {
+		t.Errorf("Expected the body matcher as request example, got %+v", post.RequestBody)
+	}
+	created := post.Responses["201"].Content["application/json"].Schema
+	if created.Properties["id"].Format != "uuid" {
+		t.Errorf("Expected uuid format from the sequence entry, got %+v", created.Properties["id"])
+	}
+	if conflict := post.Responses["409"]; conflict == nil || conflict.Content["text/plain"].Example != "exists" {
+		t.Errorf("Expected the 409 entry, got %+v", conflict)
+	}
+
+	files := doc.Paths["/files/{wildcard1}"].Get
+	if files == nil || len(files.Parameters) != 2 || files.Parameters[1].In != "query" {
+		t.Errorf("Expected wildcard and query parameters, got %+v", files)
+	}
+
+	live := doc.Paths["/live/{rest}"].Get
+	if live.Parameters[0].Schema.Pattern != "^.+$" || live.Responses["200"].Description != "Proxied to https://api.example.com" {
+		t.Errorf("Unexpected proxied operation: %+v", live)
+	}
+
+	// The document can be read back
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Failed to marshal document: %v", err)
+	}
+	parsed, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Failed to parse exported document: %v", err)
+	}
+	reimported, err := parsed.Routes()
+	if err != nil || len(reimported) != 5 {
+		t.Errorf("Expected 5 routes from the exported document, got %d (%v)", len(reimported), err)
+	}
+}
+
+func TestInferSchema(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+		format   string
+	}{
+		{"text", "string", ""},
+		{"2024-01-01T00:00:00Z", "string", "date-time"},
+		{true, "boolean", ""},
+		{3, "integer", ""},
+		{3.0, "integer", ""},
+		{3.5, "number", ""},
+		{[]interface{}{1}, "array", ""},
+		{map[string]interface{}{}, "object", ""},
+	}
+
+	for _, tt := range tests {
+		schema := InferSchema(tt.value)
+		if !schema.Type.Is(tt.expected) || schema.Format != tt.format {
+			t.Errorf("InferSchema(%v) = %v %s, expected %s %s", tt.value, schema.Type, schema.Format, tt.expected, tt.format)
+		}
+	}
+	if schema := InferSchema(nil); !schema.Nullable {
+		t.Error("Expected null values to be nullable")
+	}
+}
@@ -0,0 +1,227 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// methodOrder is the order in which the operations of a path become routes
+var methodOrder = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// Routes generates a route per operation. The route answers with the first
+// 2xx response (or the default one); every other documented status code gets
+// a route selected by sending "Prefer: code=<status>".
+func (d *Document) Routes() ([]config.Route, error) {
+	basePath := d.basePath()
+
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return moreSpecific(paths[i], paths[j])
+	})
+
+	var routes []config.Route
+	for _, path := range paths {
+		item := d.Paths[path]
+		if item == nil {
+			continue
+		}
+		operations := item.Operations()
+		for _, method := range methodOrder {
+			operation := operations[method]
+			if operation == nil {
+				continue
+			}
+			operationRoutes, err := d.operationRoutes(basePath, path, method, item, operation)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			routes = append(routes, operationRoutes...)
+		}
+	}
+	return routes, nil
+}
+
+// operationRoutes generates the routes of a single operation
+func (d *Document) operationRoutes(basePath, path, method string, item *PathItem, operation *Operation) ([]config.Route, error) {
+	parameters, err := d.OperationParameters(item, operation)
+	if err != nil {
+		return nil, err
+	}
+	routePath := basePath + d.routePath(path, parameters)
+
+	statuses := make([]string, 0, len(operation.Responses))
+	for status := range operation.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	primary := ""
+	for _, status := range statuses {
+		if strings.HasPrefix(status, "2") {
+			primary = status
+			break
+		}
+	}
+	if primary == "" && operation.Responses["default"] != nil {
+		primary = "default"
+	}
+	if primary == "" && len(statuses) > 0 {
+		primary = statuses[0]
+	}
+
+	var routes []config.Route
+	for _, status := range statuses {
+		code, err := strconv.Atoi(status)
+		if status == primary || err != nil {
+			continue
+		}
+		route, err := d.responseRoute(routePath, method, code, operation.Responses[status])
+		if err != nil {
+			return nil, err
+		}
+		route.Match = &config.RequestMatch{Headers: map[string]config.HeaderMatcher{
+			"Prefer": {Contains: "code=" + status},
+		}}
+		routes = append(routes, route)
+	}
+
+	code := http.StatusOK
+	if primary != "" && primary != "default" {
+		if parsed, err := strconv.Atoi(strings.ReplaceAll(strings.ToUpper(primary), "X", "0")); err == nil {
+			code = parsed
+		}
+	}
+	var response *Response
+	if primary != "" {
+		response = operation.Responses[primary]
+	}
+	route, err := d.responseRoute(routePath, method, code, response)
+	if err != nil {
+		return nil, err
+	}
+	return append(routes, route), nil
+}
+
+// responseRoute creates a route answering with the documented response
+func (d *Document) responseRoute(path, method string, code int, response *Response) (config.Route, error) {
+	route := config.Route{Path: path, Method: method, StatusCode: code}
+	if response == nil {
+		return route, nil
+	}
+	response, err := d.ResolveResponse(response)
+	if err != nil {
+		return route, err
+	}
+
+	if contentType := PreferredContentType(response.Content); contentType != "" {
+		route.ContentType = contentType
+		route.Response = d.MediaTypeExample(response.Content[contentType])
+	}
+
+	for name, header := range response.Headers {
+		if strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		header, err := d.ResolveHeader(header)
+		if err != nil {
+			return route, err
+		}
+		value := header.Example
+		if value == nil {
+			value = d.SchemaExample(header.Schema)
+		}
+		if value == nil {
+			continue
+		}
+		if route.Headers == nil {
+			route.Headers = make(map[string]string)
+		}
+		route.Headers[name] = fmt.Sprint(value)
+	}
+	return route, nil
+}
+
+// routePath converts an OpenAPI path to a route path, constraining integer
+// and UUID path parameters so that e.g. /users/{id} does not swallow
+// /users/me
+func (d *Document) routePath(path string, parameters []*Parameter) string {
+	for _, parameter := range parameters {
+		if parameter.In != "path" {
+			continue
+		}
+		schema, err := d.ResolveSchema(parameter.Schema)
+		if err != nil || schema == nil {
+			continue
+		}
+		constraint := ""
+		switch {
+		case schema.Type.Is("integer"):
+			constraint = "int"
+		case schema.Type.Is("string") && schema.Format == "uuid":
+			constraint = "uuid"
+		}
+		if constraint != "" {
+			path = strings.ReplaceAll(path, "{"+parameter.Name+"}", "{"+parameter.Name+":"+constraint+"}")
+		}
+	}
+	return path
+}
+
+// basePath returns the path of the first server URL, e.g. /v1
+func (d *Document) basePath() string {
+	if len(d.Servers) == 0 || strings.Contains(d.Servers[0].URL, "{") {
+		return ""
+	}
+	u, err := url.Parse(d.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// moreSpecific orders paths so that literal segments come before parameters
+// at the same position, e.g. /users/me before /users/{id}, and parents come
+// before their children
+func moreSpecific(a, b string) bool {
+	aSegments := strings.Split(a, "/")
+	bSegments := strings.Split(b, "/")
+	for i := 0; i < len(aSegments) && i < len(bSegments); i++ {
+		aParam := strings.HasPrefix(aSegments[i], "{")
+		bParam := strings.HasPrefix(bSegments[i], "{")
+		if aParam != bParam {
+			return bParam
+		}
+		if aSegments[i] != bSegments[i] {
+			return aSegments[i] < bSegments[i]
+		}
+	}
+	return len(aSegments) < len(bSegments)
+}
+
+// PreferredContentType picks JSON when available, otherwise the first
+// content type in alphabetical order
+func PreferredContentType(content map[string]*MediaType) string {
+	types := make([]string, 0, len(content))
+	for contentType := range content {
+		types = append(types, contentType)
+	}
+	sort.Strings(types)
+	for _, contentType := range types {
+		if config.IsJSONContentType(contentType) {
+			return contentType
+		}
+	}
+	if len(types) > 0 {
+		return types[0]
+	}
+	return ""
+}
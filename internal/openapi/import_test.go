@@ -0,0 +1,79 @@
+package openapi
+
+import (
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	doc, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	routes, err := doc.Routes()
+	if err != nil {
+		t.Fatalf("Failed to generate routes: %v", err)
+	}
+
+	var keys []string
+	for _, route := range routes {
+		keys = append(keys, route.Method+" "+route.Path)
+	}
+	expected := []string{
+		"GET /v1/pets",
+		"POST /v1/pets",
+		"POST /v1/pets",
+		"GET /v1/pets/mine",
+		"GET /v1/pets/{petId:int}",
+		"GET /v1/pets/{petId:int}",
+	}
+	if len(keys) != len(expected) {
+		t.Fatalf("Expected routes %v, got %v", expected, keys)
+	}
+	for i := range expected {
+		if keys[i] != expected[i] {
+			t.Errorf("Route %d: expected %s, got %s", i, expected[i], keys[i])
+		}
+	}
+
+	list := routes[0]
+	if list.StatusCode != 200 || list.ContentType != "application/json" || list.Headers["X-Total-Count"] != "2" {
+		t.Errorf("Unexpected list route: %+v", list)
+	}
+	if pets, ok := list.Response.([]interface{}); !ok || len(pets) != 1 {
+		t.Errorf("Expected the media type example, got %#v", list.Response)
+	}
+
+	badRequest := routes[1]
+	if badRequest.StatusCode != 400 || badRequest.Match == nil || badRequest.Match.Headers["Prefer"].Contains != "code=400" {
+		t.Errorf("Expected a 400 route selected by Prefer, got %+v", badRequest)
+	}
+
+	created := routes[2]
+	if created.StatusCode != 201 || created.Match != nil {
+		t.Errorf("Expected the default 201 route, got %+v", created)
+	}
+	if body := created.Response.(map[string]interface{}); body["name"] != "Tom" {
+		t.Errorf("Expected the first named example, got %v", body)
+	}
+
+	if mine := routes[3]; mine.ContentType != "text/plain" || mine.Response != "Rex" {
+		t.Errorf("Expected the text example, got %+v", mine)
+	}
+
+	if notFound := routes[4]; notFound.StatusCode != 404 || notFound.Response != nil {
+		t.Errorf("Expected an empty 404 route, got %+v", notFound)
+	}
+	if pet := routes[5].Response.(map[string]interface{}); pet["name"] != "string" {
+		t.Errorf("Expected a synthesized pet, got %v", pet)
+	}
+}
+
+func TestMoreSpecific(t *testing.T) {
+	if !moreSpecific("/users/me", "/users/{id}") || moreSpecific("/users/{id}", "/users/me") {
+		t.Error("Expected literal segments before parameters")
+	}
+	if !moreSpecific("/users/{id}", "/users/{id}/orders") {
+		t.Error("Expected parents before children")
+	}
+}
@@ -0,0 +1,395 @@
+// Package openapi reads and writes the subset of OpenAPI 3 documents the mock
+// server needs to generate routes, export them and validate requests
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Document is an OpenAPI 3.x document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components *Components          `json:"components,omitempty"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server is a base URL the API is served from
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// Components holds reusable objects referenced with $ref
+type Components struct {
+	Schemas       map[string]*Schema      `json:"schemas,omitempty"`
+	Parameters    map[string]*Parameter   `json:"parameters,omitempty"`
+	Responses     map[string]*Response    `json:"responses,omitempty"`
+	RequestBodies map[string]*RequestBody `json:"requestBodies,omitempty"`
+	Headers       map[string]*Header      `json:"headers,omitempty"`
+	Examples      map[string]*Example     `json:"examples,omitempty"`
+}
+
+// PathItem holds the operations available on a path
+type PathItem struct {
+	Parameters []*Parameter `json:"parameters,omitempty"`
+	Get        *Operation   `json:"get,omitempty"`
+	Put        *Operation   `json:"put,omitempty"`
+	Post       *Operation   `json:"post,omitempty"`
+	Delete     *Operation   `json:"delete,omitempty"`
+	Options    *Operation   `json:"options,omitempty"`
+	Head       *Operation   `json:"head,omitempty"`
+	Patch      *Operation   `json:"patch,omitempty"`
+}
+
+// Operations returns the operations of the path item keyed by upper-case
+// HTTP method
+func (p *PathItem) Operations() map[string]*Operation {
+	operations := make(map[string]*Operation)
+	for method, operation := range map[string]*Operation{
+		"GET": p.Get, "PUT": p.Put, "POST": p.Post, "DELETE": p.Delete,
+		"OPTIONS": p.Options, "HEAD": p.Head, "PATCH": p.Patch,
+	} {
+		if operation != nil {
+			operations[method] = operation
+		}
+	}
+	return operations
+}
+
+// SetOperation sets the operation for an HTTP method
+func (p *PathItem) SetOperation(method string, operation *Operation) {
+	switch strings.ToUpper(method) {
+	case "GET":
+		p.Get = operation
+	case "PUT":
+		p.Put = operation
+	case "POST":
+		p.Post = operation
+	case "DELETE":
+		p.Delete = operation
+	case "OPTIONS":
+		p.Options = operation
+	case "HEAD":
+		p.Head = operation
+	case "PATCH":
+		p.Patch = operation
+	}
+}
+
+// Operation is a single API operation on a path
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a path, query, header or cookie parameter
+type Parameter struct {
+	Ref         string      `json:"$ref,omitempty"`
+	Name        string      `json:"name,omitempty"`
+	In          string      `json:"in,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Schema      *Schema     `json:"schema,omitempty"`
+	Example     interface{} `json:"example,omitempty"`
+}
+
+// RequestBody describes the body of a request
+type RequestBody struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// Response describes a response of an operation
+type Response struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// Header describes a response header
+type Header struct {
+	Ref         string      `json:"$ref,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Schema      *Schema     `json:"schema,omitempty"`
+	Example     interface{} `json:"example,omitempty"`
+}
+
+// MediaType describes a body in one content type
+type MediaType struct {
+	Schema   *Schema             `json:"schema,omitempty"`
+	Example  interface{}         `json:"example,omitempty"`
+	Examples map[string]*Example `json:"examples,omitempty"`
+}
+
+// Example is a named example value
+type Example struct {
+	Ref     string      `json:"$ref,omitempty"`
+	Summary string      `json:"summary,omitempty"`
+	Value   interface{} `json:"value,omitempty"`
+}
+
+// Schema is a JSON schema as used by OpenAPI
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 TypeSet            `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Example              interface{}        `json:"example,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     interface{}        `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     interface{}        `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+// TypeSet is the type of a schema. OpenAPI 3.0 uses a single type while
+// 3.1 allows a list such as ["string", "null"].
+type TypeSet []string
+
+// UnmarshalJSON accepts a single type or a list of types
+func (t *TypeSet) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = TypeSet{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("schema type must be a string or a list of strings")
+	}
+	*t = TypeSet(list)
+	return nil
+}
+
+// MarshalJSON writes a single type as a string
+func (t TypeSet) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// Is reports whether the schema allows the type
+func (t TypeSet) Is(name string) bool {
+	for _, typ := range t {
+		if typ == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Primary returns the first type other than null
+func (t TypeSet) Primary() string {
+	for _, typ := range t {
+		if typ != "null" {
+			return typ
+		}
+	}
+	return ""
+}
+
+// Parse reads an OpenAPI 3 document in YAML or JSON
+func Parse(data []byte) (*Document, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+
+	encoded, err := json.Marshal(normalize(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+
+	var doc Document
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q: expected 3.x", doc.OpenAPI)
+	}
+	return &doc, nil
+}
+
+// normalize converts YAML maps to JSON objects, turning keys such as status
+// codes into strings
+func normalize(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			result[fmt.Sprint(key)] = normalize(value)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = normalize(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// refName returns the component name of a local reference such as
+// #/components/schemas/User
+func refName(ref, kind string) (string, error) {
+	prefix := "#/components/" + kind + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("unsupported reference %s", ref)
+	}
+	return strings.TrimPrefix(ref, prefix), nil
+}
+
+// ResolveSchema follows $ref until it reaches a schema definition
+func (d *Document) ResolveSchema(schema *Schema) (*Schema, error) {
+	for seen := 0; schema != nil && schema.Ref != ""; seen++ {
+		if seen > 32 {
+			return nil, fmt.Errorf("reference cycle at %s", schema.Ref)
+		}
+		name, err := refName(schema.Ref, "schemas")
+		if err != nil {
+			return nil, err
+		}
+		if d.Components == nil || d.Components.Schemas[name] == nil {
+			return nil, fmt.Errorf("schema %s not found", schema.Ref)
+		}
+		schema = d.Components.Schemas[name]
+	}
+	return schema, nil
+}
+
+// ResolveParameter follows $ref to a parameter definition
+func (d *Document) ResolveParameter(parameter *Parameter) (*Parameter, error) {
+	if parameter.Ref == "" {
+		return parameter, nil
+	}
+	name, err := refName(parameter.Ref, "parameters")
+	if err != nil {
+		return nil, err
+	}
+	if d.Components == nil || d.Components.Parameters[name] == nil {
+		return nil, fmt.Errorf("parameter %s not found", parameter.Ref)
+	}
+	return d.Components.Parameters[name], nil
+}
+
+// ResolveResponse follows $ref to a response definition
+func (d *Document) ResolveResponse(response *Response) (*Response, error) {
+	if response.Ref == "" {
+		return response, nil
+	}
+	name, err := refName(response.Ref, "responses")
+	if err != nil {
+		return nil, err
+	}
+	if d.Components == nil || d.Components.Responses[name] == nil {
+		return nil, fmt.Errorf("response %s not found", response.Ref)
+	}
+	return d.Components.Responses[name], nil
+}
+
+// ResolveRequestBody follows $ref to a request body definition
+func (d *Document) ResolveRequestBody(body *RequestBody) (*RequestBody, error) {
+	if body.Ref == "" {
+		return body, nil
+	}
+	name, err := refName(body.Ref, "requestBodies")
+	if err != nil {
+		return nil, err
+	}
+	if d.Components == nil || d.Components.RequestBodies[name] == nil {
+		return nil, fmt.Errorf("request body %s not found", body.Ref)
+	}
+	return d.Components.RequestBodies[name], nil
+}
+
+// ResolveHeader follows $ref to a header definition
+func (d *Document) ResolveHeader(header *Header) (*Header, error) {
+	if header.Ref == "" {
+		return header, nil
+	}
+	name, err := refName(header.Ref, "headers")
+	if err != nil {
+		return nil, err
+	}
+	if d.Components == nil || d.Components.Headers[name] == nil {
+		return nil, fmt.Errorf("header %s not found", header.Ref)
+	}
+	return d.Components.Headers[name], nil
+}
+
+// ResolveExample follows $ref to an example definition
+func (d *Document) ResolveExample(example *Example) (*Example, error) {
+	if example.Ref == "" {
+		return example, nil
+	}
+	name, err := refName(example.Ref, "examples")
+	if err != nil {
+		return nil, err
+	}
+	if d.Components == nil || d.Components.Examples[name] == nil {
+		return nil, fmt.Errorf("example %s not found", example.Ref)
+	}
+	return d.Components.Examples[name], nil
+}
+
+// OperationParameters returns the resolved parameters of an operation,
+// including those declared on its path. Operation parameters override path
+// parameters with the same name and location.
+func (d *Document) OperationParameters(item *PathItem, operation *Operation) ([]*Parameter, error) {
+	var parameters []*Parameter
+	index := make(map[string]int)
+	for _, list := range [][]*Parameter{item.Parameters, operation.Parameters} {
+		for _, parameter := range list {
+			resolved, err := d.ResolveParameter(parameter)
+			if err != nil {
+				return nil, err
+			}
+			key := resolved.In + ":" + resolved.Name
+			if i, ok := index[key]; ok {
+				parameters[i] = resolved
+				continue
+			}
+			index[key] = len(parameters)
+			parameters = append(parameters, resolved)
+		}
+	}
+	return parameters, nil
+}
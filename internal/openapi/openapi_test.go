@@ -0,0 +1,237 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const petstore = `
+openapi: 3.0.3
+info:
+  title: Petstore
+  version: 1.0.0
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      parameters:
+        - $ref: '#/components/parameters/Limit'
+      responses:
+        200:
+          description: A list of pets
+          headers:
+            X-Total-Count:
+              schema:
+                type: integer
+                example: 2
+          content:
+            application/json:
+              example:
+                - id: 1
+                  name: Rex
+        default:
+          description: Unexpected error
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Error'
+    post:
+      requestBody:
+        $ref: '#/components/requestBodies/Pet'
+      responses:
+        '201':
+          description: Created
+          content:
+            application/json:
+              examples:
+                b-second:
+                  value: {id: 2}
+                a-first:
+                  $ref: '#/components/examples/NewPet'
+        '400':
+          $ref: '#/components/responses/BadRequest'
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: integer
+    get:
+      responses:
+        '200':
+          description: A pet
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+        '404':
+          description: Not found
+  /pets/mine:
+    get:
+      responses:
+        '200':
+          description: My pets
+          content:
+            text/plain:
+              example: "Rex"
+components:
+  parameters:
+    Limit:
+      name: limit
+      in: query
+      schema:
+        type: integer
+        maximum: 100
+  requestBodies:
+    Pet:
+      required: true
+      content:
+        application/json:
+          schema:
+            $ref: '#/components/schemas/Pet'
+  responses:
+    BadRequest:
+      description: Bad request
+      content:
+        application/json:
+          schema:
+            $ref: '#/components/schemas/Error'
+  examples:
+    NewPet:
+      value: {id: 3, name: Tom}
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id:
+          type: integer
+          format: int64
+        name:
+          type: string
+        tag:
+          type: [string, "null"]
+        born:
+          type: string
+          format: date
+        owner:
+          $ref: '#/components/schemas/Owner'
+    Owner:
+      type: object
+      properties:
+        email:
+          type: string
+          format: email
+        pets:
+          type: array
+          items:
+            $ref: '#/components/schemas/Pet'
+    Error:
+      type: object
+      properties:
+        code:
+          type: integer
+          minimum: 400
+        message:
+          type: string
+          default: failed
+`
+
+func TestParse(t *testing.T) {
+	doc, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	if doc.Info.Title != "Petstore" || len(doc.Paths) != 3 {
+		t.Errorf("Unexpected document: %+v", doc.Info)
+	}
+	if _, ok := doc.Paths["/pets"].Get.Responses["200"]; !ok {
+		t.Error("Expected integer status code keys to be converted to strings")
+	}
+
+	tag := doc.Components.Schemas["Pet"].Properties["tag"]
+	if !tag.Type.Is("null") || tag.Type.Primary() != "string" {
+		t.Errorf("Expected type list [string null], got %v", tag.Type)
+	}
+	encoded, _ := json.Marshal(doc.Components.Schemas["Pet"].Properties["name"].Type)
+	if string(encoded) != `"string"` {
+		t.Errorf("Expected a single type to be written as a string, got %s", encoded)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"not: [valid",
+		"swagger: '2.0'\ninfo: {title: old, version: 1}",
+		"openapi: 3.0.0\npaths: []",
+	}
+
+	for _, input := range tests {
+		if _, err := Parse([]byte(input)); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestResolveReferences(t *testing.T) {
+	doc, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	parameters, err := doc.OperationParameters(doc.Paths["/pets"], doc.Paths["/pets"].Get)
+	if err != nil || len(parameters) != 1 || parameters[0].Name != "limit" {
+		t.Errorf("Expected the referenced limit parameter, got %v (%v)", parameters, err)
+	}
+
+	response, err := doc.ResolveResponse(doc.Paths["/pets"].Post.Responses["400"])
+	if err != nil || response.Description != "Bad request" {
+		t.Errorf("Expected the referenced response, got %+v (%v)", response, err)
+	}
+
+	if _, err := doc.ResolveSchema(&Schema{Ref: "#/components/schemas/Missing"}); err == nil {
+		t.Error("Expected error for a missing schema")
+	}
+	if _, err := doc.ResolveSchema(&Schema{Ref: "other.yaml#/Pet"}); err == nil {
+		t.Error("Expected error for an external reference")
+	}
+}
+
+func TestSchemaExample(t *testing.T) {
+	doc, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	pet, ok := doc.SchemaExample(&Schema{Ref: "#/components/schemas/Pet"}).(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected an object example")
+	}
+	if pet["id"] != 0 || pet["name"] != "string" || pet["born"] != "2024-01-01" {
+		t.Errorf("Unexpected example: %v", pet)
+	}
+	owner := pet["owner"].(map[string]interface{})
+	if owner["email"] != "user@example.com" {
+		t.Errorf("Expected email format example, got %v", owner["email"])
+	}
+	if pets, ok := owner["pets"].([]interface{}); !ok || len(pets) != 0 {
+		t.Errorf("Expected recursive schemas to be cut off, got %v", owner["pets"])
+	}
+
+	errorExample := doc.SchemaExample(doc.Components.Schemas["Error"]).(map[string]interface{})
+	if errorExample["code"] != 400 || errorExample["message"] != "failed" {
+		t.Errorf("Expected minimum and default to be used, got %v", errorExample)
+	}
+
+	allOf := &Schema{AllOf: []*Schema{
+		{Ref: "#/components/schemas/Error"},
+		{Type: TypeSet{"object"}, Properties: map[string]*Schema{"retry": {Type: TypeSet{"boolean"}}}},
+	}}
+	if merged := doc.SchemaExample(allOf).(map[string]interface{}); merged["retry"] != true || merged["code"] != 400 {
+		t.Errorf("Expected allOf parts to be merged, got %v", merged)
+	}
+}
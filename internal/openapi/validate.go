@@ -0,0 +1,540 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/netip"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// ValidationError describes why a request does not conform to the document
+type ValidationError struct {
+	// Location is path, query, header, cookie or body
+	Location string `json:"location"`
+	// Name is the parameter name or the JSON pointer into the body
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	if e.Name == "" {
+		return e.Location + ": " + e.Message
+	}
+	return e.Location + " " + e.Name + ": " + e.Message
+}
+
+// OperationMatch is the operation documented for a request
+type OperationMatch struct {
+	Path       string
+	Method     string
+	Item       *PathItem
+	Operation  *Operation
+	PathParams map[string]string
+}
+
+// pathRegex compiles an OpenAPI path template into a regular expression
+func pathRegex(basePath, path string) (*regexp.Regexp, []string) {
+	var expr strings.Builder
+	var names []string
+	expr.WriteString("^" + regexp.QuoteMeta(basePath))
+	for {
+		start := strings.Index(path, "{")
+		end := strings.Index(path, "}")
+		if start < 0 || end < start {
+			expr.WriteString(regexp.QuoteMeta(path))
+			break
+		}
+		expr.WriteString(regexp.QuoteMeta(path[:start]))
+		expr.WriteString("([^/]+)")
+		names = append(names, path[start+1:end])
+		path = path[end+1:]
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()), names
+}
+
+// PathMatcher finds the operations of a document by request path. Its path
+// templates are compiled once, so it should be kept for as long as the
+// document is used.
+type PathMatcher struct {
+	paths []compiledPath
+}
+
+// compiledPath is a path template with its regular expression and the names
+// of its parameters
+type compiledPath struct {
+	path  string
+	item  *PathItem
+	regex *regexp.Regexp
+	names []string
+}
+
+// CompilePaths compiles the path templates of the document, most specific
+// first, taking the path of the first server URL into account
+func (d *Document) CompilePaths() *PathMatcher {
+	basePath := d.basePath()
+
+	paths := make([]string, 0, len(d.Paths))
+	for path, item := range d.Paths {
+		if item != nil {
+			paths = append(paths, path)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return moreSpecific(paths[i], paths[j])
+	})
+
+	matcher := &PathMatcher{paths: make([]compiledPath, 0, len(paths))}
+	for _, path := range paths {
+		regex, names := pathRegex(basePath, path)
+		matcher.paths = append(matcher.paths, compiledPath{path: path, item: d.Paths[path], regex: regex, names: names})
+	}
+	return matcher
+}
+
+// FindOperation returns the operation documented for the method and request
+// path
+func (m *PathMatcher) FindOperation(method, requestPath string) *OperationMatch {
+	for _, compiled := range m.paths {
+		matches := compiled.regex.FindStringSubmatch(requestPath)
+		if matches == nil {
+			continue
+		}
+		operation := compiled.item.Operations()[strings.ToUpper(method)]
+		if operation == nil {
+			continue
+		}
+		params := make(map[string]string, len(compiled.names))
+		for i, name := range compiled.names {
+			params[name] = matches[i+1]
+		}
+		return &OperationMatch{Path: compiled.path, Method: strings.ToUpper(method), Item: compiled.item, Operation: operation, PathParams: params}
+	}
+	return nil
+}
+
+// FindOperation returns the operation documented for the method and request
+// path, taking the path of the first server URL into account. It compiles
+// the path templates on every call; use CompilePaths to match many requests.
+func (d *Document) FindOperation(method, requestPath string) *OperationMatch {
+	return d.CompilePaths().FindOperation(method, requestPath)
+}
+
+// ValidateRequest checks the request's path parameters, query parameters,
+// headers, cookies and JSON body against the operation. The body is passed
+// separately so that the request body stays readable.
+func (d *Document) ValidateRequest(match *OperationMatch, r *http.Request, body []byte) []ValidationError {
+	var errors []ValidationError
+
+	parameters, err := d.OperationParameters(match.Item, match.Operation)
+	if err != nil {
+		return []ValidationError{{Location: "spec", Message: err.Error()}}
+	}
+
+	query := r.URL.Query()
+	for _, parameter := range parameters {
+		var values []string
+		switch parameter.In {
+		case "path":
+			if value, ok := match.PathParams[parameter.Name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			values = query[parameter.Name]
+		case "header":
+			values = r.Header.Values(parameter.Name)
+		case "cookie":
+			if cookie, err := r.Cookie(parameter.Name); err == nil {
+				values = []string{cookie.Value}
+			}
+		default:
+			continue
+		}
+
+		if len(values) == 0 {
+			if parameter.Required || parameter.In == "path" {
+				errors = append(errors, ValidationError{Location: parameter.In, Name: parameter.Name, Message: "is required"})
+			}
+			continue
+		}
+
+		value, err := d.parameterValue(parameter, values)
+		if err != nil {
+			errors = append(errors, ValidationError{Location: parameter.In, Name: parameter.Name, Message: err.Error()})
+			continue
+		}
+		for _, message := range d.ValidateValue(parameter.Schema, value) {
+			errors = append(errors, ValidationError{Location: parameter.In, Name: parameter.Name, Message: message.Message})
+		}
+	}
+
+	return append(errors, d.validateBody(match.Operation, r, body)...)
+}
+
+// parameterValue converts the raw parameter values to the type declared by
+// the parameter's schema
+func (d *Document) parameterValue(parameter *Parameter, values []string) (interface{}, error) {
+	schema, err := d.ResolveSchema(parameter.Schema)
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		return values[0], nil
+	}
+
+	if schema.Type.Primary() == "array" {
+		if len(values) == 1 && parameter.In != "query" {
+			values = strings.Split(values[0], ",")
+		}
+		items := make([]interface{}, len(values))
+		for i, value := range values {
+			item, err := d.coerce(schema.Items, value)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return d.coerce(schema, values[0])
+}
+
+// coerce converts a string to the type declared by the schema
+func (d *Document) coerce(schema *Schema, value string) (interface{}, error) {
+	schema, err := d.ResolveSchema(schema)
+	if err != nil || schema == nil {
+		return value, err
+	}
+
+	switch schema.Type.Primary() {
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected integer, got %q", value)
+		}
+		return float64(n), nil
+	case "number":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected number, got %q", value)
+		}
+		return n, nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("expected boolean, got %q", value)
+		}
+		return b, nil
+	}
+	return value, nil
+}
+
+// validateBody checks the request body against the operation's request body
+func (d *Document) validateBody(operation *Operation, r *http.Request, body []byte) []ValidationError {
+	if operation.RequestBody == nil {
+		return nil
+	}
+	requestBody, err := d.ResolveRequestBody(operation.RequestBody)
+	if err != nil {
+		return []ValidationError{{Location: "spec", Message: err.Error()}}
+	}
+
+	if len(body) == 0 {
+		if requestBody.Required {
+			return []ValidationError{{Location: "body", Message: "is required"}}
+		}
+		return nil
+	}
+	if len(requestBody.Content) == 0 {
+		return nil
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	media := findMediaType(requestBody.Content, mediaType)
+	if media == nil {
+		return []ValidationError{{Location: "header", Name: "Content-Type", Message: fmt.Sprintf("unsupported content type %q", contentType)}}
+	}
+	if !config.IsJSONContentType(mediaType) || media.Schema == nil {
+		return nil
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return []ValidationError{{Location: "body", Message: "invalid JSON: " + err.Error()}}
+	}
+
+	var errors []ValidationError
+	for _, message := range d.ValidateValue(media.Schema, document) {
+		errors = append(errors, ValidationError{Location: "body", Name: message.Name, Message: message.Message})
+	}
+	return errors
+}
+
+// findMediaType returns the media type matching the request content type,
+// honoring wildcards such as application/* and */*
+func findMediaType(content map[string]*MediaType, mediaType string) *MediaType {
+	if media, ok := content[mediaType]; ok {
+		return media
+	}
+	if idx := strings.Index(mediaType, "/"); idx >= 0 {
+		if media, ok := content[mediaType[:idx]+"/*"]; ok {
+			return media
+		}
+	}
+	return content["*/*"]
+}
+
+// ValidateValue checks a decoded JSON value against the schema. Each error
+// is named by the JSON pointer of the offending value.
+func (d *Document) ValidateValue(schema *Schema, value interface{}) []ValidationError {
+	var errors []ValidationError
+	d.validateValue(schema, value, "", &errors)
+	return errors
+}
+
+// validateValue appends the violations of value at pointer to errors
+func (d *Document) validateValue(schema *Schema, value interface{}, pointer string, errors *[]ValidationError) {
+	schema, err := d.ResolveSchema(schema)
+	if err != nil {
+		*errors = append(*errors, ValidationError{Name: pointer, Message: err.Error()})
+		return
+	}
+	if schema == nil {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		*errors = append(*errors, ValidationError{Name: pointer, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, part := range schema.AllOf {
+		d.validateValue(part, value, pointer, errors)
+	}
+	if len(schema.AnyOf) > 0 && d.countMatching(schema.AnyOf, value) == 0 {
+		fail("does not match any of the allowed schemas")
+	}
+	if len(schema.OneOf) > 0 {
+		if count := d.countMatching(schema.OneOf, value); count != 1 {
+			fail("must match exactly one schema, matched %d", count)
+		}
+	}
+
+	if value == nil {
+		if len(schema.Type) > 0 && !schema.Nullable && !schema.Type.Is("null") {
+			fail("must not be null")
+		}
+		return
+	}
+
+	if len(schema.Type) > 0 && !typeMatches(schema.Type, value) {
+		fail("expected %s, got %s", strings.Join(schema.Type, " or "), jsonType(value))
+		return
+	}
+
+	if len(schema.Enum) > 0 {
+		allowed := false
+		for _, option := range schema.Enum {
+			if reflect.DeepEqual(normalizeNumber(option), normalizeNumber(value)) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			fail("must be one of %s", compact(schema.Enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := len([]rune(v))
+		if schema.MinLength != nil && length < *schema.MinLength {
+			fail("must be at least %d characters long", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			fail("must be at most %d characters long", *schema.MaxLength)
+		}
+		if schema.Pattern != "" {
+			if regex, err := regexp.Compile(schema.Pattern); err == nil && !regex.MatchString(v) {
+				fail("must match pattern %s", schema.Pattern)
+			}
+		}
+		if !formatMatches(schema.Format, v) {
+			fail("must be a valid %s", schema.Format)
+		}
+	case float64:
+		d.validateNumber(schema, v, fail)
+	case []interface{}:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			fail("must have at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			fail("must have at most %d items", *schema.MaxItems)
+		}
+		for i, item := range v {
+			d.validateValue(schema.Items, item, pointer+"/"+strconv.Itoa(i), errors)
+		}
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				*errors = append(*errors, ValidationError{Name: pointer + "/" + name, Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := schema.Properties[name]; ok {
+				d.validateValue(property, v[name], pointer+"/"+name, errors)
+				continue
+			}
+			switch additional := schema.AdditionalProperties.(type) {
+			case bool:
+				if !additional {
+					*errors = append(*errors, ValidationError{Name: pointer + "/" + name, Message: "is not allowed"})
+				}
+			case map[string]interface{}:
+				if encoded, err := json.Marshal(additional); err == nil {
+					var extra Schema
+					if json.Unmarshal(encoded, &extra) == nil {
+						d.validateValue(&extra, v[name], pointer+"/"+name, errors)
+					}
+				}
+			}
+		}
+	}
+}
+
+// validateNumber checks the numeric bounds of the schema
+func (d *Document) validateNumber(schema *Schema, v float64, fail func(string, ...interface{})) {
+	if schema.Minimum != nil {
+		if exclusive, _ := schema.ExclusiveMinimum.(bool); exclusive && v <= *schema.Minimum {
+			fail("must be greater than %v", *schema.Minimum)
+		} else if v < *schema.Minimum {
+			fail("must be at least %v", *schema.Minimum)
+		}
+	}
+	if bound, ok := schema.ExclusiveMinimum.(float64); ok && v <= bound {
+		fail("must be greater than %v", bound)
+	}
+	if schema.Maximum != nil {
+		if exclusive, _ := schema.ExclusiveMaximum.(bool); exclusive && v >= *schema.Maximum {
+			fail("must be less than %v", *schema.Maximum)
+		} else if v > *schema.Maximum {
+			fail("must be at most %v", *schema.Maximum)
+		}
+	}
+	if bound, ok := schema.ExclusiveMaximum.(float64); ok && v >= bound {
+		fail("must be less than %v", bound)
+	}
+}
+
+// countMatching returns how many of the schemas accept the value
+func (d *Document) countMatching(schemas []*Schema, value interface{}) int {
+	count := 0
+	for _, schema := range schemas {
+		var errors []ValidationError
+		d.validateValue(schema, value, "", &errors)
+		if len(errors) == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// typeMatches reports whether the JSON value has one of the types
+func typeMatches(types TypeSet, value interface{}) bool {
+	actual := jsonType(value)
+	for _, typ := range types {
+		if typ == actual || (typ == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON schema type of a decoded value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case int, int64:
+		return "integer"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// normalizeNumber converts integers to float64 so enum values from YAML
+// compare equal to decoded JSON numbers
+func normalizeNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return value
+}
+
+// formatMatches checks the well-known string formats; unknown formats pass
+func formatMatches(format, value string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil
+	case "email":
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	case "uuid":
+		return uuidPattern.MatchString(value)
+	case "ipv4":
+		addr, err := netip.ParseAddr(value)
+		return err == nil && addr.Is4()
+	case "ipv6":
+		addr, err := netip.ParseAddr(value)
+		return err == nil && addr.Is6()
+	}
+	return true
+}
+
+// compact renders a value as single-line JSON for messages
+func compact(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
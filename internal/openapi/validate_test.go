@@ -0,0 +1,142 @@
+package openapi
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFindOperation(t *testing.T) {
+	doc, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"GET", "/v1/pets", "/pets"},
+		{"get", "/v1/pets/mine", "/pets/mine"},
+		{"GET", "/v1/pets/42", "/pets/{petId}"},
+		{"DELETE", "/v1/pets/42", ""},
+		{"GET", "/pets", ""},
+	}
+
+	paths := doc.CompilePaths()
+	for _, test := range tests {
+		match := paths.FindOperation(test.method, test.path)
+		got := ""
+		if match != nil {
+			got = match.Path
+		}
+		if got != test.want {
+			t.Errorf("FindOperation(%s %s) = %q, expected %q", test.method, test.path, got, test.want)
+		}
+	}
+
+	if match := doc.FindOperation("GET", "/v1/pets/42"); match.PathParams["petId"] != "42" {
+		t.Errorf("Expected petId 42, got %v", match.PathParams)
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	doc, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		want        []string
+	}{
+		{"valid query", "GET", "/v1/pets?limit=10", "", "", nil},
+		{"query type", "GET", "/v1/pets?limit=ten", "", "", []string{"query limit: expected integer"}},
+		{"query maximum", "GET", "/v1/pets?limit=500", "", "", []string{"query limit: must be at most 100"}},
+		{"path type", "GET", "/v1/pets/abc", "", "", []string{"path petId: expected integer"}},
+		{"valid body", "POST", "/v1/pets", "application/json", `{"id": 1, "name": "Rex", "tag": null, "born": "2020-02-29"}`, nil},
+		{"missing body", "POST", "/v1/pets", "application/json", "", []string{"body: is required"}},
+		{"invalid JSON", "POST", "/v1/pets", "application/json", `{"id":`, []string{"body: invalid JSON"}},
+		{"content type", "POST", "/v1/pets", "text/plain", "Rex", []string{"header Content-Type: unsupported content type"}},
+		{
+			"body schema", "POST", "/v1/pets", "application/json; charset=utf-8",
+			`{"id": "one", "born": "yesterday", "owner": {"email": "nobody", "pets": [{"id": 2}]}}`,
+			[]string{
+				"body /name: is required",
+				"body /born: must be a valid date",
+				"body /id: expected integer, got string",
+				"body /owner/email: must be a valid email",
+				"body /owner/pets/0/name: is required",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+			if test.contentType != "" {
+				req.Header.Set("Content-Type", test.contentType)
+			}
+			match := doc.FindOperation(req.Method, req.URL.Path)
+			if match == nil {
+				t.Fatalf("No operation found for %s %s", test.method, test.target)
+			}
+
+			errors := doc.ValidateRequest(match, req, []byte(test.body))
+			if len(errors) != len(test.want) {
+				t.Fatalf("Expected %d errors, got %v", len(test.want), errors)
+			}
+			for i, want := range test.want {
+				if !strings.HasPrefix(errors[i].Error(), want) {
+					t.Errorf("Expected error %q, got %q", want, errors[i].Error())
+				}
+			}
+		})
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	five, two := 5.0, 2
+	tests := []struct {
+		name   string
+		schema *Schema
+		value  interface{}
+		valid  bool
+	}{
+		{"enum", &Schema{Enum: []interface{}{"a", "b"}}, "c", false},
+		{"integer enum", &Schema{Type: TypeSet{"integer"}, Enum: []interface{}{1, 2}}, 2.0, true},
+		{"number accepts integer", &Schema{Type: TypeSet{"number"}}, 3.0, true},
+		{"integer rejects fraction", &Schema{Type: TypeSet{"integer"}}, 3.5, false},
+		{"exclusive minimum 3.0", &Schema{Minimum: &five, ExclusiveMinimum: true}, 5.0, false},
+		{"exclusive minimum 3.1", &Schema{ExclusiveMinimum: 5.0}, 6.0, true},
+		{"min length", &Schema{Type: TypeSet{"string"}, MinLength: &two}, "é", false},
+		{"pattern", &Schema{Type: TypeSet{"string"}, Pattern: "^[a-z]+$"}, "abc", true},
+		{"max items", &Schema{Type: TypeSet{"array"}, MaxItems: &two}, []interface{}{1.0, 2.0, 3.0}, false},
+		{"nullable", &Schema{Type: TypeSet{"string"}, Nullable: true}, nil, true},
+		{"not nullable", &Schema{Type: TypeSet{"string"}}, nil, false},
+		{"closed object", &Schema{Type: TypeSet{"object"}, AdditionalProperties: false}, map[string]interface{}{"x": 1.0}, false},
+		{
+			"typed additional properties",
+			&Schema{Type: TypeSet{"object"}, AdditionalProperties: map[string]interface{}{"type": "integer"}},
+			map[string]interface{}{"x": "1"}, false,
+		},
+		{"one of", &Schema{OneOf: []*Schema{{Type: TypeSet{"number"}}, {Type: TypeSet{"integer"}}}}, 1.0, false},
+		{"any of", &Schema{AnyOf: []*Schema{{Type: TypeSet{"string"}}, {Type: TypeSet{"integer"}}}}, 1.0, true},
+		{"all of", &Schema{AllOf: []*Schema{{Required: []string{"a"}}, {Required: []string{"b"}}}}, map[string]interface{}{"a": 1.0}, false},
+		{"uuid", &Schema{Type: TypeSet{"string"}, Format: "uuid"}, "3fa85f64-5717-4562-b3fc-2c963f66afa6", true},
+		{"unknown format", &Schema{Type: TypeSet{"string"}, Format: "color"}, "red", true},
+	}
+
+	doc := &Document{}
+	for _, test := range tests {
+		errors := doc.ValidateValue(test.schema, test.value)
+		if valid := len(errors) == 0; valid != test.valid {
+			t.Errorf("%s: expected valid=%v, got errors %v", test.name, test.valid, errors)
+		}
+	}
+}
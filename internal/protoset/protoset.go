@@ -0,0 +1,226 @@
+// Package protoset loads protobuf descriptors from .proto files and
+// descriptor sets, so that services can be served without generated code.
+package protoset
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Set holds loaded files together with the files they import
+type Set struct {
+	Files *protoregistry.Files
+	// Types resolves messages of the set, e.g. inside google.protobuf.Any
+	Types *dynamicpb.Types
+	// Services are the services of the loaded files, sorted by name
+	Services []protoreflect.ServiceDescriptor
+}
+
+// Load compiles the .proto files matching the patterns and reads the
+// descriptor sets. Each .proto file must be inside one of the import paths,
+// which are searched for imports; the well-known types are always available.
+func Load(protos, importPaths, descriptorSets []string) (*Set, error) {
+	s := &Set{Files: new(protoregistry.Files)}
+	var loaded []protoreflect.FileDescriptor
+
+	names, err := protoNames(protos, importPaths)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > 0 {
+		compiler := protocompile.Compiler{
+			Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+		}
+		files, err := compiler.Compile(context.Background(), names...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile proto files: %w", err)
+		}
+		for _, file := range files {
+			loaded = append(loaded, file)
+		}
+	}
+
+	for _, path := range descriptorSets {
+		files, err := s.readDescriptorSet(path)
+		if err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, files...)
+	}
+
+	seen := make(map[protoreflect.FullName]bool)
+	for _, file := range loaded {
+		if err := s.register(file); err != nil {
+			return nil, err
+		}
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			if service := services.Get(i); !seen[service.FullName()] {
+				seen[service.FullName()] = true
+				s.Services = append(s.Services, service)
+			}
+		}
+	}
+	sort.Slice(s.Services, func(i, j int) bool { return s.Services[i].FullName() < s.Services[j].FullName() })
+
+	s.Types = dynamicpb.NewTypes(s.Files)
+	return s, nil
+}
+
+// FindMethod returns the descriptor of a method named /package.Service/Method
+func (s *Set) FindMethod(fullMethod string) (protoreflect.MethodDescriptor, error) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid method name %s", fullMethod)
+	}
+
+	desc, err := s.Files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("unknown service %s", service)
+	}
+	serviceDesc, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	methodDesc := serviceDesc.Methods().ByName(protoreflect.Name(method))
+	if methodDesc == nil {
+		return nil, fmt.Errorf("unknown method %s of service %s", method, service)
+	}
+	return methodDesc, nil
+}
+
+// Resolver returns a resolver for the set's descriptors that falls back to
+// the descriptors linked into the binary
+func (s *Set) Resolver() protodesc.Resolver {
+	return fallbackResolver{s.Files}
+}
+
+// readDescriptorSet reads the files of a FileDescriptorSet. Imports missing
+// from the set are looked up among the loaded files and the well-known types.
+func (s *Set) readDescriptorSet(path string) ([]protoreflect.FileDescriptor, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set %s: %w", path, err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse descriptor set %s: %w", path, err)
+	}
+
+	files := make([]protoreflect.FileDescriptor, 0, len(set.File))
+	for _, fileProto := range set.File {
+		if existing, err := s.Files.FindFileByPath(fileProto.GetName()); err == nil {
+			files = append(files, existing)
+			continue
+		}
+		file, err := protodesc.NewFile(fileProto, fallbackResolver{s.Files})
+		if err != nil {
+			return nil, fmt.Errorf("invalid descriptor %s in %s: %w", fileProto.GetName(), path, err)
+		}
+		if err := s.register(file); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// register adds a file and its imports to the registry
+func (s *Set) register(file protoreflect.FileDescriptor) error {
+	if _, err := s.Files.FindFileByPath(file.Path()); err == nil {
+		return nil
+	}
+
+	imports := file.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if err := s.register(imports.Get(i).FileDescriptor); err != nil {
+			return err
+		}
+	}
+	if err := s.Files.RegisterFile(file); err != nil {
+		return fmt.Errorf("failed to register %s: %w", file.Path(), err)
+	}
+	return nil
+}
+
+// protoNames expands the patterns and returns each file's name relative to
+// the first import path containing it, as imports refer to it
+func protoNames(patterns, importPaths []string) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pattern, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			return nil, fmt.Errorf("proto file %s not found", pattern)
+		}
+		sort.Strings(matches)
+
+		for _, match := range matches {
+			name, err := relativeName(match, importPaths)
+			if err != nil {
+				return nil, err
+			}
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// relativeName returns the path of a file relative to the first import path
+// containing it
+func relativeName(path string, importPaths []string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for _, importPath := range importPaths {
+		dir, err := filepath.Abs(importPath)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(rel), nil
+		}
+	}
+	return "", fmt.Errorf("proto file %s is not inside an import path", path)
+}
+
+// fallbackResolver looks descriptors up in the registry, then among the
+// descriptors linked into the binary, which include the well-known types
+type fallbackResolver struct {
+	files *protoregistry.Files
+}
+
+// FindFileByPath implements protodesc.Resolver
+func (r fallbackResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if file, err := r.files.FindFileByPath(path); err == nil {
+		return file, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+// FindDescriptorByName implements protodesc.Resolver
+func (r fallbackResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if desc, err := r.files.FindDescriptorByName(name); err == nil {
+		return desc, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
@@ -0,0 +1,156 @@
+package protoset
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const greeterProto = `syntax = "proto3";
+package demo.v1;
+
+import "google/protobuf/timestamp.proto";
+import "demo/v1/types.proto";
+
+service Greeter {
+  rpc SayHello (HelloRequest) returns (HelloReply);
+  rpc Count (HelloRequest) returns (stream HelloReply);
+}
+
+message HelloRequest {
+  string name = 1;
+  Mood mood = 2;
+}
+
+message HelloReply {
+  string message = 1;
+  google.protobuf.Timestamp at = 2;
+}
+`
+
+const typesProto = `syntax = "proto3";
+package demo.v1;
+
+enum Mood {
+  MOOD_UNSPECIFIED = 0;
+  MOOD_HAPPY = 1;
+}
+`
+
+// writeProtos writes the test files below dir/demo/v1
+func writeProtos(t *testing.T, dir string) {
+	t.Helper()
+	pkg := filepath.Join(dir, "demo", "v1")
+	if err := os.MkdirAll(pkg, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"greeter.proto": greeterProto, "types.proto": typesProto} {
+		if err := os.WriteFile(filepath.Join(pkg, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadProtos(t *testing.T) {
+	dir := t.TempDir()
+	writeProtos(t, dir)
+
+	set, err := Load([]string{filepath.Join(dir, "demo/v1/greeter.proto")}, []string{dir}, nil)
+	if err != nil {
+		t.Fatalf("Failed to load protos: %v", err)
+	}
+	if len(set.Services) != 1 || set.Services[0].FullName() != "demo.v1.Greeter" {
+		t.Fatalf("Expected the Greeter service, got %v", set.Services)
+	}
+
+	method, err := set.FindMethod("/demo.v1.Greeter/Count")
+	if err != nil {
+		t.Fatalf("Failed to find method: %v", err)
+	}
+	if !method.IsStreamingServer() || method.Input().FullName() != "demo.v1.HelloRequest" {
+		t.Errorf("Unexpected method descriptor %v", method)
+	}
+	for _, path := range []string{"demo/v1/types.proto", "google/protobuf/timestamp.proto"} {
+		if _, err := set.Files.FindFileByPath(path); err != nil {
+			t.Errorf("Expected import %s to be registered: %v", path, err)
+		}
+	}
+
+	for _, name := range []string{"/demo.v1.Greeter/Missing", "/demo.v1.Missing/SayHello", "/demo.v1.HelloRequest/name", "Greeter"} {
+		if _, err := set.FindMethod(name); err == nil {
+			t.Errorf("Expected an error finding %s", name)
+		}
+	}
+}
+
+func TestLoadDescriptorSet(t *testing.T) {
+	dir := t.TempDir()
+	writeProtos(t, dir)
+	compiled, err := Load([]string{filepath.Join(dir, "demo/v1/*.proto")}, []string{dir}, nil)
+	if err != nil {
+		t.Fatalf("Failed to load protos: %v", err)
+	}
+
+	// Like protoc --descriptor_set_out without --include_imports: the
+	// well-known types are missing from the set
+	var set descriptorpb.FileDescriptorSet
+	for _, path := range []string{"demo/v1/types.proto", "demo/v1/greeter.proto"} {
+		file, _ := compiled.Files.FindFileByPath(path)
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(file))
+	}
+	data, err := proto.Marshal(&set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setPath := filepath.Join(dir, "demo.pb")
+	if err := os.WriteFile(setPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(nil, nil, []string{setPath})
+	if err != nil {
+		t.Fatalf("Failed to load descriptor set: %v", err)
+	}
+	if _, err := loaded.FindMethod("/demo.v1.Greeter/SayHello"); err != nil {
+		t.Errorf("Expected SayHello in the descriptor set: %v", err)
+	}
+	if _, err := loaded.Resolver().FindFileByPath("google/protobuf/timestamp.proto"); err != nil {
+		t.Errorf("Expected the resolver to find well-known types: %v", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeProtos(t, dir)
+	broken := filepath.Join(dir, "broken.proto")
+	os.WriteFile(broken, []byte(`syntax = "proto3"; message Broken { Unknown field = 1; }`), 0644)
+	os.WriteFile(filepath.Join(dir, "bad.pb"), []byte("not a descriptor set"), 0644)
+
+	tests := []struct {
+		name           string
+		protos         []string
+		importPaths    []string
+		descriptorSets []string
+		expected       string
+	}{
+		{"missing proto", []string{filepath.Join(dir, "missing.proto")}, []string{dir}, nil, "not found"},
+		{"outside import paths", []string{filepath.Join(dir, "demo/v1/types.proto")}, []string{filepath.Join(dir, "other")}, nil, "not inside an import path"},
+		{"compile error", []string{broken}, []string{dir}, nil, "Unknown"},
+		{"missing descriptor set", nil, nil, []string{filepath.Join(dir, "missing.pb")}, "failed to read"},
+		{"invalid descriptor set", nil, nil, []string{filepath.Join(dir, "bad.pb")}, "failed to parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.protos, tt.importPaths, tt.descriptorSets)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -3,21 +3,26 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/walterfan/lazy-mock-server/internal/config"
 	"github.com/walterfan/lazy-mock-server/internal/handlers"
 	"github.com/walterfan/lazy-mock-server/internal/logger"
+	"google.golang.org/grpc"
 )
 
 // Server represents the mock server
 type Server struct {
 	httpServer    *http.Server
+	grpcServer    *grpc.Server
+	grpcPort      int
 	configManager *config.Manager
 	handler       *handlers.MockHandler
 	logger        *logger.Logger
@@ -26,6 +31,15 @@ type Server struct {
 	enableTLS     bool
 	certFile      string
 	keyFile       string
+	watch         bool
+	watchInterval time.Duration
+	stopWatching  context.CancelFunc
+	reloadMutex   sync.Mutex
+
+	// watchMutex guards the files and include patterns being watched
+	watchMutex      sync.Mutex
+	watchedFiles    []string
+	watchedPatterns []string
 }
 
 // Config represents server configuration
@@ -36,6 +50,23 @@ type Config struct {
 	EnableTLS  bool
 	CertFile   string
 	KeyFile    string
+	ProxyURL   string
+	// Record forwards every request to ProxyURL and saves each unique
+	// exchange as a route in RecordFile
+	Record        bool
+	RecordFile    string
+	RecordHeaders []string
+	// JournalSize is the number of requests kept for /_mock/requests;
+	// zero keeps the default and a negative size disables the journal
+	JournalSize int
+	// Watch reloads the configuration when the file changes. WatchInterval
+	// polls the file at this interval instead of using file system
+	// notifications.
+	Watch         bool
+	WatchInterval time.Duration
+	// GRPCPort serves the configured gRPC services on this port instead of
+	// the one in the configuration
+	GRPCPort int
 }
 
 // New creates a new mock server instance
@@ -59,11 +90,49 @@ func New(cfg Config) (*Server, error) {
 		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	if err := configManager.ValidateConfig(configManager.GetConfig()); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
 	log.LogInfo("Loaded configuration from: %s", configPath)
 	log.LogInfo("Found %d routes in configuration", configManager.GetRouteCount())
 
+	if validation := configManager.GetConfig().Validation; validation != nil {
+		log.LogInfo("Validating requests against: %s", validation.Spec)
+	}
+
 	// Initialize handlers
 	mockHandler := handlers.NewMockHandler(configManager, log)
+	if cfg.ProxyURL != "" {
+		if err := config.ValidateProxyTarget(cfg.ProxyURL); err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+		mockHandler.SetProxy(&config.ProxyConfig{Target: cfg.ProxyURL})
+		log.LogInfo("Forwarding unmatched requests to: %s", cfg.ProxyURL)
+	}
+	if cfg.JournalSize != 0 {
+		mockHandler.SetJournalSize(cfg.JournalSize)
+	}
+	if cfg.Record {
+		if cfg.ProxyURL == "" {
+			return nil, fmt.Errorf("record mode requires a proxy target")
+		}
+		opts := handlers.RecordOptions{Path: cfg.RecordFile, Headers: cfg.RecordHeaders}
+		if err := mockHandler.StartRecording(opts); err != nil {
+			return nil, fmt.Errorf("failed to start recording: %w", err)
+		}
+	}
+
+	if err := mockHandler.LoadGRPC(configManager.GetConfig().GRPC); err != nil {
+		return nil, fmt.Errorf("failed to load gRPC services: %w", err)
+	}
+	grpcPort := cfg.GRPCPort
+	if grpcSettings := configManager.GetConfig().GRPC; grpcSettings != nil && grpcPort == 0 {
+		grpcPort = grpcSettings.Port
+		if grpcPort == 0 {
+			grpcPort = config.DefaultGRPCPort
+		}
+	}
 
 	// Create HTTP server with logging middleware
 	mux := http.NewServeMux()
@@ -87,6 +156,15 @@ func New(cfg Config) (*Server, error) {
 		enableTLS:     cfg.EnableTLS,
 		certFile:      cfg.CertFile,
 		keyFile:       cfg.KeyFile,
+		watch:         cfg.Watch,
+		watchInterval: cfg.WatchInterval,
+	}
+
+	// Calls of every method go to the mock; only reflection is registered
+	if grpcPort > 0 {
+		server.grpcPort = grpcPort
+		server.grpcServer = grpc.NewServer(grpc.UnknownServiceHandler(mockHandler.HandleGRPC))
+		mockHandler.RegisterGRPCReflection(server.grpcServer)
 	}
 
 	return server, nil
@@ -124,6 +202,23 @@ func (s *Server) Start() error {
 		}
 	}()
 
+	if s.grpcServer != nil {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.grpcPort))
+		if err != nil {
+			return fmt.Errorf("failed to listen for gRPC: %w", err)
+		}
+		s.logger.LogInfo("Serving gRPC on port %d", s.grpcPort)
+		go func() {
+			if err := s.grpcServer.Serve(listener); err != nil {
+				s.logger.LogError(err, "gRPC server error")
+			}
+		}()
+	}
+
+	if s.watch {
+		s.startWatching()
+	}
+
 	s.logger.LogInfo("Mock server started successfully")
 	return nil
 }
@@ -132,6 +227,24 @@ func (s *Server) Start() error {
 func (s *Server) Stop(ctx context.Context) error {
 	s.logger.LogInfo("Shutting down mock server...")
 
+	if s.stopWatching != nil {
+		s.stopWatching()
+	}
+
+	if s.grpcServer != nil {
+		// Streaming calls may not finish in time
+		stopped := make(chan struct{})
+		go func() {
+			s.grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			s.grpcServer.Stop()
+		}
+	}
+
 	if err := s.httpServer.Shutdown(ctx); err != nil {
 		s.logger.LogError(err, "server shutdown")
 		return err
@@ -148,11 +261,24 @@ func (s *Server) Run() error {
 		return err
 	}
 
+	// Reload the configuration on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	<-quit
+	for waiting := true; waiting; {
+		select {
+		case <-hup:
+			s.logger.LogInfo("Received SIGHUP")
+			_ = s.Reload()
+		case <-quit:
+			waiting = false
+		}
+	}
 	s.logger.LogInfo("Received shutdown signal")
 
 	// Create a context with timeout for graceful shutdown
@@ -167,6 +293,11 @@ func (s *Server) GetPort() int {
 	return s.port
 }
 
+// GetGRPCPort returns the port of the gRPC listener, 0 when gRPC is not served
+func (s *Server) GetGRPCPort() int {
+	return s.grpcPort
+}
+
 // GetConfigPath returns the configuration file path
 func (s *Server) GetConfigPath() string {
 	return s.configPath
@@ -187,17 +318,37 @@ func (s *Server) GetHandler() *handlers.MockHandler {
 	return s.handler
 }
 
-// Reload reloads the configuration from file
+// Reload reloads the configuration from file. The new configuration is
+// validated first; when it is invalid the current one stays in effect.
 func (s *Server) Reload() error {
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+
 	s.logger.LogInfo("Reloading configuration...")
 
-	if err := s.configManager.Load(); err != nil {
-		s.logger.LogError(err, "reloading configuration")
+	cfg, err := s.configManager.Read()
+	if err == nil {
+		err = s.configManager.ValidateConfig(cfg)
+	}
+	if err == nil {
+		err = s.handler.LoadGRPC(cfg.GRPC)
+	}
+	if err != nil {
+		s.logger.LogError(err, "reloading configuration, keeping the current one")
 		return err
 	}
 
-	s.logger.LogInfo("Configuration reloaded successfully")
-	s.logger.LogInfo("Found %d routes in configuration", s.configManager.GetRouteCount())
+	s.setWatched(cfg)
+	var previous []config.Route
+	if old := s.handler.ReplaceConfig(cfg); old != nil {
+		previous = old.Routes
+	}
+
+	s.logger.LogInfo("Configuration reloaded successfully: %s", config.DiffRoutes(previous, cfg.Routes))
+	s.logger.LogInfo("Found %d routes in configuration", len(cfg.Routes))
+	if cfg.GRPC != nil && s.grpcServer == nil {
+		s.logger.LogWarn("gRPC services are configured but the gRPC listener only starts with the server")
+	}
 
 	return nil
 }
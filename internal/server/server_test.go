@@ -2,8 +2,11 @@ package server
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -80,6 +83,24 @@ func TestNewWithInvalidConfig(t *testing.T) {
 	}
 }
 
+func TestNewRejectsInvalidRoutes(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "invalid.yaml")
+	configData := `routes:
+  - path: "/ws"
+    method: "GET"
+    websocket:
+      periodic: [{interval_ms: 0, data: "hi"}]
+`
+	if err := os.WriteFile(configPath, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	_, err := New(Config{Port: 8081, ConfigPath: configPath, LogLevel: logger.LogLevelError})
+	if err == nil || !strings.Contains(err.Error(), "invalid configuration") {
+		t.Errorf("Expected startup to reject the invalid route, got %v", err)
+	}
+}
+
 func TestServerMethods(t *testing.T) {
 	configPath := createTestConfig(t)
 
@@ -377,3 +398,109 @@ func TestSaveConfig(t *testing.T) {
 		t.Error("Expected saved route to be found in new server instance")
 	}
 }
+
+func TestReloadKeepsConfigOnError(t *testing.T) {
+	configPath := createTestConfig(t)
+
+	server, err := New(Config{Port: 8087, ConfigPath: configPath, LogLevel: logger.LogLevelError})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	invalid := []string{
+		"routes: [not valid",
+		"routes:\n  - path: \"/test\"\n    method: \"FETCH\"\n    status_code: 200\n",
+	}
+	for _, data := range invalid {
+		if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to update config file: %v", err)
+		}
+		if err := server.Reload(); err == nil {
+			t.Errorf("Expected reload of %q to fail", data)
+		}
+		if routes := server.GetConfigManager().GetRoutes(); len(routes) != 1 || routes[0].Path != "/test" {
+			t.Errorf("Expected the previous configuration to stay in effect, got %+v", routes)
+		}
+	}
+}
+
+func TestWatchReloads(t *testing.T) {
+	for _, interval := range []time.Duration{0, 20 * time.Millisecond} {
+		configPath := createTestConfig(t)
+		server, err := New(Config{
+			Port: 8088, ConfigPath: configPath, LogLevel: logger.LogLevelError,
+			Watch: true, WatchInterval: interval,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		server.startWatching()
+
+		// Make sure the new file has a different size and modification time
+		time.Sleep(50 * time.Millisecond)
+		data := "routes:\n  - path: \"/watched\"\n    method: \"GET\"\n    status_code: 204\n"
+		if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to update config file: %v", err)
+		}
+
+		reloaded := false
+		for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			if servesRoute(server, "/watched") {
+				reloaded = true
+				break
+			}
+		}
+		server.stopWatching()
+		if !reloaded {
+			t.Errorf("Expected the configuration to be reloaded (poll interval %s)", interval)
+		}
+	}
+}
+
+func TestWatchDirectory(t *testing.T) {
+	for _, interval := range []time.Duration{0, 20 * time.Millisecond} {
+		dir := t.TempDir()
+		data := "include:\n  - teams/*.yaml\nroutes:\n  - path: \"/main\"\n    method: \"GET\"\n    status_code: 200\n"
+		if err := os.WriteFile(filepath.Join(dir, "main.yaml"), []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		if err := os.Mkdir(filepath.Join(dir, "teams"), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+
+		server, err := New(Config{
+			Port: 8088, ConfigPath: dir, LogLevel: logger.LogLevelError,
+			Watch: true, WatchInterval: interval,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		server.startWatching()
+
+		// A file matching an include pattern of a file in the directory
+		time.Sleep(50 * time.Millisecond)
+		data = "routes:\n  - path: \"/team\"\n    method: \"GET\"\n    status_code: 204\n"
+		if err := os.WriteFile(filepath.Join(dir, "teams", "billing.yaml"), []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write included file: %v", err)
+		}
+
+		reloaded := false
+		for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			if servesRoute(server, "/team") {
+				reloaded = true
+				break
+			}
+		}
+		server.stopWatching()
+		if !reloaded {
+			t.Errorf("Expected the included file to be picked up (poll interval %s)", interval)
+		}
+	}
+}
+
+// servesRoute reports whether the server answers the path with 204 No Content
+func servesRoute(server *Server, path string) bool {
+	w := httptest.NewRecorder()
+	server.handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	return w.Code == http.StatusNoContent
+}
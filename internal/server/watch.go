@@ -0,0 +1,262 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/walterfan/lazy-mock-server/internal/config"
+)
+
+// defaultPollInterval is how often the configuration files are checked when
+// file system notifications are unavailable
+const defaultPollInterval = time.Second
+
+// reloadDebounce coalesces the bursts of events editors cause when saving
+const reloadDebounce = 100 * time.Millisecond
+
+// fileState is what polling compares to notice a changed file
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// startWatching reloads the configuration whenever one of its files changes
+// until stopWatching is called. File system notifications are used unless a
+// poll interval is configured or they are unavailable.
+func (s *Server) startWatching() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopWatching = cancel
+	s.setWatched(s.configManager.GetConfig())
+
+	if s.watchInterval == 0 {
+		watcher, err := s.newWatcher()
+		if err == nil {
+			s.logger.LogInfo("Watching %s for changes", s.configPath)
+			go s.watchNotify(ctx, watcher)
+			return
+		}
+		s.logger.LogInfo("File notifications unavailable (%v), polling %s instead", err, s.configPath)
+	} else {
+		s.logger.LogInfo("Polling %s for changes every %s", s.configPath, s.watchInterval)
+	}
+	go s.watchPoll(ctx)
+}
+
+// setWatched records the files and include patterns of a configuration,
+// including the .proto files and descriptor sets of the gRPC services
+func (s *Server) setWatched(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+
+	s.watchMutex.Lock()
+	defer s.watchMutex.Unlock()
+	s.watchedFiles = cfg.Files()
+	s.watchedPatterns = cfg.IncludePatterns()
+	if cfg.GRPC != nil {
+		s.watchedPatterns = append(s.watchedPatterns, cfg.GRPC.SourcePatterns()...)
+	}
+}
+
+// isConfigDir reports whether the configuration path is a directory
+func (s *Server) isConfigDir() bool {
+	info, err := os.Stat(s.configPath)
+	return err == nil && info.IsDir()
+}
+
+// watchedDirs returns the directories holding configuration files, including
+// those include patterns may add files to
+func (s *Server) watchedDirs() []string {
+	s.watchMutex.Lock()
+	defer s.watchMutex.Unlock()
+
+	var dirs []string
+	seen := make(map[string]bool)
+	add := func(dir string) {
+		if !seen[dir] && !strings.ContainsAny(dir, "*?[") {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	if s.isConfigDir() {
+		add(s.configPath)
+	} else {
+		add(filepath.Dir(s.configPath))
+	}
+	for _, file := range s.watchedFiles {
+		add(filepath.Dir(file))
+	}
+	for _, pattern := range s.watchedPatterns {
+		add(filepath.Dir(pattern))
+	}
+	return dirs
+}
+
+// isWatched reports whether a file is, or may become, part of the
+// configuration
+func (s *Server) isWatched(name string) bool {
+	name = filepath.Clean(name)
+	if name == s.configPath || (filepath.Dir(name) == s.configPath && config.IsConfigFile(name)) {
+		return true
+	}
+
+	s.watchMutex.Lock()
+	defer s.watchMutex.Unlock()
+	for _, file := range s.watchedFiles {
+		if name == file {
+			return true
+		}
+	}
+	for _, pattern := range s.watchedPatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// newWatcher watches the directories of the configuration files, since
+// editors often save by replacing a file rather than writing to it
+func (s *Server) newWatcher() (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	dirs := s.watchedDirs()
+	if err := watcher.Add(dirs[0]); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	s.addWatches(watcher, dirs[1:])
+	return watcher, nil
+}
+
+// addWatches adds directories to the watcher; directories already watched
+// are left as they are
+func (s *Server) addWatches(watcher *fsnotify.Watcher, dirs []string) {
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			s.logger.LogError(err, "watching configuration directory "+dir)
+		}
+	}
+}
+
+// watchNotify reloads the configuration on file system events for its files
+func (s *Server) watchNotify(ctx context.Context, watcher *fsnotify.Watcher) {
+	defer watcher.Close()
+
+	var timer *time.Timer
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) || !s.isWatched(event.Name) {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(reloadDebounce, func() {
+				s.logger.LogInfo("Configuration file changed: %s", event.Name)
+				if s.Reload() == nil {
+					// Includes may have added directories
+					s.addWatches(watcher, s.watchedDirs())
+				}
+			})
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			s.logger.LogError(err, "watching configuration file")
+		}
+	}
+}
+
+// watchPoll reloads the configuration when the modification time or size of
+// one of its files changes, or files are added or removed
+func (s *Server) watchPoll(ctx context.Context) {
+	interval := s.watchInterval
+	if interval == 0 {
+		interval = defaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := s.snapshot()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := s.snapshot()
+			if sameSnapshot(last, current) {
+				continue
+			}
+			last = current
+			s.logger.LogInfo("Configuration file changed")
+			if s.Reload() == nil {
+				// Includes may have added files
+				last = s.snapshot()
+			}
+		}
+	}
+}
+
+// snapshot returns the state of every file that is, or may become, part of
+// the configuration
+func (s *Server) snapshot() map[string]fileState {
+	var paths []string
+	if s.isConfigDir() {
+		if entries, err := os.ReadDir(s.configPath); err == nil {
+			for _, entry := range entries {
+				if !entry.IsDir() && config.IsConfigFile(entry.Name()) {
+					paths = append(paths, filepath.Join(s.configPath, entry.Name()))
+				}
+			}
+		}
+	} else {
+		paths = append(paths, s.configPath)
+	}
+
+	s.watchMutex.Lock()
+	paths = append(paths, s.watchedFiles...)
+	for _, pattern := range s.watchedPatterns {
+		matches, _ := filepath.Glob(pattern)
+		paths = append(paths, matches...)
+	}
+	s.watchMutex.Unlock()
+
+	states := make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			states[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return states
+}
+
+// sameSnapshot reports whether two snapshots describe the same files
+func sameSnapshot(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, state := range a {
+		other, ok := b[path]
+		if !ok || !other.modTime.Equal(state.modTime) || other.size != state.size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,269 @@
+// Package xmldoc reads XML request bodies, evaluates the subset of XPath used
+// for request matching and writes structured responses as XML.
+package xmldoc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// NodeType identifies the kind of a node
+type NodeType int
+
+const (
+	DocumentNode NodeType = iota
+	ElementNode
+	TextNode
+	AttributeNode
+)
+
+// Node is a node of a parsed document. Attribute nodes are only created
+// when an expression selects them.
+type Node struct {
+	Type NodeType
+	// Name is the name of elements and attributes, with the namespace URI
+	// as Space
+	Name xml.Name
+	// Data is the content of text and attribute nodes
+	Data     string
+	Attr     []xml.Attr
+	Parent   *Node
+	Children []*Node
+}
+
+// Parse reads an XML document. Declared encodings other than UTF-8 are not
+// converted.
+func Parse(data []byte) (*Node, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	doc := &Node{Type: DocumentNode}
+	current := doc
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &Node{Type: ElementNode, Name: t.Name, Attr: t.Attr, Parent: current}
+			current.Children = append(current.Children, node)
+			current = node
+		case xml.EndElement:
+			current = current.Parent
+		case xml.CharData:
+			if current == doc {
+				continue
+			}
+			if last := len(current.Children) - 1; last >= 0 && current.Children[last].Type == TextNode {
+				current.Children[last].Data += string(t)
+			} else {
+				current.Children = append(current.Children, &Node{Type: TextNode, Data: string(t), Parent: current})
+			}
+		}
+	}
+
+	for _, child := range doc.Children {
+		if child.Type == ElementNode {
+			return doc, nil
+		}
+	}
+	return nil, fmt.Errorf("no root element")
+}
+
+// Value returns the string value of a node: the text it contains, or the
+// value of an attribute
+func (n *Node) Value() string {
+	if n.Type == TextNode || n.Type == AttributeNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for _, child := range n.Children {
+		sb.WriteString(child.Value())
+	}
+	return sb.String()
+}
+
+// IsXMLContentType reports whether a content type denotes XML, such as
+// application/xml, text/xml or application/soap+xml
+func IsXMLContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// namePattern matches element and attribute names with an optional prefix
+var namePattern = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_.\-]*(:[\p{L}_][\p{L}\p{N}_.\-]*)?$`)
+
+// escaper escapes text and attribute values
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// Marshal writes a map holding the root element as an XML document. Keys
+// name child elements; "@name" keys are attributes and "#text" is the
+// element's text. Lists repeat an element. Prefixed names are written as
+// given, so namespaces are declared with "@xmlns:prefix" attributes. The keys
+// of a yaml.MapSlice, as decoded from YAML, are written in their order; the
+// keys of other maps in the order of their names.
+func Marshal(value interface{}) ([]byte, error) {
+	elements, ok := fields(value)
+	if !ok {
+		return nil, fmt.Errorf("XML documents must be a map of elements")
+	}
+	if len(elements) != 1 {
+		return nil, fmt.Errorf("XML documents must have exactly one root element, got %d", len(elements))
+	}
+	root := elements[0]
+	if strings.HasPrefix(root.name, "@") || root.name == "#text" {
+		return nil, fmt.Errorf("%s outside of an element", root.name)
+	}
+	if list, ok := root.value.([]interface{}); ok && len(list) != 1 {
+		return nil, fmt.Errorf("XML documents must have exactly one root element, got %d %s elements", len(list), root.name)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := writeElement(&buf, root.name, root.value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// field is a key of a map of elements with its value
+type field struct {
+	name  string
+	value interface{}
+}
+
+// fields returns the keys and values of a map of elements in the order they
+// are written
+func fields(value interface{}) ([]field, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		result := make([]field, len(keys))
+		for i, key := range keys {
+			result[i] = field{key, v[key]}
+		}
+		return result, true
+	case yaml.MapSlice:
+		result := make([]field, len(v))
+		for i, item := range v {
+			result[i] = field{fmt.Sprint(item.Key), item.Value}
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+// writeElement writes the element or, for lists, the elements named name
+func writeElement(buf *bytes.Buffer, name string, value interface{}) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid element name %q", name)
+	}
+
+	if children, ok := fields(value); ok {
+		return writeChildren(buf, name, children)
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if _, nested := item.([]interface{}); nested {
+				return fmt.Errorf("element %s: nested lists cannot be written as XML", name)
+			}
+			if err := writeElement(buf, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case nil:
+		fmt.Fprintf(buf, "<%s/>", name)
+		return nil
+	default:
+		text, err := scalarText(v)
+		if err != nil {
+			return fmt.Errorf("element %s: %w", name, err)
+		}
+		fmt.Fprintf(buf, "<%s>%s</%s>", name, escaper.Replace(text), name)
+		return nil
+	}
+}
+
+// writeChildren writes an element with the attributes, text and child
+// elements given by the fields of its map
+func writeChildren(buf *bytes.Buffer, name string, children []field) error {
+	buf.WriteString("<" + name)
+	for _, child := range children {
+		if !strings.HasPrefix(child.name, "@") {
+			continue
+		}
+		attr := child.name[1:]
+		if !namePattern.MatchString(attr) {
+			return fmt.Errorf("element %s: invalid attribute name %q", name, attr)
+		}
+		text, err := scalarText(child.value)
+		if err != nil {
+			return fmt.Errorf("element %s: attribute %s: %w", name, attr, err)
+		}
+		fmt.Fprintf(buf, ` %s="%s"`, attr, escaper.Replace(text))
+	}
+	buf.WriteString(">")
+
+	for _, child := range children {
+		if child.name != "#text" {
+			continue
+		}
+		text, err := scalarText(child.value)
+		if err != nil {
+			return fmt.Errorf("element %s: #text: %w", name, err)
+		}
+		buf.WriteString(escaper.Replace(text))
+	}
+	for _, child := range children {
+		if strings.HasPrefix(child.name, "@") || child.name == "#text" {
+			continue
+		}
+		if err := writeElement(buf, child.name, child.value); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("</" + name + ">")
+	return nil
+}
+
+// scalarText formats a string, number or boolean
+func scalarText(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case map[string]interface{}, yaml.MapSlice, []interface{}:
+		return "", fmt.Errorf("expected text, got a structured value")
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
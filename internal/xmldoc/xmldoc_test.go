@@ -0,0 +1,140 @@
+package xmldoc
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := Parse([]byte(`<?xml version="1.0" encoding="ISO-8859-1"?><a xmlns="urn:a" id="1">x<b>y</b>z</a>`))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+	root := doc.Children[0]
+	if root.Name.Space != "urn:a" || root.Name.Local != "a" || len(root.Children) != 3 {
+		t.Errorf("Unexpected root element %+v", root)
+	}
+	if root.Value() != "xyz" {
+		t.Errorf("Expected the text in document order, got %q", root.Value())
+	}
+
+	for _, data := range []string{"", "   ", "not xml", "<a><b></a>", "<a>"} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Expected an error parsing %q", data)
+		}
+	}
+}
+
+func TestIsXMLContentType(t *testing.T) {
+	for contentType, expected := range map[string]bool{
+		"application/xml":                        true,
+		"text/xml; charset=utf-8":                true,
+		`application/soap+xml; action="urn:Get"`: true,
+		"application/atom+xml":                   true,
+		"application/json":                       false,
+		"text/plain":                             false,
+		"":                                       false,
+	} {
+		if got := IsXMLContentType(contentType); got != expected {
+			t.Errorf("%s: expected %v, got %v", contentType, expected, got)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	value := map[string]interface{}{
+		"soap:Envelope": map[string]interface{}{
+			"@xmlns:soap": "http://schemas.xmlsoap.org/soap/envelope/",
+			"soap:Body": map[string]interface{}{
+				"m:GetBalanceResponse": map[string]interface{}{
+					"@xmlns:m": "urn:billing",
+					"m:Balance": map[string]interface{}{
+						"@currency": "EUR",
+						"#text":     12.5,
+					},
+					"m:Invoice": []interface{}{1, 2},
+					"m:Note":    "Tom & Jerry <3",
+					"m:Empty":   nil,
+					"m:Paid":    true,
+				},
+			},
+		},
+	}
+
+	data, err := Marshal(value)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` +
+		`<m:GetBalanceResponse xmlns:m="urn:billing"><m:Balance currency="EUR">12.5</m:Balance>` +
+		`<m:Empty/><m:Invoice>1</m:Invoice><m:Invoice>2</m:Invoice><m:Note>Tom &amp; Jerry &lt;3</m:Note>` +
+		`<m:Paid>true</m:Paid></m:GetBalanceResponse></soap:Body></soap:Envelope>`
+	if string(data) != expected {
+		t.Errorf("Unexpected document:\n%s\nexpected:\n%s", data, expected)
+	}
+
+	// The written document reads back with its namespaces
+	doc, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Failed to parse the written document: %v", err)
+	}
+	expr, _ := Compile("//m:Balance[@currency='EUR'] = 12.5", map[string]string{"m": "urn:billing"})
+	if !expr.Match(doc) {
+		t.Error("Expected the balance in the billing namespace")
+	}
+}
+
+func TestMarshalOrdered(t *testing.T) {
+	var value yaml.MapSlice
+	err := yaml.Unmarshal([]byte(`
+soap:Envelope:
+  "@xmlns:soap": "http://schemas.xmlsoap.org/soap/envelope/"
+  soap:Header:
+    Token: abc
+  soap:Body:
+    Result: [{z: 1, a: 2}]
+`), &value)
+	if err != nil {
+		t.Fatalf("Failed to decode YAML: %v", err)
+	}
+
+	data, err := Marshal(value)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">` +
+		`<soap:Header><Token>abc</Token></soap:Header>` +
+		`<soap:Body><Result><z>1</z><a>2</a></Result></soap:Body></soap:Envelope>`
+	if string(data) != expected {
+		t.Errorf("Unexpected document:\n%s\nexpected:\n%s", data, expected)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"not a map", []interface{}{"a"}, "map of elements"},
+		{"attribute outside element", map[string]interface{}{"@id": "1"}, "outside of an element"},
+		{"invalid element name", map[string]interface{}{"1st": "x"}, "invalid element name"},
+		{"invalid attribute name", map[string]interface{}{"a": map[string]interface{}{"@a b": "x"}}, "invalid attribute name"},
+		{"structured attribute", map[string]interface{}{"a": map[string]interface{}{"@id": []interface{}{1}}}, "structured value"},
+		{"nested lists", map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{[]interface{}{1}}}}, "nested lists"},
+		{"no root", map[string]interface{}{}, "exactly one root element, got 0"},
+		{"two roots", map[string]interface{}{"a": 1, "b": 2}, "exactly one root element, got 2"},
+		{"repeated root", map[string]interface{}{"a": []interface{}{1, 2}}, "got 2 a elements"},
+		{"ordered roots", yaml.MapSlice{{Key: "a", Value: 1}, {Key: "b", Value: 2}}, "exactly one root element"},
+	}
+
+	for _, tt := range tests {
+		if _, err := Marshal(tt.value); err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
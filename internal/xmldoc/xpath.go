@@ -0,0 +1,443 @@
+package xmldoc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// operators are checked longest first so "<=" is not read as "<"
+var operators = []string{"!=", "<=", ">=", "=", "<", ">"}
+
+// axis identifies the direction of a step
+type axis int
+
+const (
+	axisChild axis = iota
+	axisAttribute
+	axisSelf
+	axisParent
+)
+
+// step is a single step of a location path
+type step struct {
+	axis axis
+	// descendant applies the step to all descendants of the context, as
+	// after "//"
+	descendant bool
+	// text selects text nodes instead of elements or attributes
+	text bool
+	// space is the namespace URI names must have, unless anySpace is set;
+	// local is the local name or "*"
+	space      string
+	anySpace   bool
+	local      string
+	predicates []*predicate
+}
+
+// predicate filters the nodes of a step by position or by a condition
+type predicate struct {
+	position  int
+	condition *comparison
+}
+
+// path is a location path such as /a/b or .//c
+type path struct {
+	absolute bool
+	steps    []*step
+}
+
+// comparison checks that a path selects a node, or a node whose value
+// compares to the literal
+type comparison struct {
+	path    *path
+	op      string
+	literal string
+	number  float64
+	numeric bool
+}
+
+// Expr is a compiled expression: a location path, optionally compared to a
+// literal, e.g. "//m:AccountId", "//m:AccountId = '42'" or
+// "//m:Item[@type='fee'][1]/m:Amount > 10". Unprefixed names match elements
+// of any namespace; prefixed names must be bound to a namespace URI.
+type Expr struct {
+	source     string
+	comparison *comparison
+}
+
+// Compile parses an expression, resolving its prefixes with namespaces
+func Compile(expr string, namespaces map[string]string) (*Expr, error) {
+	p := &parser{src: expr, namespaces: namespaces}
+	c, err := p.comparison()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", expr, err)
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("%s: unexpected %q", expr, p.src[p.pos:])
+	}
+	return &Expr{source: expr, comparison: c}, nil
+}
+
+// String returns the source expression
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Match reports whether the expression selects a node of the document or,
+// with a comparison, whether any selected node satisfies it. Values are
+// compared without surrounding white space, as numbers when the literal is
+// a number.
+func (e *Expr) Match(doc *Node) bool {
+	return e.comparison.eval(doc)
+}
+
+// Find returns the nodes the path of the expression selects
+func (e *Expr) Find(doc *Node) []*Node {
+	return e.comparison.path.find(doc)
+}
+
+// Get returns the trimmed value of the first node expr selects
+func Get(doc *Node, expr string) (string, bool) {
+	compiled, err := Compile(expr, nil)
+	if err != nil {
+		return "", false
+	}
+	nodes := compiled.Find(doc)
+	if len(nodes) == 0 {
+		return "", false
+	}
+	return strings.TrimSpace(nodes[0].Value()), true
+}
+
+// eval evaluates the comparison with node as context
+func (c *comparison) eval(node *Node) bool {
+	nodes := c.path.find(node)
+	if c.op == "" {
+		return len(nodes) > 0
+	}
+	for _, n := range nodes {
+		if c.compare(strings.TrimSpace(n.Value())) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare applies the operator to a single value
+func (c *comparison) compare(value string) bool {
+	if !c.numeric && (c.op == "=" || c.op == "!=") {
+		return (value == c.literal) == (c.op == "=")
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return c.op == "!="
+	}
+	expected := c.number
+	if !c.numeric {
+		if expected, err = strconv.ParseFloat(c.literal, 64); err != nil {
+			return false
+		}
+	}
+	switch c.op {
+	case "=":
+		return number == expected
+	case "!=":
+		return number != expected
+	case "<":
+		return number < expected
+	case "<=":
+		return number <= expected
+	case ">":
+		return number > expected
+	default:
+		return number >= expected
+	}
+}
+
+// find evaluates the path with node as context
+func (p *path) find(node *Node) []*Node {
+	if p.absolute {
+		for node.Parent != nil {
+			node = node.Parent
+		}
+	}
+
+	nodes := []*Node{node}
+	for _, s := range p.steps {
+		var next []*Node
+		seen := make(map[*Node]bool)
+		for _, context := range nodes {
+			contexts := []*Node{context}
+			if s.descendant {
+				contexts = descendantsOrSelf(context, nil)
+			}
+			for _, c := range contexts {
+				for _, selected := range s.apply(c) {
+					if !seen[selected] {
+						seen[selected] = true
+						next = append(next, selected)
+					}
+				}
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// apply selects the nodes of a step from a single context node
+func (s *step) apply(node *Node) []*Node {
+	var selected []*Node
+	switch s.axis {
+	case axisSelf:
+		selected = []*Node{node}
+	case axisParent:
+		if node.Parent != nil {
+			selected = []*Node{node.Parent}
+		}
+	case axisAttribute:
+		if node.Type != ElementNode {
+			return nil
+		}
+		for _, attr := range node.Attr {
+			if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+				continue
+			}
+			if s.matchesName(attr.Name.Space, attr.Name.Local) {
+				selected = append(selected, &Node{Type: AttributeNode, Name: attr.Name, Data: attr.Value, Parent: node})
+			}
+		}
+	default:
+		for _, child := range node.Children {
+			if s.text && child.Type == TextNode || !s.text && child.Type == ElementNode && s.matchesName(child.Name.Space, child.Name.Local) {
+				selected = append(selected, child)
+			}
+		}
+	}
+
+	for _, pred := range s.predicates {
+		var kept []*Node
+		for i, n := range selected {
+			if pred.condition == nil && i+1 == pred.position || pred.condition != nil && pred.condition.eval(n) {
+				kept = append(kept, n)
+			}
+		}
+		selected = kept
+	}
+	return selected
+}
+
+// matchesName checks a name against the step's name test
+func (s *step) matchesName(space, local string) bool {
+	return (s.anySpace || space == s.space) && (s.local == "*" || local == s.local)
+}
+
+// descendantsOrSelf appends node and all its descendants to nodes
+func descendantsOrSelf(node *Node, nodes []*Node) []*Node {
+	nodes = append(nodes, node)
+	for _, child := range node.Children {
+		nodes = descendantsOrSelf(child, nodes)
+	}
+	return nodes
+}
+
+// parser reads an expression
+type parser struct {
+	src        string
+	pos        int
+	namespaces map[string]string
+}
+
+// comparison reads a path and an optional comparison with a literal
+func (p *parser) comparison() (*comparison, error) {
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	c := &comparison{path: path}
+
+	p.skipSpace()
+	for _, op := range operators {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			p.pos += len(op)
+			c.op = op
+			return c, p.literal(c)
+		}
+	}
+	return c, nil
+}
+
+// path reads a location path
+func (p *parser) path() (*path, error) {
+	result := &path{}
+	descendant := false
+	if p.consume("//") {
+		result.absolute, descendant = true, true
+	} else if p.consume("/") {
+		result.absolute = true
+	}
+
+	for {
+		s, err := p.step()
+		if err != nil {
+			return nil, err
+		}
+		s.descendant = descendant
+		result.steps = append(result.steps, s)
+
+		if p.consume("//") {
+			descendant = true
+		} else if p.consume("/") {
+			descendant = false
+		} else {
+			return result, nil
+		}
+	}
+}
+
+// step reads a step with its predicates
+func (p *parser) step() (*step, error) {
+	if p.consume("..") {
+		return &step{axis: axisParent}, nil
+	}
+	if p.consume(".") {
+		return &step{axis: axisSelf}, nil
+	}
+
+	s := &step{axis: axisChild}
+	if p.consume("@") {
+		s.axis = axisAttribute
+	}
+	if err := p.nameTest(s); err != nil {
+		return nil, err
+	}
+
+	for p.consume("[") {
+		pred := &predicate{}
+		p.skipSpace()
+		if start := p.pos; p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+			for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+				p.pos++
+			}
+			pred.position, _ = strconv.Atoi(p.src[start:p.pos])
+			if pred.position < 1 {
+				return nil, fmt.Errorf("positions start at 1")
+			}
+		} else {
+			condition, err := p.comparison()
+			if err != nil {
+				return nil, err
+			}
+			pred.condition = condition
+		}
+		if !p.consume("]") {
+			return nil, fmt.Errorf("missing ] at %d", p.pos)
+		}
+		s.predicates = append(s.predicates, pred)
+	}
+	return s, nil
+}
+
+// nameTest reads *, name, prefix:name, prefix:* or text()
+func (p *parser) nameTest(s *step) error {
+	if p.consume("*") {
+		s.anySpace, s.local = true, "*"
+		return nil
+	}
+
+	p.skipSpace()
+	name := p.name()
+	if name == "" {
+		return fmt.Errorf("expected a name at %d", p.pos)
+	}
+
+	if p.pos < len(p.src) && p.src[p.pos] == ':' {
+		p.pos++
+		uri, ok := p.namespaces[name]
+		if !ok {
+			return fmt.Errorf("unbound namespace prefix %s", name)
+		}
+		s.space = uri
+		if p.consume("*") {
+			s.local = "*"
+			return nil
+		}
+		if s.local = p.name(); s.local == "" {
+			return fmt.Errorf("expected a name at %d", p.pos)
+		}
+		return nil
+	}
+
+	if name == "text" && p.consume("(") {
+		if !p.consume(")") {
+			return fmt.Errorf("missing ) at %d", p.pos)
+		}
+		if s.axis == axisAttribute {
+			return fmt.Errorf("attributes have no text nodes")
+		}
+		s.text = true
+		return nil
+	}
+
+	s.anySpace, s.local = true, name
+	return nil
+}
+
+// name reads a name without prefix
+func (p *parser) name() string {
+	start := p.pos
+	for i, r := range p.src[p.pos:] {
+		if !(unicode.IsLetter(r) || r == '_' || i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.')) {
+			break
+		}
+		p.pos = start + i + len(string(r))
+	}
+	return p.src[start:p.pos]
+}
+
+// literal reads a quoted string or a number
+func (p *parser) literal(c *comparison) error {
+	p.skipSpace()
+	if p.pos < len(p.src) && (p.src[p.pos] == '\'' || p.src[p.pos] == '"') {
+		quote := p.src[p.pos]
+		end := strings.IndexByte(p.src[p.pos+1:], quote)
+		if end < 0 {
+			return fmt.Errorf("unterminated string at %d", p.pos)
+		}
+		c.literal = p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte("+-.0123456789eE", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	number, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if err != nil {
+		return fmt.Errorf("expected a string or number at %d", start)
+	}
+	c.literal, c.number, c.numeric = p.src[start:p.pos], number, true
+	return nil
+}
+
+// consume skips white space and the token if it comes next
+func (p *parser) consume(token string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+// skipSpace skips white space
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
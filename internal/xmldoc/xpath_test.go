@@ -0,0 +1,107 @@
+package xmldoc
+
+import (
+	"testing"
+)
+
+const soapRequest = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:bill="urn:billing">
+  <soap:Header>
+    <bill:Auth token="secret"/>
+  </soap:Header>
+  <soap:Body>
+    <bill:GetInvoices>
+      <bill:AccountId> 42 </bill:AccountId>
+      <bill:Invoice status="open"><bill:Amount>12.50</bill:Amount></bill:Invoice>
+      <bill:Invoice status="paid"><bill:Amount>99</bill:Amount></bill:Invoice>
+      <Note>rush <b>now</b> please</Note>
+    </bill:GetInvoices>
+  </soap:Body>
+</soap:Envelope>`
+
+var testNamespaces = map[string]string{
+	"soap":  "http://schemas.xmlsoap.org/soap/envelope/",
+	"bill":  "urn:billing",
+	"other": "urn:other",
+}
+
+func TestMatch(t *testing.T) {
+	doc, err := Parse([]byte(soapRequest))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{"/soap:Envelope/soap:Body/bill:GetInvoices", true},
+		{"/Envelope/Body/GetInvoices", true},
+		{"/other:Envelope", false},
+		{"//bill:AccountId = '42'", true},
+		{"//bill:AccountId = 42", true},
+		{"//bill:AccountId != '42'", false},
+		{"//AccountId = '43'", false},
+		{"//bill:Invoice[@status='open']/bill:Amount = 12.5", true},
+		{"//bill:Invoice[@status='paid']/bill:Amount < 50", false},
+		{"//bill:Invoice/bill:Amount > 50", true},
+		{"//bill:Invoice[2]/@status = 'paid'", true},
+		{"//bill:Invoice[3]", false},
+		{"//bill:Invoice[bill:Amount='99']/@status = \"paid\"", true},
+		{"//bill:Auth/@token", true},
+		{"//bill:Auth/@missing", false},
+		{"//soap:Header/*", true},
+		{"//bill:*[@token='secret']", true},
+		{"//Note = 'rush now please'", true},
+		{"//Note/text() = 'rush'", true},
+		{"//b/.. = 'rush now please'", true},
+		{"//bill:Invoice[.='99']", true},
+		{"//@xmlns", false},
+	}
+
+	for _, tt := range tests {
+		expr, err := Compile(tt.expr, testNamespaces)
+		if err != nil {
+			t.Errorf("Failed to compile %s: %v", tt.expr, err)
+			continue
+		}
+		if got := expr.Match(doc); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.expr, tt.expected, got)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"/",
+		"//unknown:Envelope",
+		"//a[",
+		"//a[0]",
+		"//a = ",
+		"//a = 'open",
+		"//a b",
+		"//@text()",
+	} {
+		if _, err := Compile(expr, testNamespaces); err == nil {
+			t.Errorf("Expected an error compiling %q", expr)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	doc, err := Parse([]byte(soapRequest))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	if value, ok := Get(doc, "//AccountId"); !ok || value != "42" {
+		t.Errorf("Expected 42, got %q %v", value, ok)
+	}
+	if value, ok := Get(doc, "//Invoice/@status"); !ok || value != "open" {
+		t.Errorf("Expected the first status, got %q %v", value, ok)
+	}
+	if _, ok := Get(doc, "//Missing"); ok {
+		t.Error("Expected no value for a missing element")
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
+	"github.com/walterfan/lazy-mock-server/internal/handlers"
 	"github.com/walterfan/lazy-mock-server/internal/logger"
 	"github.com/walterfan/lazy-mock-server/internal/server"
 )
@@ -18,15 +20,31 @@ var (
 )
 
 func main() {
+	// Run subcommands
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(os.Args[2:]); err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		return
+	}
+
 	// Parse command-line arguments
 	var (
 		port       = flag.Int("port", 8080, "Port to listen on")
-		configPath = flag.String("config", "app/mock_response.yaml", "Path to configuration file")
+		configPath = flag.String("config", "app/mock_response.yaml", "Path to configuration file or directory")
 		logLevel   = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
 		version    = flag.Bool("version", false, "Show version information")
 		enableTLS  = flag.Bool("tls", false, "Enable HTTPS/TLS")
 		certFile   = flag.String("cert", "server.crt", "Path to TLS certificate file")
 		keyFile    = flag.String("key", "server.key", "Path to TLS private key file")
+		proxy      = flag.String("proxy", "", "Forward unmatched requests to this upstream base URL")
+		record     = flag.Bool("record", false, "Forward all requests to -proxy and record them as routes")
+		recordFile = flag.String("record-file", "recorded_mocks.yaml", "Path to the YAML file recorded routes are written to")
+		journalLen = flag.Int("journal-size", handlers.DefaultJournalSize, "Number of requests kept for /_mock/requests (negative disables the journal)")
+		recordHdrs = flag.String("record-headers", "", "Comma-separated response headers to record (default: all but hop-by-hop, Date and Content-Length)")
+		watch      = flag.Bool("watch", true, "Reload the configuration when the file changes")
+		watchEvery = flag.Duration("watch-interval", 0, "Poll the configuration file at this interval instead of using file system notifications")
+		grpcPort   = flag.Int("grpc-port", 0, "Port to serve gRPC on (default: grpc.port of the configuration, or 9090 when it configures gRPC)")
 	)
 	flag.Parse()
 
@@ -56,12 +74,22 @@ func main() {
 
 	// Create server configuration
 	serverConfig := server.Config{
-		Port:       *port,
-		ConfigPath: *configPath,
-		LogLevel:   logLevelEnum,
-		EnableTLS:  *enableTLS,
-		CertFile:   *certFile,
-		KeyFile:    *keyFile,
+		Port:          *port,
+		ConfigPath:    *configPath,
+		LogLevel:      logLevelEnum,
+		EnableTLS:     *enableTLS,
+		CertFile:      *certFile,
+		KeyFile:       *keyFile,
+		ProxyURL:      *proxy,
+		Record:        *record,
+		RecordFile:    *recordFile,
+		JournalSize:   *journalLen,
+		Watch:         *watch,
+		WatchInterval: *watchEvery,
+		GRPCPort:      *grpcPort,
+	}
+	if *recordHdrs != "" {
+		serverConfig.RecordHeaders = strings.Split(*recordHdrs, ",")
 	}
 
 	// Create and start the server
@@ -79,9 +107,17 @@ func main() {
 	fmt.Printf("📁 Config: %s\n", srv.GetConfigPath())
 	fmt.Printf("🌐 Server: %s://localhost:%d\n", protocol, srv.GetPort())
 	fmt.Printf("🎛️  Web UI: %s://localhost:%d/_mock/ui\n", protocol, srv.GetPort())
+	if srv.GetGRPCPort() > 0 {
+		fmt.Printf("🔌 gRPC: localhost:%d\n", srv.GetGRPCPort())
+	}
 	if *enableTLS {
 		fmt.Printf("🔒 TLS: Enabled (cert: %s, key: %s)\n", *certFile, *keyFile)
 	}
+	if *record {
+		fmt.Printf("⏺️  Recording: %s -> %s\n", *proxy, *recordFile)
+	} else if *proxy != "" {
+		fmt.Printf("🔀 Proxy: unmatched requests forwarded to %s\n", *proxy)
+	}
 	fmt.Printf("📊 Routes: %d configured\n", srv.GetConfigManager().GetRouteCount())
 	fmt.Println("🔥 Server starting...")
 